@@ -2,15 +2,33 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"time"
 	"gopkg.in/ini.v1"
 )
 
-var AppConfig struct {
+// OIDCProvider holds the client registration for a social login provider
+type OIDCProvider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Config is the application configuration
+type Config struct {
+	// Env is the deployment environment from GO_ENV, e.g. production
+	Env      string
+	Database struct {
+		Driver       string
+		SQLitePath   string
+		ReadTimeout  string
+		WriteTimeout string
+	}
 	MySQL struct {
 		ServiceBookingDBConn string
 		Host                 string
@@ -20,131 +38,266 @@ var AppConfig struct {
 		Name                 string
 	}
 	HttpPort int
+	Server   struct {
+		ReadTimeout       string
+		ReadHeaderTimeout string
+		WriteTimeout      string
+		IdleTimeout       string
+		ShutdownTimeout   string
+		// TrustedProxies may set the client IP with X-Forwarded-For
+		TrustedProxies    []string
+	}
+	Log struct {
+		Level  string
+		Format string
+	}
+	Tracing struct {
+		Exporter    string
+		Endpoint    string
+		Insecure    bool
+		ServiceName string
+		SampleRatio string
+	}
+	Metrics struct {
+		Token string
+	}
 	JWT      struct {
 		SecretKey            string
 		AccessTokenDuration  string
 		RefreshTokenDuration string
 	}
+	OTP struct {
+		Length             int
+		TTL                string
+		MaxAttempts        int
+		ResendInterval     string
+		DefaultCountryCode string
+	}
+	SMS struct {
+		Driver     string
+		AccountSID string
+		AuthToken  string
+		From       string
+	}
+	OIDCProviders map[string]OIDCProvider
+	LoginThrottle struct {
+		Store           string
+		FreeAttempts    int
+		MaxFailures     int
+		IPMaxFailures   int
+		BaseDelay       string
+		MaxDelay        string
+		LockoutDuration string
+		Window          string
+	}
+	RateLimit struct {
+		Store   string
+		Auth    string
+		Booking string
+		Catalog string
+		Default string
+		Lookup  string
+	}
+	BookingReference struct {
+		Prefix string
+		Length int
+	}
+	GuestAccess struct {
+		LinkBaseURL string
+		TokenTTL    string
+	}
+	Idempotency struct {
+		TTL string
+	}
+	CORS struct {
+		AllowedOrigins   []string
+		AllowedMethods   []string
+		AllowedHeaders   []string
+		ExposedHeaders   []string
+		AllowCredentials bool
+		MaxAge           string
+	}
+	SecurityHeaders struct {
+		HSTSMaxAge            string
+		HSTSIncludeSubdomains bool
+		FrameOptions          string
+		ContentSecurityPolicy string
+		ReferrerPolicy        string
+	}
 }
 
-// LoadConfig loads the configuration from the app.conf file located in the config folder
-func LoadConfig() error {
+// Load reads the configuration from the app.conf file located in the config
+// folder, with environment variables taking precedence
+func Load() (*Config, error) {
 	// Get the absolute path to the config folder and file
 	configFilePath := filepath.Join("config", "app.conf")
 
 	// Check if the file exists
 	_, err := os.Stat(configFilePath)
 	if err != nil {
-		log.Fatalf("Error opening config file: %v", err)
-		return err
+		return nil, fmt.Errorf("error opening config file: %w", err)
 	}
 
 	// Parse the INI configuration file
-	cfg, err := ini.Load(configFilePath)
+	file, err := ini.Load(configFilePath)
 	if err != nil {
-		log.Fatalf("Error loading config file: %v", err)
-		return err
+		return nil, fmt.Errorf("error loading config file: %w", err)
 	}
 
+	cfg := &Config{}
+
+	// Load the database driver: mysql, sqlite or memory (in-memory SQLite)
+	cfg.Database.Driver = strings.ToLower(getEnv("DB_DRIVER", file.Section("database").Key("driver").String(), "mysql"))
+	cfg.Database.SQLitePath = getEnv("DB_SQLITE_PATH", file.Section("database").Key("sqlite_path").String(), "service_booking.db")
+	cfg.Database.ReadTimeout = getEnv("DB_READ_TIMEOUT", file.Section("database").Key("read_timeout").String(), "5s")
+	cfg.Database.WriteTimeout = getEnv("DB_WRITE_TIMEOUT", file.Section("database").Key("write_timeout").String(), "10s")
+
 	// Load MySQL configuration with environment variable fallback
-	AppConfig.MySQL.Host = getEnv("DB_HOST", cfg.Section("mysql").Key("db_host").String(), "localhost")
-	AppConfig.MySQL.Port = getEnv("DB_PORT", cfg.Section("mysql").Key("db_port").String(), "3306")
-	AppConfig.MySQL.User = getEnv("DB_USER", cfg.Section("mysql").Key("db_user").String(), "root")
-	AppConfig.MySQL.Password = getEnv("DB_PASSWORD", cfg.Section("mysql").Key("db_password").String(), "")
-	AppConfig.MySQL.Name = getEnv("DB_NAME", cfg.Section("mysql").Key("db_name").String(), "sheba_service_booking_db")
+	cfg.MySQL.Host = getEnv("DB_HOST", file.Section("mysql").Key("db_host").String(), "localhost")
+	cfg.MySQL.Port = getEnv("DB_PORT", file.Section("mysql").Key("db_port").String(), "3306")
+	cfg.MySQL.User = getEnv("DB_USER", file.Section("mysql").Key("db_user").String(), "root")
+	cfg.MySQL.Password = getEnv("DB_PASSWORD", file.Section("mysql").Key("db_password").String(), "")
+	cfg.MySQL.Name = getEnv("DB_NAME", file.Section("mysql").Key("db_name").String(), "sheba_service_booking_db")
 
 	// Generate connection string using environment variables
-	AppConfig.MySQL.ServiceBookingDBConn = fmt.Sprintf(
-		"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		AppConfig.MySQL.User,
-		AppConfig.MySQL.Host,
-		AppConfig.MySQL.Port,
-		AppConfig.MySQL.Name,
-	)
+	cfg.MySQL.ServiceBookingDBConn = cfg.MySQLConfigString()
 
 	// Load HTTP port
-	httpport, err := cfg.Section("").Key("httpport").Int()
+	httpport, err := file.Section("").Key("httpport").Int()
 	if err != nil {
-		log.Printf("Error parsing httpport: %v, using default", err)
-		AppConfig.HttpPort = 8087
+		slog.Warn("Error parsing httpport, using default", "error", err)
+		cfg.HttpPort = 8087
 	} else {
-		AppConfig.HttpPort = httpport
+		cfg.HttpPort = httpport
 	}
 
+	// Load HTTP server timeouts
+	cfg.Server.ReadTimeout = getEnv("SERVER_READ_TIMEOUT", file.Section("server").Key("read_timeout").String(), "15s")
+	cfg.Server.ReadHeaderTimeout = getEnv("SERVER_READ_HEADER_TIMEOUT", file.Section("server").Key("read_header_timeout").String(), "5s")
+	cfg.Server.WriteTimeout = getEnv("SERVER_WRITE_TIMEOUT", file.Section("server").Key("write_timeout").String(), "30s")
+	cfg.Server.IdleTimeout = getEnv("SERVER_IDLE_TIMEOUT", file.Section("server").Key("idle_timeout").String(), "60s")
+	cfg.Server.ShutdownTimeout = getEnv("SERVER_SHUTDOWN_TIMEOUT", file.Section("server").Key("shutdown_timeout").String(), "20s")
+	cfg.Server.TrustedProxies = getEnvList("TRUSTED_PROXIES", file.Section("server").Key("trusted_proxies").String(), "")
+
+	// Load logging configuration
+	cfg.Log.Level = strings.ToLower(getEnv("LOG_LEVEL", file.Section("log").Key("level").String(), "info"))
+	cfg.Log.Format = strings.ToLower(getEnv("LOG_FORMAT", file.Section("log").Key("format").String(), "json"))
+
+	// Load tracing configuration
+	cfg.Tracing.Exporter = strings.ToLower(getEnv("TRACING_EXPORTER", file.Section("tracing").Key("exporter").String(), "none"))
+	cfg.Tracing.Endpoint = getEnv("TRACING_ENDPOINT", file.Section("tracing").Key("endpoint").String(), "localhost:4318")
+	cfg.Tracing.Insecure = getEnvBool("TRACING_INSECURE", file.Section("tracing").Key("insecure").String(), true)
+	cfg.Tracing.ServiceName = getEnv("TRACING_SERVICE_NAME", file.Section("tracing").Key("service_name").String(), "service-booking")
+	cfg.Tracing.SampleRatio = getEnv("TRACING_SAMPLE_RATIO", file.Section("tracing").Key("sample_ratio").String(), "1")
+
+	// Load metrics configuration
+	cfg.Metrics.Token = getEnv("METRICS_TOKEN", file.Section("metrics").Key("token").String(), "")
+
 	// Load JWT configuration
-	AppConfig.JWT.SecretKey = getEnv("JWT_SECRET_KEY", cfg.Section("").Key("jwt_secret_key").String(), "")
-	AppConfig.JWT.AccessTokenDuration = getEnv("ACCESS_TOKEN_DURATION", cfg.Section("").Key("access_token_duration").String(), "24h")
-	AppConfig.JWT.RefreshTokenDuration = getEnv("REFRESH_TOKEN_DURATION", cfg.Section("").Key("refresh_token_duration").String(), "168h")
+	cfg.JWT.SecretKey = getEnv("JWT_SECRET_KEY", file.Section("").Key("jwt_secret_key").String(), "")
+	cfg.JWT.AccessTokenDuration = getEnv("ACCESS_TOKEN_DURATION", file.Section("").Key("access_token_duration").String(), "24h")
+	cfg.JWT.RefreshTokenDuration = getEnv("REFRESH_TOKEN_DURATION", file.Section("").Key("refresh_token_duration").String(), "168h")
 
-	// Logging for debugging
-	log.Printf("MySQL Host: %s", AppConfig.MySQL.Host)
-	log.Printf("MySQL Port: %s", AppConfig.MySQL.Port)
-	log.Printf("MySQL Database: %s", AppConfig.MySQL.Name)
-	log.Printf("Connection String: %s", maskConnectionString(AppConfig.MySQL.ServiceBookingDBConn))
+	// Load OTP configuration
+	cfg.OTP.Length = getEnvInt("OTP_LENGTH", file.Section("otp").Key("length").String(), 6)
+	cfg.OTP.TTL = getEnv("OTP_TTL", file.Section("otp").Key("ttl").String(), "5m")
+	cfg.OTP.MaxAttempts = getEnvInt("OTP_MAX_ATTEMPTS", file.Section("otp").Key("max_attempts").String(), 5)
+	cfg.OTP.ResendInterval = getEnv("OTP_RESEND_INTERVAL", file.Section("otp").Key("resend_interval").String(), "1m")
+	cfg.OTP.DefaultCountryCode = getEnv("OTP_DEFAULT_COUNTRY_CODE", file.Section("otp").Key("default_country_code").String(), "880")
 
-	return nil
-}
+	// Load SMS delivery configuration
+	cfg.SMS.Driver = strings.ToLower(getEnv("SMS_DRIVER", file.Section("sms").Key("driver").String(), "fake"))
+	cfg.SMS.AccountSID = getEnv("SMS_ACCOUNT_SID", file.Section("sms").Key("account_sid").String(), "")
+	cfg.SMS.AuthToken = getEnv("SMS_AUTH_TOKEN", file.Section("sms").Key("auth_token").String(), "")
+	cfg.SMS.From = getEnv("SMS_FROM", file.Section("sms").Key("from").String(), "")
 
+	// Load login throttling configuration
+	cfg.LoginThrottle.Store = getEnv("LOGIN_THROTTLE_STORE", file.Section("login_throttle").Key("store").String(), "memory")
+	cfg.LoginThrottle.FreeAttempts = getEnvInt("LOGIN_THROTTLE_FREE_ATTEMPTS", file.Section("login_throttle").Key("free_attempts").String(), 3)
+	cfg.LoginThrottle.MaxFailures = getEnvInt("LOGIN_THROTTLE_MAX_FAILURES", file.Section("login_throttle").Key("max_failures").String(), 10)
+	cfg.LoginThrottle.IPMaxFailures = getEnvInt("LOGIN_THROTTLE_IP_MAX_FAILURES", file.Section("login_throttle").Key("ip_max_failures").String(), 100)
+	cfg.LoginThrottle.BaseDelay = getEnv("LOGIN_THROTTLE_BASE_DELAY", file.Section("login_throttle").Key("base_delay").String(), "1s")
+	cfg.LoginThrottle.MaxDelay = getEnv("LOGIN_THROTTLE_MAX_DELAY", file.Section("login_throttle").Key("max_delay").String(), "5m")
+	cfg.LoginThrottle.LockoutDuration = getEnv("LOGIN_THROTTLE_LOCKOUT_DURATION", file.Section("login_throttle").Key("lockout_duration").String(), "15m")
+	cfg.LoginThrottle.Window = getEnv("LOGIN_THROTTLE_WINDOW", file.Section("login_throttle").Key("window").String(), "15m")
 
-// String returns the value of a configuration key as a string
-func String(key string) string {
-	log.Printf("Fetching config for key: %s", key)
-	switch key {
-	case "httpport":
-		if AppConfig.HttpPort == 0 {
-			log.Printf("Warning: httpport is 0, using default 8087")
-			return "8087"
-		}
-		return fmt.Sprintf("%d", AppConfig.HttpPort)
-	case "jwt_secret_key":
-		// Check environment variable first
-		if secretKey := os.Getenv("JWT_SECRET_KEY"); secretKey != "" {
-			return secretKey
-		}
-		
-		// Fallback to AppConfig
-		if AppConfig.JWT.SecretKey != "" {
-			return AppConfig.JWT.SecretKey
-		}
-		
-		// Generate a fallback secret key
-		return generateFallbackSecretKey()
-	case "access_token_duration":
-		// Check environment variable first
-		if duration := os.Getenv("ACCESS_TOKEN_DURATION"); duration != "" {
-			return duration
-		}
-		
-		// Fallback to AppConfig
-		if AppConfig.JWT.AccessTokenDuration != "" {
-			return AppConfig.JWT.AccessTokenDuration
+	// Load rate limiting configuration
+	cfg.RateLimit.Store = getEnv("RATE_LIMIT_STORE", file.Section("rate_limit").Key("store").String(), "memory")
+	cfg.RateLimit.Auth = getEnv("RATE_LIMIT_AUTH", file.Section("rate_limit").Key("auth").String(), "10/1m")
+	cfg.RateLimit.Booking = getEnv("RATE_LIMIT_BOOKING", file.Section("rate_limit").Key("booking").String(), "20/1m")
+	cfg.RateLimit.Catalog = getEnv("RATE_LIMIT_CATALOG", file.Section("rate_limit").Key("catalog").String(), "300/1m")
+	cfg.RateLimit.Default = getEnv("RATE_LIMIT_DEFAULT", file.Section("rate_limit").Key("default").String(), "120/1m")
+	cfg.RateLimit.Lookup = getEnv("RATE_LIMIT_LOOKUP", file.Section("rate_limit").Key("lookup").String(), "10/10m")
+
+	// Load booking reference code configuration
+	cfg.BookingReference.Prefix = getEnv("BOOKING_REFERENCE_PREFIX", file.Section("booking_reference").Key("prefix").String(), "SB")
+	cfg.BookingReference.Length = getEnvInt("BOOKING_REFERENCE_LENGTH", file.Section("booking_reference").Key("length").String(), 8)
+
+	// Load guest booking access configuration
+	cfg.GuestAccess.LinkBaseURL = getEnv("GUEST_ACCESS_LINK_BASE_URL", file.Section("guest_access").Key("link_base_url").String(), "http://localhost:3000/bookings/lookup")
+	cfg.GuestAccess.TokenTTL = getEnv("GUEST_ACCESS_TOKEN_TTL", file.Section("guest_access").Key("token_ttl").String(), "720h")
+
+	// Load idempotency configuration
+	cfg.Idempotency.TTL = getEnv("IDEMPOTENCY_TTL", file.Section("idempotency").Key("ttl").String(), "24h")
+
+	// Load CORS configuration, with overrides from [cors.<environment>]
+	env := getEnv("GO_ENV", "", "development")
+	cfg.Env = env
+	cfg.CORS.AllowedOrigins = getEnvList("CORS_ALLOWED_ORIGINS", sectionValue(file, "cors", env, "allowed_origins"), "")
+	cfg.CORS.AllowedMethods = getEnvList("CORS_ALLOWED_METHODS", sectionValue(file, "cors", env, "allowed_methods"), "GET,POST,PUT,PATCH,DELETE,OPTIONS")
+	cfg.CORS.AllowedHeaders = getEnvList("CORS_ALLOWED_HEADERS", sectionValue(file, "cors", env, "allowed_headers"), "Authorization,Content-Type,X-API-Key,X-Refresh-Token,Idempotency-Key,X-Guest-Token,X-Request-ID,traceparent,tracestate")
+	cfg.CORS.ExposedHeaders = getEnvList("CORS_EXPOSED_HEADERS", sectionValue(file, "cors", env, "exposed_headers"), "RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy,Retry-After,Idempotent-Replayed,X-Request-ID")
+	cfg.CORS.AllowCredentials = getEnvBool("CORS_ALLOW_CREDENTIALS", sectionValue(file, "cors", env, "allow_credentials"), false)
+	cfg.CORS.MaxAge = getEnv("CORS_MAX_AGE", sectionValue(file, "cors", env, "max_age"), "10m")
+
+	// Load security headers configuration, with overrides from [security_headers.<environment>]
+	cfg.SecurityHeaders.HSTSMaxAge = getEnv("HSTS_MAX_AGE", sectionValue(file, "security_headers", env, "hsts_max_age"), "0")
+	cfg.SecurityHeaders.HSTSIncludeSubdomains = getEnvBool("HSTS_INCLUDE_SUBDOMAINS", sectionValue(file, "security_headers", env, "hsts_include_subdomains"), false)
+	cfg.SecurityHeaders.FrameOptions = getEnv("FRAME_OPTIONS", sectionValue(file, "security_headers", env, "frame_options"), "DENY")
+	cfg.SecurityHeaders.ContentSecurityPolicy = getEnv("CONTENT_SECURITY_POLICY", sectionValue(file, "security_headers", env, "content_security_policy"), "default-src 'none'; frame-ancestors 'none'")
+	cfg.SecurityHeaders.ReferrerPolicy = getEnv("REFERRER_POLICY", sectionValue(file, "security_headers", env, "referrer_policy"), "no-referrer")
+
+	// Load OIDC providers from [oidc.<name>] sections
+	cfg.OIDCProviders = make(map[string]OIDCProvider)
+	for _, section := range file.Sections() {
+		if !strings.HasPrefix(section.Name(), "oidc.") {
+			continue
 		}
-		
-		// Default duration
-		return "24h"
-	case "refresh_token_duration":
-		// Check environment variable first
-		if duration := os.Getenv("REFRESH_TOKEN_DURATION"); duration != "" {
-			return duration
+
+		name := strings.TrimPrefix(section.Name(), "oidc.")
+		envPrefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OIDCProvider{
+			Issuer:       getEnv(envPrefix+"ISSUER", section.Key("issuer").String(), ""),
+			ClientID:     getEnv(envPrefix+"CLIENT_ID", section.Key("client_id").String(), ""),
+			ClientSecret: getEnv(envPrefix+"CLIENT_SECRET", section.Key("client_secret").String(), ""),
+			RedirectURL:  getEnv(envPrefix+"REDIRECT_URL", section.Key("redirect_url").String(), ""),
+			Scopes:       strings.Fields(getEnv(envPrefix+"SCOPES", section.Key("scopes").String(), "openid email profile")),
 		}
-		
-		// Fallback to AppConfig
-		if AppConfig.JWT.RefreshTokenDuration != "" {
-			return AppConfig.JWT.RefreshTokenDuration
+
+		if provider.Issuer == "" || provider.ClientID == "" {
+			slog.Warn("Skipping OIDC provider: issuer and client_id are required", "provider", name)
+			continue
 		}
-		
-		// Default duration
-		return "168h"
+		cfg.OIDCProviders[name] = provider
+	}
+
+	// Logging for debugging
+	switch cfg.Database.Driver {
+	case "sqlite":
+		slog.Info("Database configured", "driver", cfg.Database.Driver, "sqlite_path", cfg.Database.SQLitePath)
+	case "mysql":
+		slog.Info("Database configured",
+			"driver", cfg.Database.Driver,
+			"mysql_host", cfg.MySQL.Host,
+			"mysql_port", cfg.MySQL.Port,
+			"mysql_database", cfg.MySQL.Name,
+			"connection", maskConnectionString(cfg.MySQL.ServiceBookingDBConn),
+		)
 	default:
-		log.Printf("Unknown key '%s'", key)
-		return ""
+		slog.Info("Database configured", "driver", cfg.Database.Driver)
 	}
-}
 
-// generateFallbackSecretKey creates a fallback secret key
-func generateFallbackSecretKey() string {
-	log.Println("WARNING: Using generated fallback secret key. Set JWT_SECRET_KEY in production!")
-	return fmt.Sprintf("fallback-secret-key-%d", time.Now().UnixNano())
+	return cfg, nil
 }
 
 // getEnv retrieves the value of the environment variable
@@ -163,6 +316,56 @@ func getEnv(envVar, configValue, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvInt retrieves an integer value from the environment or config file
+func getEnvInt(envVar, configValue string, defaultValue int) int {
+	value := getEnv(envVar, configValue, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Error parsing config value, using default", "variable", envVar, "error", err)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvBool retrieves a boolean value from the environment or config file
+func getEnvBool(envVar, configValue string, defaultValue bool) bool {
+	value := getEnv(envVar, configValue, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("Error parsing config value, using default", "variable", envVar, "error", err)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvList retrieves a comma separated list from the environment or config file
+func getEnvList(envVar, configValue, defaultValue string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(envVar, configValue, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// sectionValue reads a key from the section of the current environment
+// (e.g. [cors.production]), falling back to the base section
+func sectionValue(cfg *ini.File, section, env, key string) string {
+	if envSection, err := cfg.GetSection(section + "." + env); err == nil && envSection.HasKey(key) {
+		return envSection.Key(key).String()
+	}
+	return cfg.Section(section).Key(key).String()
+}
+
 // maskConnectionString masks sensitive information in the connection string
 func maskConnectionString(dsn string) string {
 	// Simple masking of password
@@ -170,26 +373,26 @@ func maskConnectionString(dsn string) string {
 }
 
 // MySQLConfigString generates the MySQL connection string
-func MySQLConfigString() string {
+func (cfg *Config) MySQLConfigString() string {
 	// For a connection without password
-	if AppConfig.MySQL.Password == "" {
+	if cfg.MySQL.Password == "" {
 		return fmt.Sprintf(
 			"%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-			AppConfig.MySQL.User,
-			AppConfig.MySQL.Host,
-			AppConfig.MySQL.Port,
-			AppConfig.MySQL.Name,
+			cfg.MySQL.User,
+			cfg.MySQL.Host,
+			cfg.MySQL.Port,
+			cfg.MySQL.Name,
 		)
 	}
 
 	// For a connection with password
 	return fmt.Sprintf(
 		"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		AppConfig.MySQL.User,
-		AppConfig.MySQL.Password,
-		AppConfig.MySQL.Host,
-		AppConfig.MySQL.Port,
-		AppConfig.MySQL.Name,
+		cfg.MySQL.User,
+		cfg.MySQL.Password,
+		cfg.MySQL.Host,
+		cfg.MySQL.Port,
+		cfg.MySQL.Name,
 	)
 }
 
@@ -1,93 +1,139 @@
 package db
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"log"
+	"log/slog"
 	"service-booking/config"
-	"sync"
+	"service-booking/pkg/logging"
 	"time"
-	"os"
+	"strings"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-var (
-	DB     *gorm.DB
-	once   sync.Once
-	dbLock sync.Mutex
-)
+// memoryDSN returns a uniquely named in-memory database for the memory
+// driver. Every connection to a named shared-cache memory database sees the
+// same data, so each Open gets its own name.
+func memoryDSN() string {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		panic(fmt.Sprintf("failed to name the in-memory database: %v", err))
+	}
+	return "file:service_booking_" + hex.EncodeToString(suffix) + "?mode=memory&cache=shared"
+}
 
-// GetDB returns the GORM database connection
-func GetDB() *gorm.DB {
-	dbLock.Lock()
-	defer dbLock.Unlock()
+// Open opens the database of the configured driver
+func Open(cfg *config.Config) (*gorm.DB, error) {
+	var (
+		conn *gorm.DB
+		err  error
+	)
 
-	once.Do(func() {
-		RegisterMySQL()
-	})
+	switch cfg.Database.Driver {
+	case "sqlite":
+		conn, err = OpenSQLite(cfg.Database.SQLitePath)
+	case "memory":
+		conn, err = OpenSQLite(memoryDSN())
+	case "mysql", "":
+		conn, err = OpenMySQL(cfg.MySQLConfigString())
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Database.Driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening DB connection: %w", err)
+	}
 
-	return DB
+	slog.Info("Connected to database", "dialect", Dialect(cfg), "driver", cfg.Database.Driver)
+	return conn, nil
 }
 
-// RegisterMySQL registers a MySQL database connection using GORM
-func RegisterMySQL() {
-	var err error
-	
-	// Create a custom logger
-	newLogger := logger.New(
-		log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
-		logger.Config{
-			SlowThreshold:             time.Second,   // Slow SQL threshold
-			LogLevel:                  logger.Silent, // Log level
-			IgnoreRecordNotFoundError: true,          // Ignore ErrRecordNotFound error for logger
-			ParameterizedQueries:      true,          // Don't include params in the SQL log
-		},
-	)
+// Dialect returns the SQL dialect of the configured driver
+func Dialect(cfg *config.Config) string {
+	switch cfg.Database.Driver {
+	case "sqlite", "memory":
+		return "sqlite"
+	}
+	return "mysql"
+}
 
-	// Configuration for connection
-	dsn := config.MySQLConfigString() // Get the connection string from config
-	
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: newLogger,
+// OpenSQLite opens a SQLite database with the pool settings it needs. It
+// needs no database server and is meant for local development and tests.
+func OpenSQLite(dsn string) (*gorm.DB, error) {
+	// Enforce foreign keys like MySQL and wait for locks instead of failing
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	dsn += separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         newLogger(),
+		TranslateError: true, // Report unique index violations as gorm.ErrDuplicatedKey
 	})
-	
 	if err != nil {
-		log.Fatalf("Error opening DB connection: %v", err)
+		return nil, err
+	}
+
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows one writer at a time, so a single connection avoids
+	// "database is locked" errors and keeps an in-memory database alive
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+
+	return conn, nil
+}
+
+// OpenMySQL opens a MySQL database connection using GORM
+func OpenMySQL(dsn string) (*gorm.DB, error) {
+	conn, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		Logger:         newLogger(),
+		TranslateError: true, // Report unique index violations as gorm.ErrDuplicatedKey
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Set connection pool parameters
-	sqlDB, err := DB.DB()
+	sqlDB, err := conn.DB()
 	if err != nil {
-		log.Fatalf("Error getting underlying DB: %v", err)
+		return nil, fmt.Errorf("error getting underlying DB: %w", err)
 	}
 
 	// Connection pool configuration
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
-	
-	fmt.Println("Connected to MySQL database using GORM")
-
-	// Optional: Auto Migrate models
-	// Uncomment and add your models
-	// err = DB.AutoMigrate(&model.User{}, &model.Service{}, &model.Booking{})
-	// if err != nil {
-	// 	log.Fatalf("Error auto-migrating database: %v", err)
-	// }
+
+	// The schema is managed by the migrate subcommand, see db/migrations
+	return conn, nil
 }
 
-// CloseDB closes the MySQL database connection
-func CloseDB() {
-	sqlDB, err := DB.DB()
+// newLogger creates the GORM logger. Queries are logged to the logger of
+// their context: failures as errors, queries slower than a second as
+// warnings and the rest at debug level, without their parameters.
+func newLogger() logger.Interface {
+	return logging.NewGormLogger(time.Second)
+}
+
+// Close closes the database connection
+func Close(conn *gorm.DB) {
+	sqlDB, err := conn.DB()
 	if err != nil {
-		log.Println("Error getting underlying DB:", err)
+		slog.Error("Error getting underlying DB", "error", err)
 		return
 	}
 	
 	if err := sqlDB.Close(); err != nil {
-		log.Println("Error closing DB connection:", err)
+		slog.Error("Error closing DB connection", "error", err)
 	} else {
-		fmt.Println("MySQL database connection closed")
+		slog.Info("Database connection closed")
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"testing"
+
+	"service-booking/config"
+)
+
+func TestOpenMemoryDatabasesAreSeparate(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Database.Driver = "memory"
+
+	first, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer Close(first)
+	second, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer Close(second)
+
+	if err := first.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)").Error; err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	if second.Migrator().HasTable("items") {
+		t.Error("in-memory databases opened separately share tables")
+	}
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"fmt"
+	"service-booking/config"
+	"service-booking/db/migrations"
+	"service-booking/pkg/migrate"
+
+	"gorm.io/gorm"
+)
+
+// NewMigrator returns a migrator for the embedded schema migrations
+func NewMigrator(conn *gorm.DB, cfg *config.Config) (*migrate.Migrator, error) {
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	dialect := Dialect(cfg)
+	scripts, err := migrations.For(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	// The lock is server-wide, so it is named after the database
+	return migrate.New(sqlDB, scripts,
+		migrate.WithDialect(migrate.Dialect(dialect)),
+		migrate.WithLockName(cfg.MySQL.Name+".schema_migrations"))
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"service-booking/config"
+	"service-booking/db/migrations"
+	"service-booking/pkg/migrate"
+)
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	conn, err := OpenSQLite("file:migrate_round_trip?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	cfg := &config.Config{}
+	cfg.Database.Driver = "memory"
+	migrator, err := NewMigrator(conn, cfg)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if _, err := migrator.Down(ctx, len(applied)); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up after down: %v", err)
+	}
+}
+
+func TestDialectsHaveSameVersions(t *testing.T) {
+	versions := make(map[string][]int64)
+	for _, dialect := range []string{"mysql", "sqlite"} {
+		scripts, err := migrations.For(dialect)
+		if err != nil {
+			t.Fatalf("%s: %v", dialect, err)
+		}
+		loaded, err := migrate.Load(scripts)
+		if err != nil {
+			t.Fatalf("%s: %v", dialect, err)
+		}
+		for _, migration := range loaded {
+			if migration.Down == "" {
+				t.Errorf("%s: migration %d has no down script", dialect, migration.Version)
+			}
+			versions[dialect] = append(versions[dialect], migration.Version)
+		}
+	}
+
+	if len(versions["mysql"]) != len(versions["sqlite"]) {
+		t.Fatalf("mysql has versions %v, sqlite has %v", versions["mysql"], versions["sqlite"])
+	}
+	for i := range versions["mysql"] {
+		if versions["mysql"][i] != versions["sqlite"][i] {
+			t.Fatalf("mysql has versions %v, sqlite has %v", versions["mysql"], versions["sqlite"])
+		}
+	}
+}
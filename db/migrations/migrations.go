@@ -0,0 +1,23 @@
+// Package migrations embeds the SQL migrations of the database schema, one
+// directory per SQL dialect. Both dialects must have the same versions. New
+// files must follow the naming of pkg/migrate and never be edited once
+// released; add a new migration instead.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed mysql/*.sql sqlite/*.sql
+var files embed.FS
+
+// For returns the migration scripts written for the dialect
+func For(dialect string) (fs.FS, error) {
+	switch dialect {
+	case "mysql", "sqlite":
+		return fs.Sub(files, dialect)
+	}
+	return nil, fmt.Errorf("no migrations for dialect %q", dialect)
+}
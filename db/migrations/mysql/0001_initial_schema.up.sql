@@ -1,9 +1,9 @@
--- filepath: /Users/jisanahmed/Desktop/sheba claude/.init.sql
-CREATE DATABASE IF NOT EXISTS sheba_service_booking_db;
-USE sheba_service_booking_db;
+-- Baseline schema, exactly as created by the former init.sql. Tables are
+-- only created when missing so databases initialised from init.sql can adopt
+-- migrations; later migrations bring both up to date.
 
 -- Users table
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
   id INT NOT NULL AUTO_INCREMENT,
   name VARCHAR(255) NOT NULL,
   email VARCHAR(255) NOT NULL,
@@ -19,7 +19,7 @@ CREATE TABLE users (
 );
 
 -- Categories table
-CREATE TABLE categories (
+CREATE TABLE IF NOT EXISTS categories (
   id INT NOT NULL AUTO_INCREMENT,
   name VARCHAR(100) NOT NULL,
   description TEXT,
@@ -36,7 +36,7 @@ CREATE TABLE categories (
 );
 
 -- Services table
-CREATE TABLE services (
+CREATE TABLE IF NOT EXISTS services (
   id INT NOT NULL AUTO_INCREMENT,
   name VARCHAR(255) NOT NULL,
   description TEXT,
@@ -53,7 +53,7 @@ CREATE TABLE services (
 );
 
 -- Bookings table
-CREATE TABLE bookings (
+CREATE TABLE IF NOT EXISTS bookings (
   id INT NOT NULL AUTO_INCREMENT,
   service_id INT NOT NULL,
   user_name VARCHAR(255) NOT NULL,
@@ -79,7 +79,7 @@ CREATE TABLE bookings (
 );
 
 -- Booking status history table
-CREATE TABLE booking_status_history (
+CREATE TABLE IF NOT EXISTS booking_status_history (
   id INT NOT NULL AUTO_INCREMENT,
   booking_id INT NOT NULL,
   status ENUM('pending','confirmed','in_progress','completed','cancelled') NOT NULL,
@@ -93,4 +93,4 @@ CREATE TABLE booking_status_history (
   KEY fk_status_history_user (created_by),
   CONSTRAINT fk_status_history_booking FOREIGN KEY (booking_id) REFERENCES bookings(id),
   CONSTRAINT fk_status_history_user FOREIGN KEY (created_by) REFERENCES users(id)
-);
\ No newline at end of file
+);
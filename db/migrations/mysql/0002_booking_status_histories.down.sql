@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS booking_status_history (
+  id INT NOT NULL AUTO_INCREMENT,
+  booking_id INT NOT NULL,
+  status ENUM('pending','confirmed','in_progress','completed','cancelled') NOT NULL,
+  created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+  is_active TINYINT(1) DEFAULT NULL,
+  notes TEXT,
+  created_by INT DEFAULT NULL,
+  estimated_completion_time DATETIME DEFAULT NULL,
+  PRIMARY KEY (id),
+  KEY fk_status_history_booking (booking_id),
+  KEY fk_status_history_user (created_by),
+  CONSTRAINT fk_status_history_booking FOREIGN KEY (booking_id) REFERENCES bookings(id),
+  CONSTRAINT fk_status_history_user FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+DROP TABLE IF EXISTS booking_status_histories;
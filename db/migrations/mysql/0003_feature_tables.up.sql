@@ -0,0 +1,142 @@
+-- Tables added for phone login, roles, API keys, social login, login
+-- throttling, two-factor authentication, sessions, rate limiting and
+-- idempotent requests.
+
+-- One-time login codes table
+CREATE TABLE IF NOT EXISTS otp_codes (
+  id INT NOT NULL AUTO_INCREMENT,
+  phone VARCHAR(20) NOT NULL,
+  code_hash VARCHAR(255) NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  expires_at DATETIME NOT NULL,
+  consumed_at DATETIME DEFAULT NULL,
+  created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  KEY idx_otp_phone (phone)
+);
+
+-- Roles table
+CREATE TABLE IF NOT EXISTS roles (
+  id INT NOT NULL AUTO_INCREMENT,
+  name VARCHAR(50) NOT NULL,
+  description TEXT,
+  permissions TEXT,
+  is_system TINYINT(1) DEFAULT 0,
+  created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY name (name)
+);
+
+INSERT IGNORE INTO roles (name, description, permissions, is_system) VALUES
+  ('admin', 'Full access to every resource', '["booking:read","booking:update_status","service:write","category:write","refund:approve","role:manage","api_key:manage","user:unlock"]', 1),
+  ('ops_agent', 'Operations agent managing bookings and the catalog', '["booking:read","booking:update_status","service:write","category:write"]', 1),
+  ('finance', 'Finance team handling payments and refunds', '["booking:read","refund:approve"]', 1),
+  ('support', 'Customer support agent', '["booking:read","booking:update_status","user:unlock"]', 1),
+  ('provider', 'Service provider fulfilling bookings', '[]', 1),
+  ('user', 'Customer', '[]', 1);
+
+-- Partner API keys table
+CREATE TABLE IF NOT EXISTS api_keys (
+  id INT NOT NULL AUTO_INCREMENT,
+  name VARCHAR(100) NOT NULL,
+  prefix VARCHAR(20) NOT NULL,
+  key_hash VARCHAR(64) NOT NULL,
+  organization VARCHAR(255) NOT NULL,
+  scopes TEXT,
+  user_id INT NOT NULL,
+  created_by INT DEFAULT NULL,
+  expires_at DATETIME DEFAULT NULL,
+  last_used_at DATETIME DEFAULT NULL,
+  revoked_at DATETIME DEFAULT NULL,
+  created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY prefix (prefix),
+  KEY idx_api_key_user (user_id),
+  CONSTRAINT fk_api_key_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- External identity provider accounts table
+CREATE TABLE IF NOT EXISTS user_identities (
+  id INT NOT NULL AUTO_INCREMENT,
+  user_id INT NOT NULL,
+  provider VARCHAR(50) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  email VARCHAR(255) DEFAULT NULL,
+  created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY idx_identity_provider_subject (provider, subject),
+  KEY idx_identity_user (user_id),
+  CONSTRAINT fk_identity_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Failed login counters table
+CREATE TABLE IF NOT EXISTS login_attempts (
+  id INT NOT NULL AUTO_INCREMENT,
+  throttle_key VARCHAR(255) NOT NULL,
+  failures INT NOT NULL DEFAULT 0,
+  last_failure_at DATETIME DEFAULT NULL,
+  locked_until DATETIME DEFAULT NULL,
+  created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY throttle_key (throttle_key)
+);
+
+-- Two-factor recovery codes table
+CREATE TABLE IF NOT EXISTS recovery_codes (
+  id INT NOT NULL AUTO_INCREMENT,
+  user_id INT NOT NULL,
+  code_hash VARCHAR(255) NOT NULL,
+  used_at DATETIME DEFAULT NULL,
+  created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  KEY idx_recovery_code_user (user_id),
+  CONSTRAINT fk_recovery_code_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Login sessions table
+CREATE TABLE IF NOT EXISTS sessions (
+  id INT NOT NULL AUTO_INCREMENT,
+  user_id INT NOT NULL,
+  user_agent VARCHAR(512) DEFAULT NULL,
+  ip_address VARCHAR(45) DEFAULT NULL,
+  last_refreshed_at DATETIME DEFAULT NULL,
+  expires_at DATETIME NOT NULL,
+  revoked_at DATETIME DEFAULT NULL,
+  created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  KEY idx_session_user (user_id),
+  CONSTRAINT fk_session_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Rate limit token buckets table
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+  id INT NOT NULL AUTO_INCREMENT,
+  bucket_key VARCHAR(255) NOT NULL,
+  tokens DOUBLE NOT NULL,
+  refilled_at DATETIME(3) NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY bucket_key (bucket_key)
+);
+
+-- Idempotent request responses table
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  id INT NOT NULL AUTO_INCREMENT,
+  scope VARCHAR(255) NOT NULL,
+  idempotency_key VARCHAR(255) NOT NULL,
+  fingerprint VARCHAR(64) NOT NULL,
+  status_code INT DEFAULT NULL,
+  content_type VARCHAR(255) DEFAULT NULL,
+  response_body MEDIUMTEXT,
+  completed_at DATETIME DEFAULT NULL,
+  expires_at DATETIME NOT NULL,
+  created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY idx_idempotency_scope_key (scope, idempotency_key),
+  KEY idx_idempotency_expires (expires_at)
+);
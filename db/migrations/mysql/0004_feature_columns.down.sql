@@ -0,0 +1,15 @@
+ALTER TABLE bookings
+  DROP FOREIGN KEY fk_booking_provider,
+  DROP KEY idx_booking_provider,
+  DROP COLUMN provider_id;
+
+ALTER TABLE categories
+  DROP COLUMN reference_prefix;
+
+-- Roles other than admin and user must be reassigned before reverting
+ALTER TABLE users
+  DROP KEY idx_user_phone,
+  DROP COLUMN totp_last_step,
+  DROP COLUMN totp_enabled,
+  DROP COLUMN totp_secret,
+  MODIFY role ENUM('admin', 'user') NOT NULL DEFAULT 'user';
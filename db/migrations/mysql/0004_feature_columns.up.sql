@@ -0,0 +1,18 @@
+-- Columns added to the baseline tables: staff roles beyond admin and user,
+-- phone login, two-factor authentication, category reference prefixes and
+-- providers assigned to bookings.
+ALTER TABLE users
+  MODIFY role VARCHAR(50) NOT NULL DEFAULT 'user',
+  ADD COLUMN totp_secret VARCHAR(64) DEFAULT NULL,
+  ADD COLUMN totp_enabled TINYINT(1) DEFAULT 0,
+  ADD COLUMN totp_last_step BIGINT DEFAULT 0,
+  ADD KEY idx_user_phone (phone);
+
+ALTER TABLE categories
+  ADD COLUMN reference_prefix VARCHAR(5) DEFAULT NULL;
+
+ALTER TABLE bookings
+  MODIFY user_id INT DEFAULT NULL,
+  ADD COLUMN provider_id INT DEFAULT NULL AFTER user_id,
+  ADD KEY idx_booking_provider (provider_id),
+  ADD CONSTRAINT fk_booking_provider FOREIGN KEY (provider_id) REFERENCES users(id);
@@ -0,0 +1,3 @@
+-- Requests in progress hold their key until locked_until, so a retry can
+-- take over the key of a request that never finished
+ALTER TABLE idempotency_keys ADD COLUMN locked_until DATETIME DEFAULT NULL;
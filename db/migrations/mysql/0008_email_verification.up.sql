@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN email_verified_at DATETIME DEFAULT NULL;
+
+-- Accounts with a social login had their email verified by the provider
+UPDATE users SET email_verified_at = created_at WHERE id IN (SELECT user_id FROM user_identities);
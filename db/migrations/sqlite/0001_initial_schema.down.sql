@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS booking_status_history;
+DROP TABLE IF EXISTS bookings;
+DROP TABLE IF EXISTS services;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS booking_status_history (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  booking_id INT NOT NULL,
+  status VARCHAR(20) NOT NULL,
+  created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+  is_active TINYINT(1) DEFAULT NULL,
+  notes TEXT,
+  created_by INT DEFAULT NULL,
+  estimated_completion_time DATETIME DEFAULT NULL,
+  CONSTRAINT fk_status_history_booking FOREIGN KEY (booking_id) REFERENCES bookings(id),
+  CONSTRAINT fk_status_history_user FOREIGN KEY (created_by) REFERENCES users(id)
+);
+CREATE INDEX IF NOT EXISTS fk_status_history_booking ON booking_status_history (booking_id);
+CREATE INDEX IF NOT EXISTS fk_status_history_user ON booking_status_history (created_by);
+
+DROP TABLE IF EXISTS booking_status_histories;
@@ -0,0 +1,18 @@
+-- GORM stores model.BookingStatusHistory in booking_status_histories, which
+-- init.sql never created. The unused booking_status_history table is dropped.
+-- created_by is 0 for entries recorded by the system, so it has no foreign key.
+CREATE TABLE IF NOT EXISTS booking_status_histories (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  booking_id INT NOT NULL,
+  status VARCHAR(20) NOT NULL,
+  created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+  is_active TINYINT(1) DEFAULT NULL,
+  notes TEXT,
+  created_by INT NOT NULL DEFAULT 0,
+  estimated_completion_time DATETIME DEFAULT NULL,
+  CONSTRAINT fk_status_histories_booking FOREIGN KEY (booking_id) REFERENCES bookings(id)
+);
+CREATE INDEX IF NOT EXISTS idx_status_history_booking ON booking_status_histories (booking_id);
+CREATE INDEX IF NOT EXISTS idx_status_history_user ON booking_status_histories (created_by);
+
+DROP TABLE IF EXISTS booking_status_history;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_booking_provider;
+ALTER TABLE bookings DROP COLUMN provider_id;
+
+ALTER TABLE categories DROP COLUMN reference_prefix;
+
+DROP INDEX IF EXISTS idx_user_phone;
+ALTER TABLE users DROP COLUMN totp_last_step;
+ALTER TABLE users DROP COLUMN totp_enabled;
+ALTER TABLE users DROP COLUMN totp_secret;
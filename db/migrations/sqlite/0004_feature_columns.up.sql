@@ -0,0 +1,11 @@
+-- SQLite counterpart of mysql/0004_feature_columns.up.sql. Roles are
+-- already VARCHAR and bookings.user_id already nullable.
+ALTER TABLE users ADD COLUMN totp_secret VARCHAR(64) DEFAULT NULL;
+ALTER TABLE users ADD COLUMN totp_enabled TINYINT(1) DEFAULT 0;
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_user_phone ON users (phone);
+
+ALTER TABLE categories ADD COLUMN reference_prefix VARCHAR(5) DEFAULT NULL;
+
+ALTER TABLE bookings ADD COLUMN provider_id INT DEFAULT NULL REFERENCES users(id);
+CREATE INDEX IF NOT EXISTS idx_booking_provider ON bookings (provider_id);
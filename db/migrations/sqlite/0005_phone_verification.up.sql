@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN phone_verified_at DATETIME DEFAULT NULL;
+
+-- Accounts created by OTP login proved their phone number
+UPDATE users SET phone_verified_at = created_at WHERE email LIKE '%@phone.invalid';
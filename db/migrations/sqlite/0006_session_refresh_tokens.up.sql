@@ -0,0 +1,3 @@
+-- Refresh tokens are single use. Sessions started before this migration have
+-- no current token and must log in again.
+ALTER TABLE sessions ADD COLUMN refresh_token_id VARCHAR(64) DEFAULT NULL;
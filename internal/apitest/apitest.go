@@ -0,0 +1,293 @@
+// Package apitest runs the HTTP API end to end against an ephemeral
+// in-memory SQLite database. A Harness builds an app.App and its router with
+// routes.SetupRouter, so tests exercise the same middleware, handlers and
+// repositories as the server. Every harness has its own configuration and
+// database.
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"service-booking/config"
+	"service-booking/db"
+	"service-booking/internal/app"
+	"service-booking/internal/model"
+	"service-booking/pkg/logging"
+	"service-booking/routes"
+)
+
+// Harness is a router backed by a fresh database
+type Harness struct {
+	t      testing.TB
+	App    *app.App
+	Router *gin.Engine
+	DB     *gorm.DB
+
+	// Logs captures the request logs instead of printing them
+	Logs *LogBuffer
+
+	sequence int
+	tokens   map[uint]Tokens
+}
+
+// Option changes the configuration used by a Harness
+type Option func(*config.Config)
+
+// WithOIDCProvider registers a social login provider, e.g. an oidctest server
+func WithOIDCProvider(name, issuer string) Option {
+	return func(cfg *config.Config) {
+		cfg.OIDCProviders[name] = config.OIDCProvider{
+			Issuer:      issuer,
+			ClientID:    "apitest",
+			RedirectURL: "http://localhost/api/v1/auth/oidc/" + name + "/callback",
+			Scopes:      []string{"openid", "email", "profile"},
+		}
+	}
+}
+
+// WithConfig changes the configuration before the router is built
+func WithConfig(change func(*config.Config)) Option {
+	return Option(change)
+}
+
+// New creates a harness with an empty, migrated database
+func New(t testing.TB, opts ...Option) *Harness {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	cfg := Config()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	conn, err := db.Open(cfg)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	migrator, err := db.NewMigrator(conn, cfg)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	application, err := app.New(cfg, conn)
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	logs := &LogBuffer{}
+	if application.Logger, err = logging.New(logs, cfg.Log.Level, cfg.Log.Format); err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	router, err := routes.SetupRouter(application)
+	if err != nil {
+		t.Fatalf("failed to set up routes: %v", err)
+	}
+
+	return &Harness{
+		t:      t,
+		App:    application,
+		Router: router,
+		DB:     conn,
+		Logs:   logs,
+		tokens: make(map[uint]Tokens),
+	}
+}
+
+// MetricsToken is the token scrapers send to /metrics
+const MetricsToken = "apitest-metrics-token"
+
+// Config returns a configuration suited to tests. Rate limits are high
+// enough that a test suite never hits them.
+func Config() *config.Config {
+	cfg := &config.Config{}
+	cfg.Database.Driver = "memory"
+	cfg.Database.ReadTimeout = "5s"
+	cfg.Database.WriteTimeout = "10s"
+	cfg.HttpPort = 8087
+	cfg.JWT.SecretKey = "apitest-secret-key"
+	cfg.JWT.AccessTokenDuration = "1h"
+	cfg.JWT.RefreshTokenDuration = "24h"
+
+	cfg.OTP.Length = 6
+	cfg.OTP.TTL = "5m"
+	cfg.OTP.MaxAttempts = 5
+	cfg.OTP.ResendInterval = "1m"
+	cfg.OTP.DefaultCountryCode = "880"
+	cfg.OIDCProviders = make(map[string]config.OIDCProvider)
+
+	cfg.LoginThrottle.Store = "memory"
+	cfg.LoginThrottle.FreeAttempts = 3
+	cfg.LoginThrottle.MaxFailures = 10
+	cfg.LoginThrottle.IPMaxFailures = 1000
+
+	cfg.RateLimit.Store = "memory"
+	cfg.RateLimit.Auth = "10000/1m"
+	cfg.RateLimit.Booking = "10000/1m"
+	cfg.RateLimit.Catalog = "10000/1m"
+	cfg.RateLimit.Default = "10000/1m"
+	cfg.RateLimit.Lookup = "10000/1m"
+
+	cfg.BookingReference.Prefix = "SB"
+	cfg.BookingReference.Length = 8
+	cfg.GuestAccess.LinkBaseURL = "http://localhost/bookings/lookup"
+	cfg.GuestAccess.TokenTTL = "1h"
+	cfg.Idempotency.TTL = "1h"
+
+	cfg.CORS.AllowedOrigins = []string{"http://localhost:3000"}
+	cfg.CORS.AllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	cfg.CORS.AllowedHeaders = []string{"Authorization", "Content-Type", "X-API-Key", "X-Refresh-Token", "Idempotency-Key", "X-Guest-Token"}
+	cfg.CORS.MaxAge = "10m"
+	cfg.SecurityHeaders.HSTSMaxAge = "0"
+	cfg.SecurityHeaders.FrameOptions = "DENY"
+	cfg.Log.Level = "info"
+	cfg.Log.Format = "json"
+	cfg.Tracing.Exporter = "none"
+	cfg.Tracing.SampleRatio = "1"
+	cfg.Metrics.Token = MetricsToken
+	return cfg
+}
+
+// LogBuffer collects log records written by concurrent requests
+type LogBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// Records decodes the JSON log records written so far
+func (b *LogBuffer) Records() []map[string]any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		var record map[string]any
+		if json.Unmarshal([]byte(line), &record) == nil {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// RequestOption changes a request before it is sent
+type RequestOption func(*http.Request)
+
+// WithToken authenticates the request with a bearer token
+func WithToken(token string) RequestOption {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithHeader sets a request header
+func WithHeader(key, value string) RequestOption {
+	return func(r *http.Request) {
+		r.Header.Set(key, value)
+	}
+}
+
+// Response is a recorded response
+type Response struct {
+	Code   int
+	Header http.Header
+	Body   []byte
+}
+
+// Decode unmarshals the JSON body into v
+func (r *Response) Decode(t testing.TB, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("failed to decode response %s: %v", r.Body, err)
+	}
+}
+
+// Map returns the JSON object body
+func (r *Response) Map(t testing.TB) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	r.Decode(t, &body)
+	return body
+}
+
+// Do sends a request to the router. A non-nil body is sent as JSON unless
+// it is already a string.
+func (h *Harness) Do(method, path string, body interface{}, opts ...RequestOption) *Response {
+	h.t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			h.t.Fatalf("failed to encode request body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	recorder := httptest.NewRecorder()
+	h.Router.ServeHTTP(recorder, req)
+	return &Response{
+		Code:   recorder.Code,
+		Header: recorder.Header(),
+		Body:   recorder.Body.Bytes(),
+	}
+}
+
+// Reload fetches the current state of a record by its primary key
+func (h *Harness) Reload(record interface{}) {
+	h.t.Helper()
+	if err := h.DB.First(record).Error; err != nil {
+		h.t.Fatalf("failed to reload %T: %v", record, err)
+	}
+}
+
+// next returns a number unique within the harness for fixture names
+func (h *Harness) next() int {
+	h.sequence++
+	return h.sequence
+}
+
+// Tokens are the tokens returned by a login
+type Tokens struct {
+	Access  string
+	Refresh string
+}
+
+// userSubject names the user a token belongs to in failure messages
+func userSubject(user *model.User) string {
+	return fmt.Sprintf("%s (%s)", user.Email, user.Role)
+}
@@ -0,0 +1,272 @@
+package apitest
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"service-booking/internal/model"
+	"service-booking/pkg/auth"
+	"service-booking/pkg/refcode"
+	"service-booking/pkg/totp"
+)
+
+// DefaultPassword is the password of users created by CreateUser
+const DefaultPassword = "password123"
+
+// UserOption changes a user before it is stored
+type UserOption func(*model.User)
+
+// WithRole sets the role of the user
+func WithRole(role model.UserRole) UserOption {
+	return func(u *model.User) {
+		u.Role = role
+	}
+}
+
+// WithPhone sets the phone number of the user, as verified with a code
+func WithPhone(phone string) UserOption {
+	return func(u *model.User) {
+		verifiedAt := time.Now()
+		u.Phone = phone
+		u.PhoneVerifiedAt = &verifiedAt
+	}
+}
+
+// WithEmail sets the email address of the user
+func WithEmail(email string) UserOption {
+	return func(u *model.User) {
+		u.Email = email
+	}
+}
+
+// WithVerifiedEmail marks the email address of the user as verified
+func WithVerifiedEmail() UserOption {
+	return func(u *model.User) {
+		verifiedAt := time.Now()
+		u.EmailVerifiedAt = &verifiedAt
+	}
+}
+
+// WithTOTP enrolls the user in two-factor authentication
+func WithTOTP() UserOption {
+	return func(u *model.User) {
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			panic("apitest: failed to generate TOTP secret: " + err.Error())
+		}
+		u.TOTPSecret = secret
+		u.TOTPEnabled = true
+	}
+}
+
+// CreateUser stores a user with DefaultPassword and the customer role
+func (h *Harness) CreateUser(opts ...UserOption) *model.User {
+	h.t.Helper()
+
+	hashedPassword, err := auth.HashPassword(DefaultPassword)
+	if err != nil {
+		h.t.Fatalf("failed to hash password: %v", err)
+	}
+
+	n := h.next()
+	user := &model.User{
+		Name:     fmt.Sprintf("User %d", n),
+		Email:    fmt.Sprintf("user%d@example.com", n),
+		Password: hashedPassword,
+		Role:     model.UserRoleUser,
+	}
+	for _, opt := range opts {
+		opt(user)
+	}
+
+	if err := h.DB.Create(user).Error; err != nil {
+		h.t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+// CreateAdmin stores an admin enrolled in two-factor authentication, as
+// required for the admin routes
+func (h *Harness) CreateAdmin() *model.User {
+	h.t.Helper()
+	return h.CreateUser(WithRole(model.UserRoleAdmin), WithTOTP())
+}
+
+// CreateCategory stores an active category, under parent when given
+func (h *Harness) CreateCategory(parent *model.Category) *model.Category {
+	h.t.Helper()
+
+	category := &model.Category{
+		Name:        fmt.Sprintf("Category %d", h.next()),
+		Description: "Test category",
+		IsActive:    true,
+	}
+	if parent != nil {
+		category.ParentCategoryID = &parent.ID
+	}
+
+	if err := h.DB.Create(category).Error; err != nil {
+		h.t.Fatalf("failed to create category: %v", err)
+	}
+	return category
+}
+
+// CreateService stores an active service in the category
+func (h *Harness) CreateService(category *model.Category) *model.Service {
+	h.t.Helper()
+
+	svc := &model.Service{
+		Name:                 fmt.Sprintf("Service %d", h.next()),
+		Description:          "Test service",
+		Price:                500,
+		CategoryID:           category.ID,
+		IsActive:             true,
+		EstimatedTimeMinutes: 60,
+	}
+	if err := h.DB.Create(svc).Error; err != nil {
+		h.t.Fatalf("failed to create service: %v", err)
+	}
+	return svc
+}
+
+// CreateBooking stores a pending booking of the service made by user, or a
+// guest booking made with phone when user is nil
+func (h *Harness) CreateBooking(svc *model.Service, user *model.User, phone string) *model.Booking {
+	h.t.Helper()
+
+	code, err := refcode.NewGenerator(refcode.DefaultLength).Generate("SB")
+	if err != nil {
+		h.t.Fatalf("failed to generate reference code: %v", err)
+	}
+
+	booking := &model.Booking{
+		ServiceID:            svc.ID,
+		UserName:             "Guest",
+		PhoneNumber:          phone,
+		Status:               model.BookingStatusPending,
+		BookingReferenceCode: code,
+		TotalPrice:           svc.Price,
+		Duration:             1,
+	}
+	if user != nil {
+		booking.UserID = &user.ID
+		booking.UserName = user.Name
+		booking.Email = user.Email
+		if booking.PhoneNumber == "" {
+			booking.PhoneNumber = user.Phone
+		}
+	}
+
+	if err := h.DB.Create(booking).Error; err != nil {
+		h.t.Fatalf("failed to create booking: %v", err)
+	}
+
+	history := &model.BookingStatusHistory{
+		BookingID: booking.ID,
+		Status:    booking.Status,
+		IsActive:  true,
+		Notes:     "Booking created",
+	}
+	if err := h.DB.Create(history).Error; err != nil {
+		h.t.Fatalf("failed to create booking status history: %v", err)
+	}
+	return booking
+}
+
+// CreateOTP stores a one-time code for the phone number, which must be in
+// E.164 format, and returns the code
+func (h *Harness) CreateOTP(phone string) string {
+	h.t.Helper()
+
+	code := fmt.Sprintf("%06d", h.next())
+	codeHash, err := auth.HashPassword(code)
+	if err != nil {
+		h.t.Fatalf("failed to hash code: %v", err)
+	}
+
+	otp := &model.OTPCode{
+		Phone:     phone,
+		CodeHash:  codeHash,
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+	if err := h.DB.Create(otp).Error; err != nil {
+		h.t.Fatalf("failed to create code: %v", err)
+	}
+	return code
+}
+
+// Login logs the user in with DefaultPassword, completing the two-factor
+// step for enrolled users. Tokens are cached per user because a TOTP code
+// cannot be used twice.
+func (h *Harness) Login(user *model.User) Tokens {
+	h.t.Helper()
+
+	if tokens, ok := h.tokens[user.ID]; ok {
+		return tokens
+	}
+
+	resp := h.Do(http.MethodPost, "/api/v1/auth/login", map[string]string{
+		"email":    user.Email,
+		"password": DefaultPassword,
+	})
+	if resp.Code != http.StatusOK {
+		h.t.Fatalf("login as %s: got status %d: %s", userSubject(user), resp.Code, resp.Body)
+	}
+
+	var body struct {
+		MFARequired  bool   `json:"mfa_required"`
+		MFAToken     string `json:"mfa_token"`
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	resp.Decode(h.t, &body)
+
+	if body.MFARequired {
+		code, err := totp.Code(user.TOTPSecret, time.Now())
+		if err != nil {
+			h.t.Fatalf("failed to generate TOTP code: %v", err)
+		}
+
+		resp = h.Do(http.MethodPost, "/api/v1/auth/mfa/verify", map[string]string{
+			"mfa_token": body.MFAToken,
+			"code":      code,
+		})
+		if resp.Code != http.StatusOK {
+			h.t.Fatalf("two-factor login as %s: got status %d: %s", userSubject(user), resp.Code, resp.Body)
+		}
+		resp.Decode(h.t, &body)
+	}
+
+	tokens := Tokens{Access: body.AccessToken, Refresh: body.RefreshToken}
+	h.tokens[user.ID] = tokens
+	return tokens
+}
+
+// Token returns an access token of the user
+func (h *Harness) Token(user *model.User) string {
+	h.t.Helper()
+	return h.Login(user).Access
+}
+
+// GuestToken returns a token proving the guest verified the phone number
+func (h *Harness) GuestToken(phone string) string {
+	h.t.Helper()
+
+	token, err := h.App.Tokens.GenerateGuestToken(phone)
+	if err != nil {
+		h.t.Fatalf("failed to generate guest token: %v", err)
+	}
+	return token
+}
+
+// BookingLinkToken returns the token of the link texted to a guest
+func (h *Harness) BookingLinkToken(booking *model.Booking) string {
+	h.t.Helper()
+
+	token, err := h.App.Services.GuestAccess.IssueAccessToken(booking)
+	if err != nil {
+		h.t.Fatalf("failed to issue booking link token: %v", err)
+	}
+	return token
+}
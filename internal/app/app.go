@@ -0,0 +1,372 @@
+// Package app wires the repositories, services and handlers of the API from
+// an explicit configuration and database connection. Nothing is read from
+// globals, so several isolated instances can run in one process.
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"service-booking/config"
+	"service-booking/db"
+	"service-booking/internal/handler"
+	"service-booking/internal/health"
+	"service-booking/internal/metrics"
+	"service-booking/internal/repository"
+	"service-booking/internal/service"
+	"service-booking/internal/tracing"
+	"service-booking/pkg/auth"
+	"service-booking/pkg/logging"
+	"service-booking/pkg/oidc"
+	"service-booking/pkg/refcode"
+	"service-booking/pkg/sms"
+)
+
+// App is one instance of the application
+type App struct {
+	Config       *config.Config
+	DB           *gorm.DB
+	Tokens       *auth.TokenService
+	Repositories Repositories
+	Services     Services
+	Handlers     Handlers
+
+	// Health runs the readiness checks
+	Health *health.Checker
+
+	// Metrics collects the Prometheus metrics served on /metrics
+	Metrics *metrics.Metrics
+
+	// Logger is the base of the per-request loggers
+	Logger *slog.Logger
+
+	// Tracing records the spans of requests and queries
+	Tracing *tracing.Tracing
+
+	// SMS delivers login codes and booking links
+	SMS sms.Sender
+
+	// IdempotencyTTL is how long idempotent responses are kept
+	IdempotencyTTL time.Duration
+}
+
+// Repositories are the data access objects of an App
+type Repositories struct {
+	Service      repository.ServiceRepository
+	Booking      repository.BookingRepository
+	User         repository.UserRepository
+	Category     repository.CategoryRepository
+	OTP          repository.OTPRepository
+	Role         repository.RoleRepository
+	APIKey       repository.APIKeyRepository
+	Identity     repository.UserIdentityRepository
+	RecoveryCode repository.RecoveryCodeRepository
+	Session      repository.SessionRepository
+	Idempotency  repository.IdempotencyRepository
+	LoginAttempt repository.LoginAttemptStore
+	RateLimit    repository.RateLimitStore
+}
+
+// Services are the business logic of an App
+type Services struct {
+	Service       service.ServiceService
+	Auth          service.AuthService
+	Category      service.CategoryService
+	OTP           service.OTPService
+	Role          service.RoleService
+	Booking       service.BookingService
+	APIKey        service.APIKeyService
+	LoginThrottle service.LoginThrottleService
+	MFA           service.MFAService
+	GuestAccess   service.GuestAccessService
+	SocialAuth    service.SocialAuthService
+	Session       service.SessionService
+}
+
+// Handlers are the HTTP handlers of an App
+type Handlers struct {
+	Service    *handler.ServiceHandler
+	Booking    *handler.BookingHandler
+	Auth       *handler.AuthHandler
+	Category   *handler.CategoryHandler
+	OTP        *handler.OTPHandler
+	Role       *handler.RoleHandler
+	APIKey     *handler.APIKeyHandler
+	SocialAuth *handler.SocialAuthHandler
+	MFA        *handler.MFAHandler
+	Session    *handler.SessionHandler
+	Health     *handler.HealthHandler
+}
+
+// New wires an App on the database connection, which must already be migrated
+func New(cfg *config.Config, conn *gorm.DB) (*App, error) {
+	tokenConfig, err := newTokenConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.ToUpper(cfg.BookingReference.Prefix)
+	if !refcode.ValidPrefix(prefix) {
+		return nil, fmt.Errorf("invalid booking reference prefix %q: must be 1 to 5 letters", prefix)
+	}
+
+	idempotencyTTL, err := time.ParseDuration(cfg.Idempotency.TTL)
+	if err != nil || idempotencyTTL <= 0 {
+		return nil, fmt.Errorf("invalid idempotency TTL %q", cfg.Idempotency.TTL)
+	}
+
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	tracingConfig, err := newTracingConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tracer, err := tracing.New(context.Background(), tracingConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	smsSender, err := newSMSSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &App{
+		Config:         cfg,
+		DB:             conn,
+		Tokens:         auth.NewTokenService(tokenConfig),
+		IdempotencyTTL: idempotencyTTL,
+		Metrics:        metrics.New(),
+		Logger:         logger,
+		Tracing:        tracer,
+		SMS:            smsSender,
+	}
+
+	// Query latency and pool statistics of the connection
+	if err := a.Metrics.InstrumentDB(conn, db.Dialect(cfg)); err != nil {
+		return nil, fmt.Errorf("failed to instrument database: %w", err)
+	}
+
+	// A span for every query
+	if err := a.Tracing.InstrumentDB(conn, db.Dialect(cfg)); err != nil {
+		return nil, fmt.Errorf("failed to trace database: %w", err)
+	}
+
+	// Initialize repositories
+	timeouts, err := newRepositoryTimeouts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	repos := &a.Repositories
+	repos.Service = repository.NewServiceRepository(conn, timeouts)
+	repos.Booking = repository.NewBookingRepository(conn, timeouts)
+	repos.User = repository.NewUserRepository(conn, timeouts)
+	repos.Category = repository.NewCategoryRepository(conn, timeouts)
+	repos.OTP = repository.NewOTPRepository(conn, timeouts)
+	repos.Role = repository.NewRoleRepository(conn, timeouts)
+	repos.APIKey = repository.NewAPIKeyRepository(conn, timeouts)
+	repos.Identity = repository.NewUserIdentityRepository(conn, timeouts)
+	repos.RecoveryCode = repository.NewRecoveryCodeRepository(conn, timeouts)
+	repos.Session = repository.NewSessionRepository(conn, timeouts)
+	idempotencyPurge := &health.Worker{}
+	repos.Idempotency = &reportingIdempotencyStore{repository.NewIdempotencyRepository(conn, timeouts), idempotencyPurge}
+	repos.LoginAttempt = newLoginAttemptStore(cfg, conn, timeouts)
+	repos.RateLimit = newRateLimitStore(cfg, conn, timeouts)
+
+	// Initialize services
+	otpConfig := service.DefaultOTPConfig(cfg)
+	services := &a.Services
+	services.Service = service.NewServiceService(repos.Service, repos.Category)
+	services.Auth = service.NewAuthService(repos.User, repos.Booking, otpConfig.DefaultCountryCode)
+	services.Category = service.NewCategoryService(repos.Category)
+	services.OTP = service.NewOTPService(repos.OTP, repos.User, repos.Booking, a.SMS, otpConfig)
+	services.Role = service.NewRoleService(repos.Role, repos.User)
+	services.Booking = service.NewBookingService(
+		repos.Booking,
+		repos.Service,
+		repos.User,
+		services.Role,
+		refcode.NewGenerator(cfg.BookingReference.Length),
+		prefix,
+		otpConfig.DefaultCountryCode,
+		a.Metrics,
+	)
+	services.APIKey = service.NewAPIKeyService(repos.APIKey, repos.User)
+	services.LoginThrottle = service.NewLoginThrottleService(repos.LoginAttempt, service.DefaultLoginThrottleConfig(cfg))
+	services.MFA = service.NewMFAService(repos.User, repos.RecoveryCode)
+	services.GuestAccess = service.NewGuestAccessService(services.Booking, a.SMS, service.DefaultGuestAccessConfig(cfg, a.Tokens.DeriveKey(auth.KeyPurposeBookingLink)))
+	services.SocialAuth = service.NewSocialAuthService(repos.User, repos.Identity, newOIDCClients(cfg), a.Tokens.DeriveKey(auth.KeyPurposeLoginState))
+	services.Session = service.NewSessionService(repos.Session, repos.User, a.Tokens)
+
+	// Readiness checks
+	checker, err := newHealthChecker(cfg, conn)
+	if err != nil {
+		return nil, err
+	}
+	checker.Register("idempotency_purge", idempotencyPurge.Check)
+	a.Health = checker
+
+	// Initialize handlers
+	a.Handlers = Handlers{
+		Service:    handler.NewServiceHandler(services.Service),
+		Booking:    handler.NewBookingHandler(services.Booking, services.GuestAccess, a.Tokens),
+		Auth:       handler.NewAuthHandler(services.Auth, services.LoginThrottle, services.Session, a.Tokens),
+		Category:   handler.NewCategoryHandler(services.Category),
+		OTP:        handler.NewOTPHandler(services.OTP, services.Session, a.Tokens),
+		Role:       handler.NewRoleHandler(services.Role),
+		APIKey:     handler.NewAPIKeyHandler(services.APIKey),
+		SocialAuth: handler.NewSocialAuthHandler(services.SocialAuth, services.Session, a.Tokens),
+		MFA:        handler.NewMFAHandler(services.MFA, services.Auth, services.LoginThrottle, services.Session, a.Tokens),
+		Session:    handler.NewSessionHandler(services.Session),
+		Health:     handler.NewHealthHandler(checker),
+	}
+
+	return a, nil
+}
+
+// newRepositoryTimeouts reads the default time limits of queries
+func newRepositoryTimeouts(cfg *config.Config) (repository.Timeouts, error) {
+	var timeouts repository.Timeouts
+	values := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"read_timeout", cfg.Database.ReadTimeout, &timeouts.Read},
+		{"write_timeout", cfg.Database.WriteTimeout, &timeouts.Write},
+	}
+	for _, v := range values {
+		duration, err := time.ParseDuration(v.value)
+		if err != nil || duration < 0 {
+			return timeouts, fmt.Errorf("invalid database %s %q", v.name, v.value)
+		}
+		*v.dest = duration
+	}
+	return timeouts, nil
+}
+
+// newTracingConfig reads the tracing configuration
+func newTracingConfig(cfg *config.Config) (tracing.Config, error) {
+	ratio, err := strconv.ParseFloat(cfg.Tracing.SampleRatio, 64)
+	if err != nil {
+		return tracing.Config{}, fmt.Errorf("invalid tracing sample ratio %q", cfg.Tracing.SampleRatio)
+	}
+
+	return tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: ratio,
+	}, nil
+}
+
+// newSMSSender selects the configured SMS driver. The fake driver delivers
+// nothing, so it is refused in production.
+func newSMSSender(cfg *config.Config) (sms.Sender, error) {
+	switch cfg.SMS.Driver {
+	case "twilio":
+		return sms.NewTwilioSender(cfg.SMS.AccountSID, cfg.SMS.AuthToken, cfg.SMS.From)
+	case "fake", "":
+		if cfg.Env == "production" {
+			return nil, fmt.Errorf("the fake SMS driver cannot be used in production")
+		}
+		return sms.NewFakeSender(), nil
+	default:
+		return nil, fmt.Errorf("invalid SMS driver %q: must be twilio or fake", cfg.SMS.Driver)
+	}
+}
+
+// newTokenConfig reads the JWT configuration. Unset values fall back to the
+// defaults of auth.NewTokenService. The secret key signs every token and
+// derives the other keys of the app, so it must be set in production.
+func newTokenConfig(cfg *config.Config) (auth.TokenConfig, error) {
+	tokenConfig := auth.TokenConfig{SecretKey: cfg.JWT.SecretKey}
+	if tokenConfig.SecretKey == "" && cfg.Env == "production" {
+		return tokenConfig, fmt.Errorf("the JWT secret key must be set in production")
+	}
+
+	var err error
+	if cfg.JWT.AccessTokenDuration != "" {
+		if tokenConfig.AccessTokenDuration, err = time.ParseDuration(cfg.JWT.AccessTokenDuration); err != nil {
+			return tokenConfig, fmt.Errorf("invalid access token duration %q", cfg.JWT.AccessTokenDuration)
+		}
+	}
+	if cfg.JWT.RefreshTokenDuration != "" {
+		if tokenConfig.RefreshTokenDuration, err = time.ParseDuration(cfg.JWT.RefreshTokenDuration); err != nil {
+			return tokenConfig, fmt.Errorf("invalid refresh token duration %q", cfg.JWT.RefreshTokenDuration)
+		}
+	}
+	return tokenConfig, nil
+}
+
+// newHealthChecker creates the readiness checks of the database and schema
+func newHealthChecker(cfg *config.Config, conn *gorm.DB) (*health.Checker, error) {
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+	migrator, err := db.NewMigrator(conn, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	checker := health.NewChecker()
+	checker.Register("database", health.DatabaseCheck(sqlDB))
+	checker.Register("migrations", health.MigrationCheck(migrator))
+	return checker, nil
+}
+
+// reportingIdempotencyStore records the outcome of purging expired
+// Idempotency-Key responses, which runs in the background
+type reportingIdempotencyStore struct {
+	repository.IdempotencyRepository
+	purge *health.Worker
+}
+
+func (s *reportingIdempotencyStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	err := s.IdempotencyRepository.DeleteExpired(ctx, before)
+	s.purge.Report(err)
+	return err
+}
+
+// newOIDCClients creates a client for every configured social login provider
+func newOIDCClients(cfg *config.Config) map[string]*oidc.Client {
+	clients := make(map[string]*oidc.Client)
+	for name, provider := range cfg.OIDCProviders {
+		clients[name] = oidc.NewClient(oidc.Config{
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       provider.Scopes,
+		}, nil)
+	}
+	return clients
+}
+
+// newLoginAttemptStore selects the configured store for failed login counters
+func newLoginAttemptStore(cfg *config.Config, conn *gorm.DB, timeouts repository.Timeouts) repository.LoginAttemptStore {
+	if cfg.LoginThrottle.Store == "database" {
+		return repository.NewLoginAttemptRepository(conn, timeouts)
+	}
+	return repository.NewMemoryLoginAttemptStore()
+}
+
+// newRateLimitStore selects the configured store for rate limit buckets
+func newRateLimitStore(cfg *config.Config, conn *gorm.DB, timeouts repository.Timeouts) repository.RateLimitStore {
+	if cfg.RateLimit.Store == "database" {
+		return repository.NewRateLimitRepository(conn, timeouts)
+	}
+	return repository.NewMemoryRateLimitStore()
+}
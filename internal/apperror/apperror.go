@@ -0,0 +1,151 @@
+package apperror
+
+import (
+	"errors"
+)
+
+// Kind classifies an error by how a client can react to it
+type Kind string
+
+const (
+	KindInternal     Kind = "internal"
+	KindNotFound     Kind = "not_found"
+	KindConflict     Kind = "conflict"
+	KindValidation   Kind = "validation_failed"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+	KindRateLimited  Kind = "rate_limited"
+	KindUnavailable  Kind = "unavailable"
+	KindTimeout      Kind = "timeout"
+)
+
+// FieldError describes a single request field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error is an error with a kind and a message that is safe to show clients.
+// The cause is kept for logging and errors.Is checks.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the machine-readable code, which defaults to the kind
+func (e *Error) ErrorCode() string {
+	if e.Code != "" {
+		return e.Code
+	}
+	return string(e.Kind)
+}
+
+// WithCode returns a copy of the error with a more specific code
+func (e *Error) WithCode(code string) *Error {
+	copied := *e
+	copied.Code = code
+	return &copied
+}
+
+// New creates an error of the given kind
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Wrap creates an error of the given kind caused by err
+func Wrap(kind Kind, message string, err error) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+func NotFound(message string) *Error {
+	return New(KindNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(KindConflict, message)
+}
+
+func Validation(message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Message: message, Fields: fields}
+}
+
+func Unauthorized(message string) *Error {
+	return New(KindUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(KindForbidden, message)
+}
+
+func RateLimited(message string) *Error {
+	return New(KindRateLimited, message)
+}
+
+func Unavailable(message string, err error) *Error {
+	return Wrap(KindUnavailable, message, err)
+}
+
+func Timeout(message string, err error) *Error {
+	return Wrap(KindTimeout, message, err)
+}
+
+func Internal(message string, err error) *Error {
+	return Wrap(KindInternal, message, err)
+}
+
+// KindOf returns the kind of the first Error in the chain of err. Errors
+// without a kind are internal.
+func KindOf(err error) Kind {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Kind
+	}
+	return KindInternal
+}
+
+// Is reports whether err is of the given kind
+func Is(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
+
+// IsTransient reports whether err is an outage or a timeout, which clients
+// may retry later
+func IsTransient(err error) bool {
+	kind := KindOf(err)
+	return err != nil && (kind == KindUnavailable || kind == KindTimeout)
+}
+
+// OrInternal returns err when it is transient, so clients are told to retry,
+// and an internal error with the message otherwise
+func OrInternal(message string, err error) *Error {
+	var appErr *Error
+	if IsTransient(err) && errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(message, err)
+}
+
+// IfNotFound returns target when err reports a missing record and err
+// otherwise, so a missing row can be reported as, say, an unknown service
+// while outages stay outages
+func IfNotFound(err error, target *Error) error {
+	if Is(err, KindNotFound) {
+		return target
+	}
+	return err
+}
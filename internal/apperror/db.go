@@ -0,0 +1,73 @@
+package apperror
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// MySQL errors that go away when the statement is retried later
+const (
+	mysqlTooManyConnections = 1040
+	mysqlLockWaitTimeout    = 1205
+	mysqlDeadlock           = 1213
+)
+
+// FromDB classifies an error returned by GORM. Missing records are not
+// found, unique and foreign key violations are conflicts, queries that ran
+// past their deadline are timeouts and lost connections or cancelled queries
+// are unavailable; anything else is internal. A nil error stays nil.
+func FromDB(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return Wrap(KindNotFound, "record not found", err)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return Wrap(KindConflict, "record already exists", err)
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return Wrap(KindConflict, "record is referenced by or references missing records", err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout("database query timed out", err)
+	case isUnavailable(err):
+		return Unavailable("database unavailable", err)
+	}
+	return Internal("database error", err)
+}
+
+// isUnavailable reports whether err means the database cannot be reached
+// or is temporarily overloaded
+func isUnavailable(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, context.Canceled) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlTooManyConnections, mysqlLockWaitTimeout, mysqlDeadlock:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"time"
+
+	"service-booking/internal/model"
+)
+
+// RoleRequest is the body of a role create or update. The name cannot change
+// once the role exists.
+type RoleRequest struct {
+	Name        string             `json:"name" binding:"required,max=50"`
+	Description string             `json:"description"`
+	Permissions []model.Permission `json:"permissions" binding:"dive,permission"`
+}
+
+// ToModel maps the request to a role
+func (r RoleRequest) ToModel() model.Role {
+	return model.Role{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: r.Permissions,
+	}
+}
+
+// AssignRoleRequest is the body of a user role change
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required,max=50"`
+}
+
+// IssueAPIKeyRequest is the body of a new partner API key
+type IssueAPIKeyRequest struct {
+	Name         string             `json:"name" binding:"required,max=100"`
+	Organization string             `json:"organization" binding:"required,max=255"`
+	UserID       uint               `json:"user_id" binding:"required"`
+	Scopes       []model.Permission `json:"scopes" binding:"dive,permission"`
+	ExpiresAt    *time.Time         `json:"expires_at" binding:"omitempty,future"`
+}
+
+// ToModel maps the request to a new API key
+func (r IssueAPIKeyRequest) ToModel() model.APIKey {
+	return model.APIKey{
+		Name:         r.Name,
+		Organization: r.Organization,
+		UserID:       r.UserID,
+		Scopes:       r.Scopes,
+		ExpiresAt:    r.ExpiresAt,
+	}
+}
@@ -0,0 +1,73 @@
+package dto
+
+import "service-booking/internal/model"
+
+// RegisterRequest is the body of a registration. The password is never
+// bound to model.User directly, as it is hidden from JSON.
+type RegisterRequest struct {
+	Name     string `json:"name" binding:"required,min=2,max=255"`
+	Email    string `json:"email" binding:"required,email,max=255"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+	Phone    string `json:"phone" binding:"omitempty,max=20"`
+	// GuestToken claims earlier guest bookings made with the same phone
+	GuestToken string `json:"guest_token"`
+}
+
+// ToModel maps the request to a new user without a password
+func (r RegisterRequest) ToModel() model.User {
+	return model.User{
+		Name:  r.Name,
+		Email: r.Email,
+		Phone: r.Phone,
+	}
+}
+
+// LoginRequest is the body of a password login
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// UpdateProfileRequest is the body of a profile update; empty fields are
+// left unchanged
+type UpdateProfileRequest struct {
+	Name  string `json:"name" binding:"omitempty,min=2,max=100"`
+	Email string `json:"email" binding:"omitempty,email,max=255"`
+}
+
+// Changes maps the request to the profile fields to update
+func (r UpdateProfileRequest) Changes() map[string]interface{} {
+	return map[string]interface{}{
+		"name":  r.Name,
+		"email": r.Email,
+	}
+}
+
+// ChangePasswordRequest is the body of a password change
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,max=72"`
+}
+
+// OTPRequest is the body of a one-time code request
+type OTPRequest struct {
+	Phone string `json:"phone" binding:"required,max=20"`
+}
+
+// VerifyOTPRequest is the body of a one-time code check
+type VerifyOTPRequest struct {
+	Phone string `json:"phone" binding:"required,max=20"`
+	Code  string `json:"code" binding:"required,numeric,max=10"`
+}
+
+// MFACodeRequest is the body of the two-factor settings changes that must be
+// confirmed with a current code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+// VerifyMFARequest is the body of the second login step
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required,max=32"`
+}
@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"time"
+
+	"service-booking/internal/model"
+)
+
+// CreateBookingRequest is the body of a new booking. The status, price and
+// reference code are always set by the server.
+type CreateBookingRequest struct {
+	ServiceID   uint       `json:"service_id" binding:"required"`
+	UserName    string     `json:"user_name" binding:"required,max=255"`
+	PhoneNumber string     `json:"phone_number" binding:"omitempty,max=20"`
+	Email       string     `json:"email" binding:"omitempty,email,max=255"`
+	ScheduledAt *time.Time `json:"scheduled_at" binding:"omitempty,future"`
+	Duration    int        `json:"duration" binding:"omitempty,gt=0"`
+	Notes       string     `json:"notes" binding:"max=2000"`
+}
+
+// ToModel maps the request to a new booking
+func (r CreateBookingRequest) ToModel() model.Booking {
+	duration := r.Duration
+	if duration == 0 {
+		duration = 1
+	}
+
+	return model.Booking{
+		ServiceID:   r.ServiceID,
+		UserName:    r.UserName,
+		PhoneNumber: r.PhoneNumber,
+		Email:       r.Email,
+		ScheduledAt: r.ScheduledAt,
+		Duration:    duration,
+		Notes:       r.Notes,
+	}
+}
+
+// UpdateBookingStatusRequest is the body of a booking status change
+type UpdateBookingStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=pending confirmed in_progress completed cancelled"`
+	Notes  string `json:"notes" binding:"max=2000"`
+}
+
+// CancelBookingRequest is the optional body of a booking cancellation
+type CancelBookingRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// AssignProviderRequest is the body of a provider assignment
+type AssignProviderRequest struct {
+	ProviderID uint `json:"provider_id" binding:"required"`
+}
+
+// BookingLookupRequest is the body of a guest booking lookup
+type BookingLookupRequest struct {
+	Phone string `json:"phone" binding:"required,max=20"`
+}
@@ -0,0 +1,51 @@
+package dto
+
+import "service-booking/internal/model"
+
+// ServiceRequest is the body of a service create or update
+type ServiceRequest struct {
+	Name                 string  `json:"name" binding:"required,max=255"`
+	Description          string  `json:"description"`
+	Price                float64 `json:"price" binding:"gte=0"`
+	CategoryID           uint    `json:"category_id" binding:"required"`
+	IsActive             bool    `json:"is_active"`
+	IsFeatured           bool    `json:"is_featured"`
+	EstimatedTimeMinutes int     `json:"estimated_time_minutes" binding:"gte=0"`
+}
+
+// ToModel maps the request to a service
+func (r ServiceRequest) ToModel() model.Service {
+	return model.Service{
+		Name:                 r.Name,
+		Description:          r.Description,
+		Price:                r.Price,
+		CategoryID:           r.CategoryID,
+		IsActive:             r.IsActive,
+		IsFeatured:           r.IsFeatured,
+		EstimatedTimeMinutes: r.EstimatedTimeMinutes,
+	}
+}
+
+// CategoryRequest is the body of a category create or update
+type CategoryRequest struct {
+	Name             string `json:"name" binding:"required,max=100"`
+	Description      string `json:"description"`
+	ParentCategoryID *uint  `json:"parent_category_id" binding:"omitempty,gt=0"`
+	IsActive         bool   `json:"is_active"`
+	IconURL          string `json:"icon_url" binding:"omitempty,url,max=255"`
+	DisplayOrder     int    `json:"display_order"`
+	ReferencePrefix  string `json:"reference_prefix" binding:"omitempty,alpha,max=5"`
+}
+
+// ToModel maps the request to a category
+func (r CategoryRequest) ToModel() model.Category {
+	return model.Category{
+		Name:             r.Name,
+		Description:      r.Description,
+		ParentCategoryID: r.ParentCategoryID,
+		IsActive:         r.IsActive,
+		IconURL:          r.IconURL,
+		DisplayOrder:     r.DisplayOrder,
+		ReferencePrefix:  r.ReferencePrefix,
+	}
+}
@@ -0,0 +1,191 @@
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+)
+
+// CodeInvalidBody is returned when a request body cannot be decoded
+const CodeInvalidBody = "invalid_body"
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// RegisterValidators adds the custom validation rules to the validator used
+// by gin and reports fields by their JSON name. The validator is shared by
+// every router in the process, so the rules must not depend on the
+// configuration; phone numbers are checked by the services instead.
+func RegisterValidators() error {
+	registerOnce.Do(func() {
+		validate, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			registerErr = errors.New("unsupported validator engine")
+			return
+		}
+
+		validate.RegisterTagNameFunc(jsonFieldName)
+
+		rules := map[string]validator.Func{
+			"future":     validateFuture,
+			"permission": validatePermission,
+		}
+		for tag, rule := range rules {
+			if err := validate.RegisterValidation(tag, rule); err != nil {
+				registerErr = fmt.Errorf("failed to register %s validator: %v", tag, err)
+				return
+			}
+		}
+	})
+	return registerErr
+}
+
+// jsonFieldName names struct fields after their JSON key
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// validateFuture accepts times after now
+func validateFuture(fl validator.FieldLevel) bool {
+	t, ok := fl.Field().Interface().(time.Time)
+	return ok && t.After(time.Now())
+}
+
+// validatePermission accepts the known permissions
+func validatePermission(fl validator.FieldLevel) bool {
+	return model.IsValidPermission(model.Permission(fl.Field().String()))
+}
+
+// BindError describes why a request body could not be bound
+func BindError(err error) *apperror.Error {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]apperror.FieldError, 0, len(validationErrors))
+		for _, fe := range validationErrors {
+			fields = append(fields, apperror.FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Message: fieldMessage(fe),
+			})
+		}
+		return apperror.Validation("Request validation failed", fields...)
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		return apperror.Validation("Request validation failed", apperror.FieldError{
+			Field:   typeError.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be a %s", jsonTypeName(typeError.Type)),
+		})
+	}
+
+	var timeError *time.ParseError
+	if errors.As(err, &timeError) {
+		return apperror.Validation("Times must be in RFC 3339 format").WithCode(CodeInvalidBody)
+	}
+
+	if errors.Is(err, io.EOF) {
+		return apperror.Validation("Request body is required").WithCode(CodeInvalidBody)
+	}
+
+	return apperror.Validation("Request body is not valid JSON").WithCode(CodeInvalidBody)
+}
+
+// fieldPath drops the struct name from the namespace of a field error
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// fieldMessage explains a failed rule in plain words
+func fieldMessage(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+	isList := fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map
+
+	switch fe.Tag() {
+	case "required", "required_without":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "future":
+		return "must be in the future"
+	case "permission":
+		return "must be a known permission"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min":
+		switch {
+		case isString:
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		case isList:
+			return fmt.Sprintf("must contain at least %s items", fe.Param())
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		switch {
+		case isString:
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		case isList:
+			return fmt.Sprintf("must contain at most %s items", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	case "len":
+		if isString {
+			return fmt.Sprintf("must be exactly %s characters", fe.Param())
+		}
+		return "must be exactly " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "alpha":
+		return "must contain only letters"
+	case "numeric":
+		return "must contain only digits"
+	}
+	return "is invalid"
+}
+
+// jsonTypeName names a Go type the way a JSON client would
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "whole number"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	}
+	return "object"
+}
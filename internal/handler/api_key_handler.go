@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"service-booking/internal/apperror"
+	"service-booking/internal/dto"
+	"service-booking/internal/service"
+)
+
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService}
+}
+
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	pageStr := c.DefaultQuery("page", "1")
+	limitStr := c.DefaultQuery("limit", "10")
+
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	// Prepare filters
+	filters := make(map[string]interface{})
+
+	if organization := c.Query("organization"); organization != "" {
+		filters["organization"] = organization
+	}
+
+	if revokedStr := c.Query("revoked"); revokedStr != "" {
+		revoked, err := strconv.ParseBool(revokedStr)
+		if err == nil {
+			filters["revoked"] = revoked
+		}
+	}
+
+	apiKeys, count, err := h.apiKeyService.GetAPIKeys(c.Request.Context(), page, limit, filters)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": apiKeys,
+		"meta": gin.H{
+			"total":       count,
+			"page":        page,
+			"limit":       limit,
+			"total_pages": (count + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// IssueAPIKey creates a key for a partner organization. The plain-text key
+// is only returned in this response.
+func (h *APIKeyHandler) IssueAPIKey(c *gin.Context) {
+	var keyRequest dto.IssueAPIKeyRequest
+	if !bindJSON(c, &keyRequest) {
+		return
+	}
+
+	apiKey := keyRequest.ToModel()
+
+	// Record the admin issuing the key
+	if userID, exists := c.Get("user_id"); exists {
+		if currentUserID, ok := userID.(uint); ok {
+			apiKey.CreatedBy = currentUserID
+		}
+	}
+
+	rawKey, err := h.apiKeyService.IssueAPIKey(c.Request.Context(), &apiKey)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": apiKey,
+		"key":     rawKey,
+	})
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.Error(apperror.Validation("Invalid API key ID"))
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(c.Request.Context(), uint(id)); err != nil {
+		c.Error(apperror.NotFound(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
@@ -1,75 +1,90 @@
 package handler
 
 import (
-	"fmt"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 
+	"service-booking/internal/apperror"
+	"service-booking/internal/dto"
 	"service-booking/internal/model"
 	"service-booking/internal/service"
 	"service-booking/pkg/auth"
+	"service-booking/pkg/logging"
 )
 
 type AuthHandler struct {
-	authService service.AuthService
+	authService    service.AuthService
+	loginThrottle  service.LoginThrottleService
+	sessionService service.SessionService
+	tokens         *auth.TokenService
 }
 
-func NewAuthHandler(authService service.AuthService) *AuthHandler {
-	return &AuthHandler{authService}
+func NewAuthHandler(
+	authService service.AuthService,
+	loginThrottle service.LoginThrottleService,
+	sessionService service.SessionService,
+	tokens *auth.TokenService,
+) *AuthHandler {
+	return &AuthHandler{authService, loginThrottle, sessionService, tokens}
 }
 
 // Register handles user registration
 func (h *AuthHandler) Register(c *gin.Context) {
-	var user model.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var registerRequest dto.RegisterRequest
+	if !bindJSON(c, &registerRequest) {
 		return
 	}
 
-	// Validate user input
-	validate := validator.New()
-	if err := validate.Struct(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	// The guest token proves the phone number, so check it before registering
+	var guestPhone string
+	if registerRequest.GuestToken != "" {
+		phoneNumber, err := h.tokens.ValidateGuestToken(registerRequest.GuestToken)
+		if err != nil {
+			c.Error(apperror.Validation("Invalid or expired guest token"))
+			return
+		}
+		guestPhone = phoneNumber
 	}
 
+	user := registerRequest.ToModel()
+
 	// Hash the password
-	hashedPassword, err := auth.HashPassword(user.Password)
+	hashedPassword, err := auth.HashPassword(registerRequest.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not hash password"})
+		c.Error(apperror.Internal("Could not hash password", err))
 		return
 	}
 
 
 	user.Password = hashedPassword
 
-	// Set default role if not specified
-	if user.Role == "" {
-		user.Role = model.UserRoleUser
-	}
+	// Self-registered users are always customers; staff roles are assigned by admins
+	user.Role = model.UserRoleUser
 
 	
 
 	// Register the user
-	registeredUser, err := h.authService.Register(&user)
+	registeredUser, err := h.authService.Register(c.Request.Context(), &user)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	// Generate JWT tokens
-	accessToken, refreshToken, err := auth.GenerateAllTokens(
-		registeredUser.ID, 
-		registeredUser.Email, 
-		registeredUser.Name, 
-		string(registeredUser.Role),
+	accessToken, refreshToken, err := h.sessionService.StartSession(
+		c.Request.Context(),
+		registeredUser,
+		c.Request.UserAgent(),
+		c.ClientIP(),
 	)
 
 	
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate tokens"})
+		c.Error(err)
 		return
 	}
 
@@ -81,47 +96,80 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		"role":  registeredUser.Role,
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	response := gin.H{
 		"user":          userResponse,
 		"access_token":  accessToken,
 		"refresh_token": refreshToken,
-	})
+	}
+
+	// Claim guest bookings when the verified phone is the registered one
+	if guestPhone != "" && guestPhone == registeredUser.Phone {
+		claimed, err := h.authService.ClaimGuestBookings(c.Request.Context(), registeredUser.ID, guestPhone)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("Failed to claim guest bookings", "user_id", registeredUser.ID, "error", err)
+		}
+		response["claimed_bookings"] = claimed
+	}
+
+	c.JSON(http.StatusCreated, response)
 }
 
 // Login handles user authentication
 // In internal/handler/auth_handler.go
 // In internal/handler/auth_handler.go
 func (h *AuthHandler) Login(c *gin.Context) {
-    var loginRequest struct {
-        Email    string `json:"email" binding:"required,email"`
-        Password string `json:"password" binding:"required"`
+    var loginRequest dto.LoginRequest
+    if !bindJSON(c, &loginRequest) {
+        return
     }
 
-    if err := c.ShouldBindJSON(&loginRequest); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+    // Reject throttled clients before checking the password
+    if err := h.loginThrottle.Check(c.Request.Context(), loginRequest.Email, c.ClientIP()); err != nil {
+        var throttled *service.ThrottledError
+        if errors.As(err, &throttled) {
+            c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+            c.Error(apperror.RateLimited("Too many failed login attempts. Try again later"))
+            return
+        }
+        c.Error(apperror.OrInternal("Could not process login", err))
         return
     }
 
-
     // Authenticate user
-    user, err := h.authService.Authenticate(loginRequest.Email, loginRequest.Password)
+    user, err := h.authService.Authenticate(c.Request.Context(), loginRequest.Email, loginRequest.Password)
+    // An outage is not a failed attempt
+    if apperror.IsTransient(err) {
+        c.Error(err)
+        return
+    }
     if err != nil {
-        // Log the specific error
-        fmt.Printf("Authentication error for %s: %v\n", loginRequest.Email, err)
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+        if recordErr := h.loginThrottle.RecordFailure(c.Request.Context(), loginRequest.Email, c.ClientIP()); recordErr != nil {
+            logging.FromContext(c.Request.Context()).Error("Failed to record login failure", "error", recordErr)
+        }
+        c.Error(apperror.Unauthorized("Invalid credentials"))
+        return
+    }
+
+    if err := h.loginThrottle.RecordSuccess(c.Request.Context(), loginRequest.Email); err != nil {
+        logging.FromContext(c.Request.Context()).Error("Failed to reset login failures", "error", err)
+    }
+
+    // Enrolled users must complete the second step at /auth/mfa/verify
+    if challengeSecondFactor(c, h.tokens, user) {
         return
     }
 
     // Generate JWT tokens
-    accessToken, refreshToken, err := auth.GenerateAllTokens(
-        user.ID, 
-        user.Email, 
-        user.Name, 
-        string(user.Role),
+    accessToken, refreshToken, err := h.sessionService.StartSession(
+        c.Request.Context(),
+        user,
+        c.Request.UserAgent(),
+        c.ClientIP(),
     )
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate tokens"})
-        return
+    	c.Error(err)
+    	return
     }
 
     // Prepare response (remove sensitive info)
@@ -132,11 +180,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
         "role":  user.Role,
     }
 
-    c.JSON(http.StatusOK, gin.H{
-        "user":          userResponse,
-        "access_token":  accessToken,
-        "refresh_token": refreshToken,
-    })
+    c.JSON(http.StatusOK, loginResponse(userResponse, user.Role, accessToken, refreshToken))
 }
 
 // GetProfile retrieves the authenticated user's profile
@@ -144,14 +188,14 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	// Retrieve user ID from the context (set by JWT middleware)
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.Error(apperror.Unauthorized("User not authenticated"))
 		return
 	}
 
 	// Fetch user profile
-	user, err := h.authService.GetUserByID(userID.(uint))
+	user, err := h.authService.GetUserByID(c.Request.Context(), userID.(uint))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User profile not found"})
+		c.Error(err)
 		return
 	}
 
@@ -171,33 +215,20 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	// Retrieve user ID from the context (set by JWT middleware)
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.Error(apperror.Unauthorized("User not authenticated"))
 		return
 	}
 
-	// Prepare update request struct
-	var updateRequest struct {
-		Name  string `json:"name" validate:"omitempty,min=2,max=100"`
-		Email string `json:"email" validate:"omitempty,email"`
-	}
-
 	// Bind and validate input
-	if err := c.ShouldBindJSON(&updateRequest); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Validate the update request
-	validate := validator.New()
-	if err := validate.Struct(updateRequest); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var updateRequest dto.UpdateProfileRequest
+	if !bindJSON(c, &updateRequest) {
 		return
 	}
 
 	// Update profile
-	updatedUser, err := h.authService.UpdateProfile(userID.(uint), &updateRequest)
+	updatedUser, err := h.authService.UpdateProfile(c.Request.Context(), userID.(uint), updateRequest.Changes())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -217,32 +248,50 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	// Retrieve user ID from the context (set by JWT middleware)
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.Error(apperror.Unauthorized("User not authenticated"))
 		return
 	}
 
-	// Prepare password change request struct
-	var passwordChangeRequest struct {
-		CurrentPassword string `json:"current_password" binding:"required"`
-		NewPassword     string `json:"new_password" binding:"required,min=8"`
-	}
-
 	// Bind input
-	if err := c.ShouldBindJSON(&passwordChangeRequest); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var passwordChangeRequest dto.ChangePasswordRequest
+	if !bindJSON(c, &passwordChangeRequest) {
 		return
 	}
 
 	// Change password
 	err := h.authService.ChangePassword(
+		c.Request.Context(),
 		userID.(uint), 
 		passwordChangeRequest.CurrentPassword, 
 		passwordChangeRequest.NewPassword,
 	)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
-}
\ No newline at end of file
+}
+
+// UnlockUser lifts a login lockout of a user
+func (h *AuthHandler) UnlockUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.Error(apperror.Validation("Invalid user ID"))
+		return
+	}
+
+	user, err := h.authService.GetUserByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.loginThrottle.Unlock(c.Request.Context(), user.Email); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
@@ -1,24 +1,57 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 	"github.com/gin-gonic/gin"
+	"service-booking/internal/apperror"
+	"service-booking/internal/dto"
 	"service-booking/internal/model"
 	"service-booking/internal/service"
+	"service-booking/pkg/auth"
+	"service-booking/pkg/logging"
 )
 
 type BookingHandler struct {
-	bookingService service.BookingService
+	bookingService     service.BookingService
+	guestAccessService service.GuestAccessService
+	tokens             *auth.TokenService
 }
 
-func NewBookingHandler(bookingService service.BookingService) *BookingHandler {
-	return &BookingHandler{bookingService}
+func NewBookingHandler(bookingService service.BookingService, guestAccessService service.GuestAccessService, tokens *auth.TokenService) *BookingHandler {
+	return &BookingHandler{bookingService, guestAccessService, tokens}
+}
+
+// currentActor builds the booking actor from the user set by the JWT middleware
+func currentActor(c *gin.Context) (service.Actor, bool) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		return service.Actor{}, false
+	}
+
+	currentUserID, ok := userID.(uint)
+	if !ok {
+		return service.Actor{}, false
+	}
+
+	role, _ := c.Get("role")
+	roleName, _ := role.(string)
+
+	return service.Actor{UserID: currentUserID, Role: roleName, MFA: c.GetBool("mfa")}, true
 }
 
 
 func (h *BookingHandler) GetBookings(c *gin.Context) {
+	actor, ok := currentActor(c)
+	if !ok {
+		c.Error(apperror.Unauthorized("User not authenticated"))
+		return
+	}
+
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "10")
 
@@ -72,9 +105,9 @@ func (h *BookingHandler) GetBookings(c *gin.Context) {
 	}
 
 	// Fetch bookings with filters
-	bookings, count, err := h.bookingService.GetBookings(page, limit, filters)
+	bookings, count, err := h.bookingService.GetBookings(c.Request.Context(), actor, page, limit, filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		c.Error(err)
 		return
 	}
 
@@ -93,13 +126,19 @@ func (h *BookingHandler) GetBookingByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		c.Error(apperror.Validation("Invalid booking ID"))
+		return
+	}
+
+	actor, ok := currentActor(c)
+	if !ok {
+		c.Error(apperror.Unauthorized("User not authenticated"))
 		return
 	}
 	
-	booking, err := h.bookingService.GetBookingByID(uint(id))
+	booking, err := h.bookingService.GetBookingByID(c.Request.Context(), actor, uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		c.Error(err)
 		return
 	}
 	
@@ -107,27 +146,46 @@ func (h *BookingHandler) GetBookingByID(c *gin.Context) {
 }
 
 func (h *BookingHandler) CreateBooking(c *gin.Context) {
-	var booking model.Booking
-	if err := c.ShouldBindJSON(&booking); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var bookingRequest dto.CreateBookingRequest
+	if !bindJSON(c, &bookingRequest) {
 		return
 	}
+	booking := bookingRequest.ToModel()
 	
-	// Optional: Get user ID from context for authenticated bookings
-	userID, exists := c.Get("user_id")
-	if exists {
-		// Convert user ID to uint
+	// Authenticated users book for themselves; guests must prove they own
+	// the phone number with a token from /bookings/guest/verify
+	if userID, exists := c.Get("user_id"); exists {
 		currentUserID, ok := userID.(uint)
-		if ok {
-			booking.UserID = currentUserID
+		if !ok {
+			c.Error(apperror.Internal("Invalid user ID", nil))
+			return
 		}
+		booking.UserID = &currentUserID
+	} else {
+		guestPhone, err := h.tokens.ValidateGuestToken(c.GetHeader("X-Guest-Token"))
+		if err != nil {
+			c.Error(apperror.Unauthorized("Phone verification is required for guest bookings"))
+			return
+		}
+		booking.UserID = nil
+		booking.PhoneNumber = guestPhone
 	}
 
 	// Create booking
-	if err := h.bookingService.CreateBooking(&booking); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking: " + err.Error()})
+	if err := h.bookingService.CreateBooking(c.Request.Context(), &booking); err != nil {
+		c.Error(err)
 		return
 	}
+
+	// Guests get a link to their booking on the phone they verified. Account
+	// holders find it in their bookings, and the phone number they entered
+	// was never proven to be theirs. The booking is created even if the
+	// link cannot be sent.
+	if booking.UserID == nil {
+		if err := h.guestAccessService.SendAccessLink(&booking); err != nil {
+			logging.FromContext(c.Request.Context()).Error("Failed to send booking link", "booking_reference", booking.BookingReferenceCode, "error", err)
+		}
+	}
 	
 	c.JSON(http.StatusCreated, booking)
 }
@@ -136,43 +194,31 @@ func (h *BookingHandler) UpdateBookingStatus(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		c.Error(apperror.Validation("Invalid booking ID"))
 		return
 	}
 	
-	// Struct to handle status update request
-	var statusData struct {
-		Status string `json:"status" binding:"required"`
-		Notes  string `json:"notes,omitempty"`
-	}
-	
-	if err := c.ShouldBindJSON(&statusData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var statusData dto.UpdateBookingStatusRequest
+	if !bindJSON(c, &statusData) {
 		return
 	}
 	
-	// Get user ID from context (assuming JWT middleware sets the user_id)
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	// Convert user ID to uint
-	currentUserID, ok := userID.(uint)
+	// Get the acting user from context (set by JWT middleware)
+	actor, ok := currentActor(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+		c.Error(apperror.Unauthorized("User not authenticated"))
 		return
 	}
 	
 	// Update booking status
 	if err := h.bookingService.UpdateBookingStatus(
+		c.Request.Context(),
+		actor,
 		uint(id), 
 		model.BookingStatus(statusData.Status), 
-		currentUserID, 
 		statusData.Notes,
 	); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status: " + err.Error()})
+		c.Error(err)
 		return
 	}
 	
@@ -187,31 +233,23 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
-		return
-	}
-
-	// Get user ID from context (assuming JWT middleware sets the user_id)
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.Error(apperror.Validation("Invalid booking ID"))
 		return
 	}
 
-	// Convert user ID to uint
-	currentUserID, ok := userID.(uint)
+	// Get the acting user from context (set by JWT middleware)
+	actor, ok := currentActor(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+		c.Error(apperror.Unauthorized("User not authenticated"))
 		return
 	}
 
 	// Optional: Allow cancellation reason
-	var cancelRequest struct {
-		Reason string `json:"reason,omitempty"`
+	var cancelRequest dto.CancelBookingRequest
+	if err := c.ShouldBindJSON(&cancelRequest); err != nil && !errors.Is(err, io.EOF) {
+		c.Error(dto.BindError(err))
+		return
 	}
-	
-	// Try to bind JSON if provided, but don't require it
-	_ = c.ShouldBindJSON(&cancelRequest)
 
 	// Prepare notes
 	notes := "Booking cancelled by user"
@@ -220,11 +258,9 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 	}
 
 	// Attempt to cancel the booking
-	err = h.bookingService.CancelBooking(uint(id), currentUserID)
+	err = h.bookingService.CancelBooking(c.Request.Context(), actor, uint(id), notes)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to cancel booking: " + err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -241,23 +277,111 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// AssignProvider assigns a service provider to a booking
+func (h *BookingHandler) AssignProvider(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.Error(apperror.Validation("Invalid booking ID"))
+		return
+	}
+
+	var providerRequest dto.AssignProviderRequest
+	if !bindJSON(c, &providerRequest) {
+		return
+	}
+
+	if err := h.bookingService.AssignProvider(c.Request.Context(), uint(id), providerRequest.ProviderID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Provider assigned successfully",
+		"provider_id": providerRequest.ProviderID,
+	})
+}
+
 // Other existing methods...
 
+// GetBookingByReferenceCode shows a guest the booking of a magic link
 func (h *BookingHandler) GetBookingByReferenceCode(c *gin.Context) {
 	referenceCode := c.Param("code")
+	token := c.Query("token")
 	
-	if referenceCode == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking reference code"})
+	if referenceCode == "" || token == "" {
+		c.Error(apperror.Validation("Booking reference code and token are required"))
 		return
 	}
 	
-	booking, err := h.bookingService.GetBookingByReferenceCode(referenceCode)
+	booking, err := h.guestAccessService.LookupByToken(c.Request.Context(), referenceCode, token)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		c.Error(err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, booking)
+	c.JSON(http.StatusOK, guestBookingResponse(booking))
+}
+
+// LookupBookingByReferenceCode shows a guest a booking after checking the
+// phone number it was made with
+func (h *BookingHandler) LookupBookingByReferenceCode(c *gin.Context) {
+	var lookupRequest dto.BookingLookupRequest
+	if !bindJSON(c, &lookupRequest) {
+		return
+	}
+
+	booking, err := h.guestAccessService.LookupByPhone(c.Request.Context(), c.Param("code"), lookupRequest.Phone)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, guestBookingResponse(booking))
+}
+
+// guestBookingResponse is the redacted view of a booking shown to guests
+func guestBookingResponse(booking *model.Booking) gin.H {
+	statusHistory := make([]gin.H, 0, len(booking.StatusHistory))
+	for _, history := range booking.StatusHistory {
+		statusHistory = append(statusHistory, gin.H{
+			"status":     history.Status,
+			"created_at": history.CreatedAt,
+		})
+	}
+
+	return gin.H{
+		"booking_reference_code": booking.BookingReferenceCode,
+		"status":                 booking.Status,
+		"service": gin.H{
+			"id":   booking.Service.ID,
+			"name": booking.Service.Name,
+		},
+		"scheduled_at":   booking.ScheduledAt,
+		"duration":       booking.Duration,
+		"total_price":    booking.TotalPrice,
+		"user_name":      firstName(booking.UserName),
+		"phone_number":   maskPhone(booking.PhoneNumber),
+		"created_at":     booking.CreatedAt,
+		"status_history": statusHistory,
+	}
+}
+
+// firstName returns the first word of a name
+func firstName(name string) string {
+	if fields := strings.Fields(name); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// maskPhone hides all but the last three digits of a phone number
+func maskPhone(phoneNumber string) string {
+	const visible = 3
+	if len(phoneNumber) <= visible {
+		return strings.Repeat("*", len(phoneNumber))
+	}
+	return strings.Repeat("*", len(phoneNumber)-visible) + phoneNumber[len(phoneNumber)-visible:]
 }
 
 // CreateUserBooking method for authenticated users to create their own bookings
@@ -265,30 +389,30 @@ func (h *BookingHandler) CreateUserBooking(c *gin.Context) {
 	// Get user ID from context
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.Error(apperror.Unauthorized("User not authenticated"))
 		return
 	}
 
 	// Convert user ID to uint
 	currentUserID, ok := userID.(uint)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+		c.Error(apperror.Internal("Invalid user ID", nil))
 		return
 	}
 
 	// Parse booking data
-	var booking model.Booking
-	if err := c.ShouldBindJSON(&booking); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var bookingRequest dto.CreateBookingRequest
+	if !bindJSON(c, &bookingRequest) {
 		return
 	}
+	booking := bookingRequest.ToModel()
 
 	// Set user ID from context
-	booking.UserID = currentUserID
+	booking.UserID = &currentUserID
 
 	// Create booking
-	if err := h.bookingService.CreateBooking(&booking); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking: " + err.Error()})
+	if err := h.bookingService.CreateBooking(c.Request.Context(), &booking); err != nil {
+		c.Error(err)
 		return
 	}
 	
@@ -5,7 +5,8 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"service-booking/internal/model"
+	"service-booking/internal/apperror"
+	"service-booking/internal/dto"
 	"service-booking/internal/service"
 )
 
@@ -48,9 +49,9 @@ func (h *CategoryHandler) GetCategories(c *gin.Context) {
 		}
 	}
 
-	categories, count, err := h.categoryService.GetCategories(page, limit, filters)
+	categories, count, err := h.categoryService.GetCategories(c.Request.Context(), page, limit, filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		c.Error(err)
 		return
 	}
 
@@ -69,13 +70,13 @@ func (h *CategoryHandler) GetCategoryByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		c.Error(apperror.Validation("Invalid category ID"))
 		return
 	}
 	
-	category, err := h.categoryService.GetCategoryByID(uint(id))
+	category, err := h.categoryService.GetCategoryByID(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		c.Error(err)
 		return
 	}
 	
@@ -83,14 +84,14 @@ func (h *CategoryHandler) GetCategoryByID(c *gin.Context) {
 }
 
 func (h *CategoryHandler) CreateCategory(c *gin.Context) {
-	var category model.Category
-	if err := c.ShouldBindJSON(&category); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var categoryRequest dto.CategoryRequest
+	if !bindJSON(c, &categoryRequest) {
 		return
 	}
+	category := categoryRequest.ToModel()
 	
-	if err := h.categoryService.CreateCategory(&category); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+	if err := h.categoryService.CreateCategory(c.Request.Context(), &category); err != nil {
+		c.Error(err)
 		return
 	}
 	
@@ -101,20 +102,20 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		c.Error(apperror.Validation("Invalid category ID"))
 		return
 	}
 	
-	var category model.Category
-	if err := c.ShouldBindJSON(&category); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var categoryRequest dto.CategoryRequest
+	if !bindJSON(c, &categoryRequest) {
 		return
 	}
+	category := categoryRequest.ToModel()
 	
 	category.ID = uint(id)
 	
-	if err := h.categoryService.UpdateCategory(&category); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+	if err := h.categoryService.UpdateCategory(c.Request.Context(), &category); err != nil {
+		c.Error(err)
 		return
 	}
 	
@@ -125,12 +126,12 @@ func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		c.Error(apperror.Validation("Invalid category ID"))
 		return
 	}
 	
-	if err := h.categoryService.DeleteCategory(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+	if err := h.categoryService.DeleteCategory(c.Request.Context(), uint(id)); err != nil {
+		c.Error(err)
 		return
 	}
 	
@@ -141,13 +142,13 @@ func (h *CategoryHandler) GetSubCategories(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		c.Error(apperror.Validation("Invalid category ID"))
 		return
 	}
 	
-	subCategories, err := h.categoryService.GetSubCategories(uint(id))
+	subCategories, err := h.categoryService.GetSubCategories(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subcategories"})
+		c.Error(err)
 		return
 	}
 	
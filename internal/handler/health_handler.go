@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"service-booking/internal/health"
+	"service-booking/pkg/logging"
+)
+
+// readinessTimeout bounds the readiness checks so probes get an answer
+const readinessTimeout = 3 * time.Second
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker}
+}
+
+// Liveness reports that the process is able to serve requests
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Readiness reports whether the dependencies needed to serve traffic are
+// healthy, answering 503 so load balancers stop routing to the instance.
+// Only the status of each check is returned; failures are logged.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	report := h.checker.Run(ctx)
+	if !report.Ready() {
+		logger := logging.FromContext(c.Request.Context())
+		for name, result := range report.Checks {
+			if result.Status != health.StatusOK {
+				logger.Warn("Readiness check failed", "check", name, "error", result.Error, "details", result.Details)
+			}
+		}
+		c.JSON(http.StatusServiceUnavailable, report.Summary())
+		return
+	}
+	c.JSON(http.StatusOK, report.Summary())
+}
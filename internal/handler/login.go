@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+	"service-booking/pkg/auth"
+)
+
+// challengeSecondFactor answers a login with a challenge for
+// /auth/mfa/verify when the user enrolled in two-factor authentication,
+// reporting whether it did. Every login method must call it before
+// starting a session.
+func challengeSecondFactor(c *gin.Context, tokens *auth.TokenService, user *model.User) bool {
+	if !user.TOTPEnabled {
+		return false
+	}
+
+	mfaToken, err := tokens.GenerateMFAToken(user.ID, user.Email, user.Name, string(user.Role))
+	if err != nil {
+		c.Error(apperror.Internal("Could not generate tokens", err))
+		return true
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_required": true,
+		"mfa_token":    mfaToken,
+	})
+	return true
+}
+
+// loginResponse is the body of a completed login. Staff who must use MFA
+// can only reach their profile until they enroll.
+func loginResponse(user gin.H, role model.UserRole, accessToken, refreshToken string) gin.H {
+	response := gin.H{
+		"user":          user,
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	}
+	if role.RequiresMFA() {
+		response["mfa_enrollment_required"] = true
+	}
+	return response
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/dto"
+	"service-booking/internal/service"
+	"service-booking/pkg/auth"
+	"service-booking/pkg/logging"
+)
+
+type MFAHandler struct {
+	mfaService     service.MFAService
+	authService    service.AuthService
+	loginThrottle  service.LoginThrottleService
+	sessionService service.SessionService
+	tokens         *auth.TokenService
+}
+
+func NewMFAHandler(
+	mfaService service.MFAService,
+	authService service.AuthService,
+	loginThrottle service.LoginThrottleService,
+	sessionService service.SessionService,
+	tokens *auth.TokenService,
+) *MFAHandler {
+	return &MFAHandler{mfaService, authService, loginThrottle, sessionService, tokens}
+}
+
+// BeginEnrollment generates a TOTP secret for the authenticated user
+func (h *MFAHandler) BeginEnrollment(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.Error(apperror.Unauthorized("User not authenticated"))
+		return
+	}
+
+	secret, uri, err := h.mfaService.BeginEnrollment(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": uri,
+	})
+}
+
+// ConfirmEnrollment enables TOTP after checking a code from the authenticator app
+func (h *MFAHandler) ConfirmEnrollment(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.Error(apperror.Unauthorized("User not authenticated"))
+		return
+	}
+
+	var confirmRequest dto.MFACodeRequest
+	if !bindJSON(c, &confirmRequest) {
+		return
+	}
+
+	recoveryCodes, err := h.mfaService.ConfirmEnrollment(c.Request.Context(), userID.(uint), confirmRequest.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// Disable turns off TOTP for the authenticated user
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.Error(apperror.Unauthorized("User not authenticated"))
+		return
+	}
+
+	var disableRequest dto.MFACodeRequest
+	if !bindJSON(c, &disableRequest) {
+		return
+	}
+
+	if err := h.mfaService.Disable(c.Request.Context(), userID.(uint), disableRequest.Code); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the authenticated user
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.Error(apperror.Unauthorized("User not authenticated"))
+		return
+	}
+
+	var regenerateRequest dto.MFACodeRequest
+	if !bindJSON(c, &regenerateRequest) {
+		return
+	}
+
+	recoveryCodes, err := h.mfaService.RegenerateRecoveryCodes(c.Request.Context(), userID.(uint), regenerateRequest.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+// Verify completes a two-step login with a TOTP or recovery code
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var verifyRequest dto.VerifyMFARequest
+	if !bindJSON(c, &verifyRequest) {
+		return
+	}
+
+	// Validate the challenge token from the first login step
+	claims, err := h.tokens.ValidateToken(verifyRequest.MFAToken)
+	if err != nil || claims.Type != string(auth.MFAToken) {
+		c.Error(apperror.Unauthorized("Invalid or expired MFA token"))
+		return
+	}
+
+	userID, err := strconv.ParseUint(claims.UserID, 10, 64)
+	if err != nil {
+		c.Error(apperror.Unauthorized("Invalid user ID"))
+		return
+	}
+
+	// Codes are guessable, so they share the login throttling
+	if err := h.loginThrottle.Check(c.Request.Context(), claims.Email, c.ClientIP()); err != nil {
+		var throttled *service.ThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.Error(apperror.RateLimited("Too many failed login attempts. Try again later"))
+			return
+		}
+		c.Error(apperror.OrInternal("Could not process login", err))
+		return
+	}
+
+	if err := h.mfaService.Verify(c.Request.Context(), uint(userID), verifyRequest.Code); err != nil {
+		if errors.Is(err, service.ErrMFAInvalidCode) {
+			if recordErr := h.loginThrottle.RecordFailure(c.Request.Context(), claims.Email, c.ClientIP()); recordErr != nil {
+				logging.FromContext(c.Request.Context()).Error("Failed to record login failure", "error", recordErr)
+			}
+		}
+		c.Error(err)
+		return
+	}
+
+	if err := h.loginThrottle.RecordSuccess(c.Request.Context(), claims.Email); err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed to reset login failures", "error", err)
+	}
+
+	// Reload the user so the tokens reflect the current role
+	user, err := h.authService.GetUserByID(c.Request.Context(), uint(userID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Generate JWT tokens
+	accessToken, refreshToken, err := h.sessionService.StartSession(
+		c.Request.Context(),
+		user,
+		c.Request.UserAgent(),
+		c.ClientIP(),
+		auth.WithMFA(true),
+	)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Prepare response (remove sensitive info)
+	userResponse := gin.H{
+		"id":    user.ID,
+		"email": user.Email,
+		"name":  user.Name,
+		"role":  user.Role,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":          userResponse,
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	})
+}
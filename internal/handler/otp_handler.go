@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/dto"
+	"service-booking/internal/service"
+	"service-booking/pkg/auth"
+)
+
+type OTPHandler struct {
+	otpService     service.OTPService
+	sessionService service.SessionService
+	tokens         *auth.TokenService
+}
+
+func NewOTPHandler(otpService service.OTPService, sessionService service.SessionService, tokens *auth.TokenService) *OTPHandler {
+	return &OTPHandler{otpService, sessionService, tokens}
+}
+
+// RequestOTP sends a one-time login code to the given phone number
+func (h *OTPHandler) RequestOTP(c *gin.Context) {
+	var otpRequest dto.OTPRequest
+	if !bindJSON(c, &otpRequest) {
+		return
+	}
+
+	if err := h.otpService.RequestOTP(c.Request.Context(), otpRequest.Phone); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification code sent"})
+}
+
+// VerifyOTP logs in the owner of the phone number, registering them on first login
+func (h *OTPHandler) VerifyOTP(c *gin.Context) {
+	var verifyRequest dto.VerifyOTPRequest
+	if !bindJSON(c, &verifyRequest) {
+		return
+	}
+
+	user, err := h.otpService.VerifyOTP(c.Request.Context(), verifyRequest.Phone, verifyRequest.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Enrolled users must complete the second step at /auth/mfa/verify
+	if challengeSecondFactor(c, h.tokens, user) {
+		return
+	}
+
+	// Generate JWT tokens
+	accessToken, refreshToken, err := h.sessionService.StartSession(
+		c.Request.Context(),
+		user,
+		c.Request.UserAgent(),
+		c.ClientIP(),
+	)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Prepare response (remove sensitive info)
+	userResponse := gin.H{
+		"id":    user.ID,
+		"email": user.Email,
+		"name":  user.Name,
+		"phone": user.Phone,
+		"role":  user.Role,
+	}
+
+	c.JSON(http.StatusOK, loginResponse(userResponse, user.Role, accessToken, refreshToken))
+}
+
+// VerifyGuestPhone checks a code sent to a phone number and returns a guest
+// token that allows booking with that number without an account
+func (h *OTPHandler) VerifyGuestPhone(c *gin.Context) {
+	var verifyRequest dto.VerifyOTPRequest
+	if !bindJSON(c, &verifyRequest) {
+		return
+	}
+
+	phoneNumber, err := h.otpService.VerifyPhone(c.Request.Context(), verifyRequest.Phone, verifyRequest.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	guestToken, err := h.tokens.GenerateGuestToken(phoneNumber)
+	if err != nil {
+		c.Error(apperror.Internal("Could not generate token", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"phone":       phoneNumber,
+		"guest_token": guestToken,
+		"expires_in":  int(h.tokens.Config().GuestTokenDuration.Seconds()),
+	})
+}
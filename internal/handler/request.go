@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"service-booking/internal/dto"
+)
+
+// bindJSON binds and validates the request body, reporting the field errors
+// when it is invalid
+func bindJSON(c *gin.Context, request interface{}) bool {
+	if err := c.ShouldBindJSON(request); err != nil {
+		c.Error(dto.BindError(err))
+		return false
+	}
+	return true
+}
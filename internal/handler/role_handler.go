@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"service-booking/internal/apperror"
+	"service-booking/internal/dto"
+	"service-booking/internal/model"
+	"service-booking/internal/service"
+)
+
+type RoleHandler struct {
+	roleService service.RoleService
+}
+
+func NewRoleHandler(roleService service.RoleService) *RoleHandler {
+	return &RoleHandler{roleService}
+}
+
+func (h *RoleHandler) GetRoles(c *gin.Context) {
+	roles, err := h.roleService.GetRoles(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        roles,
+		"permissions": model.AllPermissions,
+	})
+}
+
+func (h *RoleHandler) GetRoleByID(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.Error(apperror.Validation("Invalid role ID"))
+		return
+	}
+
+	role, err := h.roleService.GetRoleByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var roleRequest dto.RoleRequest
+	if !bindJSON(c, &roleRequest) {
+		return
+	}
+	role := roleRequest.ToModel()
+
+	if err := h.roleService.CreateRole(c.Request.Context(), &role); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, role)
+}
+
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.Error(apperror.Validation("Invalid role ID"))
+		return
+	}
+
+	var roleRequest dto.RoleRequest
+	if !bindJSON(c, &roleRequest) {
+		return
+	}
+	role := roleRequest.ToModel()
+
+	role.ID = uint(id)
+
+	if err := h.roleService.UpdateRole(c.Request.Context(), &role); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.Error(apperror.Validation("Invalid role ID"))
+		return
+	}
+
+	if err := h.roleService.DeleteRole(c.Request.Context(), uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// AssignUserRole changes the role of a user
+func (h *RoleHandler) AssignUserRole(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.Error(apperror.Validation("Invalid user ID"))
+		return
+	}
+
+	var roleRequest dto.AssignRoleRequest
+	if !bindJSON(c, &roleRequest) {
+		return
+	}
+
+	user, err := h.roleService.AssignRole(c.Request.Context(), uint(id), roleRequest.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":    user.ID,
+		"email": user.Email,
+		"name":  user.Name,
+		"role":  user.Role,
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"service-booking/internal/apperror"
+	"service-booking/internal/dto"
 	"service-booking/internal/model"
 	"service-booking/internal/service"
 )
@@ -41,7 +43,7 @@ func (h *ServiceHandler) GetServices(c *gin.Context) {
 	if categoryIDStr != "" {
 		categoryID, err := strconv.ParseUint(categoryIDStr, 10, 32)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+			c.Error(apperror.Validation("Invalid category ID"))
 			return
 		}
 		
@@ -50,9 +52,9 @@ func (h *ServiceHandler) GetServices(c *gin.Context) {
 	}
 
 	// Use GetServices with filters
-	services, count, err = h.serviceService.GetServices(page, limit, filters)
+	services, count, err = h.serviceService.GetServices(c.Request.Context(), page, limit, filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
+		c.Error(err)
 		return
 	}
 
@@ -77,9 +79,9 @@ func (h *ServiceHandler) GetFeaturedServices(c *gin.Context) {
 	}
 
 	// Fetch featured services
-	services, err := h.serviceService.GetFeaturedServices(limit)
+	services, err := h.serviceService.GetFeaturedServices(c.Request.Context(), limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch featured services"})
+		c.Error(err)
 		return
 	}
 
@@ -97,7 +99,7 @@ func (h *ServiceHandler) GetFeaturedServicesByCategory(c *gin.Context) {
 	categoryIDStr := c.Param("category_id")
 	categoryID, err := strconv.ParseUint(categoryIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		c.Error(apperror.Validation("Invalid category ID"))
 		return
 	}
 
@@ -116,9 +118,9 @@ func (h *ServiceHandler) GetFeaturedServicesByCategory(c *gin.Context) {
 	}
 
 	// Fetch featured services for the specific category
-	services, count, err := h.serviceService.GetServices(1, limit, filters)
+	services, count, err := h.serviceService.GetServices(c.Request.Context(), 1, limit, filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch featured services"})
+		c.Error(err)
 		return
 	}
 
@@ -135,13 +137,13 @@ func (h *ServiceHandler) GetServiceByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		c.Error(apperror.Validation("Invalid service ID"))
 		return
 	}
 	
-	service, err := h.serviceService.GetServiceByID(uint(id))
+	service, err := h.serviceService.GetServiceByID(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		c.Error(err)
 		return
 	}
 	
@@ -149,14 +151,14 @@ func (h *ServiceHandler) GetServiceByID(c *gin.Context) {
 }
 
 func (h *ServiceHandler) CreateService(c *gin.Context) {
-	var service model.Service
-	if err := c.ShouldBindJSON(&service); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var serviceRequest dto.ServiceRequest
+	if !bindJSON(c, &serviceRequest) {
 		return
 	}
+	service := serviceRequest.ToModel()
 	
-	if err := h.serviceService.CreateService(&service); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service"})
+	if err := h.serviceService.CreateService(c.Request.Context(), &service); err != nil {
+		c.Error(err)
 		return
 	}
 	
@@ -167,29 +169,30 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		c.Error(apperror.Validation("Invalid service ID"))
 		return
 	}
 	
 	// Fetch existing service
-	existingService, err := h.serviceService.GetServiceByID(uint(id))
+	existingService, err := h.serviceService.GetServiceByID(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		c.Error(err)
 		return
 	}
 	
 	// Bind update data
-	var updateData model.Service
-	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var serviceRequest dto.ServiceRequest
+	if !bindJSON(c, &serviceRequest) {
 		return
 	}
 	
 	// Update fields
+	updateData := serviceRequest.ToModel()
 	updateData.ID = existingService.ID
+	updateData.CreatedAt = existingService.CreatedAt
 	
-	if err := h.serviceService.UpdateService(&updateData); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update service"})
+	if err := h.serviceService.UpdateService(c.Request.Context(), &updateData); err != nil {
+		c.Error(err)
 		return
 	}
 	
@@ -200,12 +203,12 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		c.Error(apperror.Validation("Invalid service ID"))
 		return
 	}
 	
-	if err := h.serviceService.DeleteService(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete service"})
+	if err := h.serviceService.DeleteService(c.Request.Context(), uint(id)); err != nil {
+		c.Error(err)
 		return
 	}
 	
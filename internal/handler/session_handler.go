@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/service"
+)
+
+type SessionHandler struct {
+	sessionService service.SessionService
+}
+
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService}
+}
+
+// GetSessions lists the devices the authenticated user is logged in on
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.Error(apperror.Unauthorized("User not authenticated"))
+		return
+	}
+
+	sessions, err := h.sessionService.GetSessions(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Mark the session making this request
+	currentSessionID, _ := c.Get("session_id")
+	data := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		data = append(data, gin.H{
+			"id":                session.ID,
+			"user_agent":        session.UserAgent,
+			"ip_address":        session.IPAddress,
+			"created_at":        session.CreatedAt,
+			"last_refreshed_at": session.LastRefreshedAt,
+			"expires_at":        session.ExpiresAt,
+			"current":           currentSessionID == session.ID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// RevokeSession logs the authenticated user out of a device
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.Error(apperror.Unauthorized("User not authenticated"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.Error(apperror.Validation("Invalid session ID"))
+		return
+	}
+
+	if err := h.sessionService.RevokeSession(c.Request.Context(), userID.(uint), uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/service"
+	"service-booking/pkg/auth"
+)
+
+const loginStateCookie = "oidc_login_state"
+
+type SocialAuthHandler struct {
+	socialAuthService service.SocialAuthService
+	sessionService    service.SessionService
+	tokens            *auth.TokenService
+}
+
+func NewSocialAuthHandler(socialAuthService service.SocialAuthService, sessionService service.SessionService, tokens *auth.TokenService) *SocialAuthHandler {
+	return &SocialAuthHandler{socialAuthService, sessionService, tokens}
+}
+
+// GetProviders lists the configured social login providers
+func (h *SocialAuthHandler) GetProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.socialAuthService.Providers()})
+}
+
+// BeginLogin redirects the user to the identity provider
+func (h *SocialAuthHandler) BeginLogin(c *gin.Context) {
+	provider := c.Param("provider")
+
+	authURL, state, err := h.socialAuthService.BeginLogin(provider)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !h.setLoginState(c, state) {
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// BeginLink returns the identity provider URL that links a provider account
+// to the signed-in user. The client must follow it in the same browser.
+func (h *SocialAuthHandler) BeginLink(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.Error(apperror.Unauthorized("User not authenticated"))
+		return
+	}
+
+	authURL, state, err := h.socialAuthService.BeginLink(c.Param("provider"), userID.(uint))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !h.setLoginState(c, state) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// setLoginState keeps the PKCE verifier and nonce in an HTTP-only cookie
+// scoped to the flow
+func (h *SocialAuthHandler) setLoginState(c *gin.Context, state *service.LoginState) bool {
+	encodedState, err := h.socialAuthService.EncodeLoginState(state)
+	if err != nil {
+		c.Error(err)
+		return false
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(loginStateCookie, encodedState, 600, "/api/v1/auth/oidc", "", gin.Mode() == gin.ReleaseMode, true)
+	return true
+}
+
+// Callback completes the login and issues our own tokens, or completes
+// linking a provider account started with BeginLink
+func (h *SocialAuthHandler) Callback(c *gin.Context) {
+	provider := c.Param("provider")
+
+	if errorCode := c.Query("error"); errorCode != "" {
+		c.Error(apperror.Unauthorized("Login was not completed: " + errorCode))
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		c.Error(apperror.Validation("Authorization code is missing"))
+		return
+	}
+
+	encodedState, err := c.Cookie(loginStateCookie)
+	if err != nil {
+		c.Error(apperror.Validation("Login state is missing"))
+		return
+	}
+
+	state, err := h.socialAuthService.DecodeLoginState(encodedState)
+	if err != nil || state.State != c.Query("state") {
+		c.Error(apperror.Validation("Invalid or expired login state"))
+		return
+	}
+
+	// The login state is single use
+	c.SetCookie(loginStateCookie, "", -1, "/api/v1/auth/oidc", "", gin.Mode() == gin.ReleaseMode, true)
+
+	if state.LinkUserID != 0 {
+		if err := h.socialAuthService.CompleteLink(c.Request.Context(), provider, code, state); err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Identity provider linked successfully"})
+		return
+	}
+
+	user, err := h.socialAuthService.CompleteLogin(c.Request.Context(), provider, code, state)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Enrolled users must complete the second step at /auth/mfa/verify
+	if challengeSecondFactor(c, h.tokens, user) {
+		return
+	}
+
+	// Generate JWT tokens
+	accessToken, refreshToken, err := h.sessionService.StartSession(
+		c.Request.Context(),
+		user,
+		c.Request.UserAgent(),
+		c.ClientIP(),
+	)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Prepare response (remove sensitive info)
+	userResponse := gin.H{
+		"id":    user.ID,
+		"email": user.Email,
+		"name":  user.Name,
+		"role":  user.Role,
+	}
+
+	c.JSON(http.StatusOK, loginResponse(userResponse, user.Role, accessToken, refreshToken))
+}
@@ -0,0 +1,186 @@
+// Package health reports whether the application can serve traffic. Checks
+// are run by the readiness endpoint; liveness only needs the process to
+// respond.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"service-booking/pkg/migrate"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc checks one dependency, returning details to include in the report
+type CheckFunc func(ctx context.Context) (map[string]interface{}, error)
+
+// Result is the outcome of one check
+type Result struct {
+	Status  string                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Report is the outcome of all checks
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Ready reports whether every check passed
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Summary is the public form of a Report, which leaves out errors and
+// details as they may reveal internals of the deployment
+type Summary struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Summary returns the status of every check
+func (r Report) Summary() Summary {
+	summary := Summary{Status: r.Status, Checks: make(map[string]string, len(r.Checks))}
+	for name, result := range r.Checks {
+		summary.Checks[name] = result.Status
+	}
+	return summary
+}
+
+// namedCheck is a registered check
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Checker runs the registered readiness checks
+type Checker struct {
+	checks []namedCheck
+}
+
+// NewChecker creates a Checker without checks
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Register adds a check reported under name
+func (c *Checker) Register(name string, check CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Run runs every check concurrently and reports failure if any fails
+func (c *Checker) Run(ctx context.Context) Report {
+	results := make([]Result, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, named := range c.checks {
+		wg.Add(1)
+		go func(i int, check CheckFunc) {
+			defer wg.Done()
+
+			details, err := check(ctx)
+			results[i] = Result{Status: StatusOK, Details: details}
+			if err != nil {
+				results[i].Status = StatusFail
+				results[i].Error = err.Error()
+			}
+		}(i, named.check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks))}
+	for i, named := range c.checks {
+		report.Checks[named.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// DatabaseCheck pings the database
+func DatabaseCheck(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		if err := db.PingContext(ctx); err != nil {
+			return nil, fmt.Errorf("ping failed: %w", err)
+		}
+
+		stats := db.Stats()
+		return map[string]interface{}{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+		}, nil
+	}
+}
+
+// MigrationCheck fails while migrations are pending or a migration failed
+// halfway, reporting the applied schema version
+func MigrationCheck(migrator *migrate.Migrator) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var version int64
+		pending := 0
+		for _, status := range statuses {
+			if status.Dirty {
+				return map[string]interface{}{"version": status.Version}, migrate.ErrDirty
+			}
+			if status.Applied {
+				version = status.Version
+			} else {
+				pending++
+			}
+		}
+
+		details := map[string]interface{}{"version": version}
+		if pending > 0 {
+			details["pending"] = pending
+			return details, fmt.Errorf("%d pending migrations", pending)
+		}
+		return details, nil
+	}
+}
+
+// Worker tracks the outcome of the last run of a background job
+type Worker struct {
+	mu      sync.Mutex
+	lastRun time.Time
+	lastErr error
+}
+
+// Report records a run of the job
+func (w *Worker) Report(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.lastRun = time.Now()
+	w.lastErr = err
+}
+
+// Check fails when the last run of the job failed. Jobs that have not run
+// yet are healthy.
+func (w *Worker) Check(ctx context.Context) (map[string]interface{}, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.lastRun.IsZero() {
+		return nil, nil
+	}
+
+	details := map[string]interface{}{"last_run": w.lastRun.UTC().Format(time.RFC3339)}
+	if w.lastErr != nil {
+		return details, fmt.Errorf("last run failed: %w", w.lastErr)
+	}
+	return details, nil
+}
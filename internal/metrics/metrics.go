@@ -0,0 +1,210 @@
+// Package metrics collects Prometheus metrics for HTTP traffic, database
+// queries and booking events. Every App has its own registry, so instances
+// in one process do not share counters.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+
+	"service-booking/internal/model"
+)
+
+// queryStartKey stores the start time of a query on the GORM statement
+const queryStartKey = "metrics:query_start"
+
+// Metrics holds the collectors of one application instance
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	httpInFlight prometheus.Gauge
+
+	queryDuration *prometheus.HistogramVec
+	queryErrors   *prometheus.CounterVec
+
+	bookingsCreated   *prometheus.CounterVec
+	statusTransitions *prometheus.CounterVec
+	cancellations     *prometheus.CounterVec
+	revenue           *prometheus.CounterVec
+}
+
+// New creates the collectors, including the Go runtime and process ones
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by route template and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time to serve HTTP requests, by route template and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests currently being served.",
+		}),
+
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Time to run database queries, by operation and table.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_query_errors_total",
+			Help: "Failed database queries, by operation and table. Missing records are not errors.",
+		}, []string{"operation", "table"}),
+
+		bookingsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bookings_created_total",
+			Help: "Bookings created, by service category.",
+		}, []string{"category"}),
+		statusTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "booking_status_transitions_total",
+			Help: "Booking status changes, by previous and new status.",
+		}, []string{"from", "to"}),
+		cancellations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "booking_cancellations_total",
+			Help: "Cancelled bookings, by service category and role of the user who cancelled.",
+		}, []string{"category", "role"}),
+		revenue: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "booking_revenue_total",
+			Help: "Total price of completed bookings, by service category.",
+		}, []string{"category"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.httpInFlight,
+		m.queryDuration,
+		m.queryErrors,
+		m.bookingsCreated,
+		m.statusTransitions,
+		m.cancellations,
+		m.revenue,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RequestStarted counts a request as in flight
+func (m *Metrics) RequestStarted() {
+	m.httpInFlight.Inc()
+}
+
+// RequestFinished records a served request. route is the route template,
+// e.g. /api/v1/bookings/:id, so IDs do not create new series.
+func (m *Metrics) RequestFinished(method, route string, status int, duration time.Duration) {
+	m.httpInFlight.Dec()
+
+	statusLabel := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, statusLabel).Inc()
+	m.httpDuration.WithLabelValues(method, route, statusLabel).Observe(duration.Seconds())
+}
+
+// BookingCreated records a new booking of a service in the category
+func (m *Metrics) BookingCreated(booking *model.Booking, category string) {
+	m.bookingsCreated.WithLabelValues(category).Inc()
+}
+
+// BookingStatusChanged records a status change made by a user with role
+func (m *Metrics) BookingStatusChanged(booking *model.Booking, from, to model.BookingStatus, category, role string) {
+	m.statusTransitions.WithLabelValues(string(from), string(to)).Inc()
+
+	switch to {
+	case model.BookingStatusCancelled:
+		m.cancellations.WithLabelValues(category, role).Inc()
+	case model.BookingStatusCompleted:
+		// Revenue is counted once, even if a booking is completed again
+		if from != model.BookingStatusCompleted {
+			m.revenue.WithLabelValues(category).Add(booking.TotalPrice)
+		}
+	}
+}
+
+// InstrumentDB records the latency and errors of every query on the
+// connection and exports its pool statistics
+func (m *Metrics) InstrumentDB(conn *gorm.DB, name string) error {
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	if err := m.registry.Register(collectors.NewDBStatsCollector(sqlDB, name)); err != nil {
+		return err
+	}
+
+	callbacks := conn.Callback()
+	hooks := []struct {
+		operation     string
+		before, after callbackRegistrar
+	}{
+		{"create", callbacks.Create().Before("gorm:create"), callbacks.Create().After("gorm:create")},
+		{"query", callbacks.Query().Before("gorm:query"), callbacks.Query().After("gorm:query")},
+		{"update", callbacks.Update().Before("gorm:update"), callbacks.Update().After("gorm:update")},
+		{"delete", callbacks.Delete().Before("gorm:delete"), callbacks.Delete().After("gorm:delete")},
+		{"row", callbacks.Row().Before("gorm:row"), callbacks.Row().After("gorm:row")},
+		{"raw", callbacks.Raw().Before("gorm:raw"), callbacks.Raw().After("gorm:raw")},
+	}
+
+	for _, hook := range hooks {
+		if err := hook.before.Register("metrics:before_"+hook.operation, startQuery); err != nil {
+			return err
+		}
+		if err := hook.after.Register("metrics:after_"+hook.operation, m.finishQuery(hook.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callbackRegistrar registers a GORM callback at a position in a chain
+type callbackRegistrar interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// startQuery notes when a query started
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+// finishQuery records the latency and outcome of a query
+func (m *Metrics) finishQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		m.queryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			m.queryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"service-booking/internal/apperror"
+	"service-booking/pkg/logging"
+)
+
+// errorResponse is the body of every error response
+type errorResponse struct {
+	Code    string                `json:"code"`
+	Message string                `json:"message"`
+	Fields  []apperror.FieldError `json:"fields,omitempty"`
+}
+
+// ErrorHandler renders the last error a handler added with c.Error, unless
+// a response was already written
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		renderPendingError(c)
+	}
+}
+
+// renderPendingError writes the last error of the context if nothing has
+// been written yet
+func renderPendingError(c *gin.Context) {
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+	renderError(c, c.Errors.Last().Err)
+}
+
+// abortWithError writes err and stops the remaining handlers
+func abortWithError(c *gin.Context, err error) {
+	renderError(c, err)
+	c.Abort()
+}
+
+// renderError writes err with the status of its kind. Errors without a kind
+// are logged and reported as internal errors.
+func renderError(c *gin.Context, err error) {
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		appErr = apperror.Internal("Internal server error", err)
+	}
+
+	status := errorStatus(appErr.Kind)
+	if status >= http.StatusInternalServerError {
+		logging.FromContext(c.Request.Context()).Error("Request failed", "error", err)
+	}
+
+	writeError(c, status, appErr)
+}
+
+// writeError writes the body of an error response
+func writeError(c *gin.Context, status int, err *apperror.Error) {
+	c.JSON(status, errorResponse{
+		Code:    err.ErrorCode(),
+		Message: err.Message,
+		Fields:  err.Fields,
+	})
+}
+
+// errorStatus maps an error kind to its HTTP status code
+func errorStatus(kind apperror.Kind) int {
+	switch kind {
+	case apperror.KindNotFound:
+		return http.StatusNotFound
+	case apperror.KindConflict:
+		return http.StatusConflict
+	case apperror.KindValidation:
+		return http.StatusBadRequest
+	case apperror.KindUnauthorized:
+		return http.StatusUnauthorized
+	case apperror.KindForbidden:
+		return http.StatusForbidden
+	case apperror.KindRateLimited:
+		return http.StatusTooManyRequests
+	case apperror.KindUnavailable:
+		return http.StatusServiceUnavailable
+	case apperror.KindTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
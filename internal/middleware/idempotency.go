@@ -0,0 +1,248 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+	"service-booking/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// idempotencyPurgeInterval is how often expired keys are deleted
+	idempotencyPurgeInterval = time.Hour
+	// idempotencyLease is how long a request holds its key before a retry
+	// may take it over, e.g. after the process crashed
+	idempotencyLease = time.Minute
+)
+
+// IdempotencyStore keeps the responses of idempotent requests
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, record *model.IdempotencyKey) (*model.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, record *model.IdempotencyKey) error
+	Delete(ctx context.Context, id uint) error
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+// Idempotency middleware makes a POST endpoint safe to retry. A request with
+// an Idempotency-Key header is processed once; retries with the same key and
+// body get the stored response, and reuse of the key with a different body
+// is rejected. Keys are scoped to the client and route and expire after ttl.
+// A request holds its key for a short lease, after which a retry of a
+// request that never completed is processed again.
+// Stored responses hold personal data, so they are encrypted with
+// encryptionKey, a 32-byte AES key. It must run after any authentication
+// middleware of the route.
+func Idempotency(store IdempotencyStore, ttl time.Duration, encryptionKey []byte) gin.HandlerFunc {
+	var lastPurge atomic.Int64
+
+	responses, err := newResponseCipher(encryptionKey)
+	if err != nil {
+		panic("middleware: invalid idempotency encryption key: " + err.Error())
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			abortWithError(c, apperror.Validation("Idempotency-Key is too long"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, apperror.Validation("Could not read request body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		if last := lastPurge.Load(); now.Sub(time.Unix(0, last)) > idempotencyPurgeInterval && lastPurge.CompareAndSwap(last, now.UnixNano()) {
+			// The purge outlives the request, so it must not be cancelled with it
+			ctx := context.WithoutCancel(c.Request.Context())
+			logger := logging.FromContext(ctx)
+			go func() {
+				if err := store.DeleteExpired(ctx, now); err != nil {
+					logger.Error("Failed to delete expired idempotency keys", "error", err)
+				}
+			}()
+		}
+
+		lockedUntil := now.Add(idempotencyLease)
+		record := &model.IdempotencyKey{
+			Scope:       idempotencyScope(c),
+			Key:         key,
+			Fingerprint: requestFingerprint(c, body),
+			LockedUntil: &lockedUntil,
+			ExpiresAt:   now.Add(ttl),
+		}
+
+		existing, reserved, err := store.Reserve(c.Request.Context(), record)
+		if err != nil {
+			abortWithError(c, apperror.OrInternal("Could not process Idempotency-Key", err))
+			return
+		}
+
+		if !reserved {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				writeError(c, http.StatusUnprocessableEntity, apperror.Conflict("Idempotency-Key was already used with a different request").WithCode("idempotency_key_reused"))
+			case existing.CompletedAt == nil:
+				renderError(c, apperror.Conflict("A request with this Idempotency-Key is still being processed"))
+			default:
+				body, err := responses.open(existing.ResponseBody, existing.Scope, existing.Key)
+				if err != nil {
+					renderError(c, apperror.Internal("Could not replay the stored response", err))
+					break
+				}
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.StatusCode, existing.ContentType, body)
+			}
+			c.Abort()
+			return
+		}
+
+		// Release the key if the handler panics so the client can retry
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if err := store.Delete(c.Request.Context(), record.ID); err != nil {
+					logging.FromContext(c.Request.Context()).Error("Failed to release idempotency key", "error", err)
+				}
+				panic(recovered)
+			}
+		}()
+
+		writer := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// Render handler errors now so they are stored with the response
+		renderPendingError(c)
+
+		// Server errors are not stored so the request can be retried
+		if writer.Status() >= http.StatusInternalServerError {
+			if err := store.Delete(c.Request.Context(), record.ID); err != nil {
+				logging.FromContext(c.Request.Context()).Error("Failed to release idempotency key", "error", err)
+			}
+			return
+		}
+
+		completedAt := time.Now()
+		record.StatusCode = writer.Status()
+		record.ContentType = writer.Header().Get("Content-Type")
+		record.CompletedAt = &completedAt
+		record.LockedUntil = nil
+		record.ResponseBody, err = responses.seal(writer.body.Bytes(), record.Scope, record.Key)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("Failed to encrypt idempotent response", "error", err)
+		} else if err = store.Complete(c.Request.Context(), record); err != nil {
+			logging.FromContext(c.Request.Context()).Error("Failed to store idempotent response", "error", err)
+		}
+
+		// Release the key so a retry is processed instead of waiting for it
+		if err != nil {
+			if err := store.Delete(c.Request.Context(), record.ID); err != nil {
+				logging.FromContext(c.Request.Context()).Error("Failed to release idempotency key", "error", err)
+			}
+		}
+	}
+}
+
+// idempotencyScope keeps keys of different clients and routes apart. Guests
+// are told apart by a hash of their guest token, which the handler verifies.
+func idempotencyScope(c *gin.Context) string {
+	client := "anonymous"
+	if apiKeyID, exists := c.Get("api_key_id"); exists {
+		client = fmt.Sprintf("api_key:%v", apiKeyID)
+	} else if userID, exists := c.Get("user_id"); exists {
+		client = fmt.Sprintf("user:%v", userID)
+	} else if guestToken := c.GetHeader("X-Guest-Token"); guestToken != "" {
+		sum := sha256.Sum256([]byte(guestToken))
+		client = "guest:" + hex.EncodeToString(sum[:])
+	}
+	return fmt.Sprintf("%s %s %s", c.Request.Method, c.FullPath(), client)
+}
+
+// responseCipher encrypts stored responses with AES-GCM. The scope and key
+// are authenticated too, so a response cannot be moved to another record.
+type responseCipher struct {
+	aead cipher.AEAD
+}
+
+func newResponseCipher(key []byte) (*responseCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &responseCipher{aead}, nil
+}
+
+// seal encrypts a response body for storage
+func (r *responseCipher) seal(body []byte, scope, key string) (string, error) {
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := r.aead.Seal(nonce, nonce, body, []byte(scope+"\n"+key))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a response body stored by seal
+func (r *responseCipher) open(stored, scope, key string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(stored)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < r.aead.NonceSize() {
+		return nil, errors.New("stored response is too short")
+	}
+	nonce, ciphertext := sealed[:r.aead.NonceSize()], sealed[r.aead.NonceSize():]
+	return r.aead.Open(nil, nonce, ciphertext, []byte(scope+"\n"+key))
+}
+
+// requestFingerprint identifies the request a key was first used with
+func requestFingerprint(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"service-booking/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyStoreStub keeps records in memory and fails to complete them
+type idempotencyStoreStub struct {
+	records map[string]*model.IdempotencyKey
+	nextID  uint
+}
+
+func (s *idempotencyStoreStub) Reserve(ctx context.Context, record *model.IdempotencyKey) (*model.IdempotencyKey, bool, error) {
+	if existing, ok := s.records[record.Scope+"\n"+record.Key]; ok {
+		return existing, false, nil
+	}
+	s.nextID++
+	record.ID = s.nextID
+	s.records[record.Scope+"\n"+record.Key] = record
+	return record, true, nil
+}
+
+func (s *idempotencyStoreStub) Complete(ctx context.Context, record *model.IdempotencyKey) error {
+	return errors.New("store unavailable")
+}
+
+func (s *idempotencyStoreStub) Delete(ctx context.Context, id uint) error {
+	for key, record := range s.records {
+		if record.ID == id {
+			delete(s.records, key)
+		}
+	}
+	return nil
+}
+
+func (s *idempotencyStoreStub) DeleteExpired(ctx context.Context, before time.Time) error {
+	return nil
+}
+
+func TestIdempotencyReleasesKeyWhenStoreFails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &idempotencyStoreStub{records: make(map[string]*model.IdempotencyKey)}
+	calls := 0
+
+	router := gin.New()
+	router.POST("/bookings", Idempotency(store, time.Hour, make([]byte, 32)), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	for i := 1; i <= 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewBufferString(`{}`))
+		req.Header.Set("Idempotency-Key", "retry-1")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("request %d: got status %d: %s", i, recorder.Code, recorder.Body)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want the retry to be processed again", calls)
+	}
+	if len(store.records) != 0 {
+		t.Errorf("the key was not released")
+	}
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"service-booking/internal/apperror"
+	"service-booking/pkg/logging"
+)
+
+// RequestIDHeader carries the ID that correlates the logs of a request
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs accepted from clients
+const maxRequestIDLength = 128
+
+// RequestID middleware assigns every request an ID, taken from a valid
+// X-Request-ID header or generated, and echoes it in the response. The
+// request context carries a logger with the ID, method, route and trace ID,
+// which handlers get with logging.FromContext.
+func RequestID(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		attrs := []any{"request_id", id, "method", c.Request.Method, "route", route}
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			attrs = append(attrs, "trace_id", span.TraceID().String())
+		}
+		setLogger(c, logger.With(attrs...))
+
+		c.Next()
+	}
+}
+
+// RequestLogger middleware logs every served request: server errors at
+// error level, client errors at warn level and the rest at info level
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		// The query string is left out as it may hold tokens
+		ctx := c.Request.Context()
+		logging.FromContext(ctx).LogAttrs(ctx, level, "Request served",
+			slog.Int("status", status),
+			slog.String("path", c.Request.URL.Path),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// Recovery middleware turns panics into internal errors and logs them with
+// their stack trace
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		logging.FromContext(c.Request.Context()).Error("Handler panicked",
+			"panic", recovered,
+			"stack", string(debug.Stack()),
+		)
+		if !c.Writer.Written() {
+			writeError(c, http.StatusInternalServerError, apperror.Internal("Internal server error", nil))
+		}
+		c.Abort()
+	})
+}
+
+// addLogAttrs adds attributes to the logger of the request
+func addLogAttrs(c *gin.Context, args ...any) {
+	setLogger(c, logging.FromContext(c.Request.Context()).With(args...))
+}
+
+// setLogger replaces the logger of the request
+func setLogger(c *gin.Context, logger *slog.Logger) {
+	c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+}
+
+// validRequestID reports whether a client supplied request ID is safe to log
+// and echo: 1 to 128 letters, digits, dots, dashes, underscores or colons
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '-', r == '_', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random 128-bit request ID
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
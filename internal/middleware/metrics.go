@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that did not match a route, so unknown
+// paths do not create new series
+const unmatchedRoute = "unmatched"
+
+// RequestRecorder records served requests
+type RequestRecorder interface {
+	RequestStarted()
+	RequestFinished(method, route string, status int, duration time.Duration)
+}
+
+// Metrics middleware records the latency and status of every request by
+// route template
+func Metrics(recorder RequestRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		recorder.RequestStarted()
+
+		// Deferred so panicking handlers are not left in flight
+		defer func() {
+			route := c.FullPath()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			recorder.RequestFinished(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+		}()
+
+		c.Next()
+	}
+}
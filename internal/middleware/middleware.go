@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"context"
+	"crypto/subtle"
 	"net/http"
+	"service-booking/internal/apperror"
 	"service-booking/internal/model"
 	"service-booking/pkg/auth"
 	"strconv"
@@ -10,127 +13,275 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// JWTAuth middleware for authenticating JWT tokens
-func JWTAuth() gin.HandlerFunc {
+// APIKeyAuthenticator resolves partner API keys
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey string) (*model.APIKey, error)
+}
+
+// TokenValidator verifies the signature and expiry of JWT tokens
+type TokenValidator interface {
+	ValidateToken(tokenString string) (*auth.JWTClaims, error)
+}
+
+// JWTAuth middleware for authenticating JWT tokens. When apiKeys is not nil,
+// an X-API-Key header is accepted as an alternative to the Bearer token.
+func JWTAuth(tokens TokenValidator, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get the Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is missing"})
+		if !authenticate(c, tokens, apiKeys) {
 			c.Abort()
 			return
 		}
 
-		// Check the header format
-		headerParts := strings.Split(authHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		c.Next()
+	}
+}
+
+// OptionalAuth middleware authenticates the request only when credentials
+// are present, letting anonymous requests through
+func OptionalAuth(tokens TokenValidator, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" && c.GetHeader("X-API-Key") == "" {
+			c.Next()
+			return
+		}
+
+		if !authenticate(c, tokens, apiKeys) {
 			c.Abort()
 			return
 		}
 
-		// Extract the token
-		tokenString := headerParts[1]
+		c.Next()
+	}
+}
 
-		// Validate the token
-		claims, err := auth.ValidateToken(tokenString)
+// authenticate validates the request credentials and sets the user
+// information in the context, writing an error response on failure
+func authenticate(c *gin.Context, tokens TokenValidator, apiKeys APIKeyAuthenticator) bool {
+	// Partner integrations authenticate with an API key
+	if rawKey := c.GetHeader("X-API-Key"); rawKey != "" && apiKeys != nil {
+		apiKey, err := apiKeys.Authenticate(c.Request.Context(), rawKey)
+		if apperror.IsTransient(err) {
+			abortWithError(c, err)
+			return false
+		}
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
-			return
+			abortWithError(c, apperror.Unauthorized("Invalid or expired API key"))
+			return false
 		}
 
-		// Check if it's an access token
-		if claims.Type != string(auth.AccessToken) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token type"})
-			c.Abort()
+		// Set user information in the context
+		c.Set("user_id", apiKey.UserID)
+		c.Set("email", apiKey.User.Email)
+		c.Set("role", string(apiKey.User.Role))
+		c.Set("name", apiKey.User.Name)
+		c.Set("api_key_id", apiKey.ID)
+		c.Set("api_key_scopes", apiKey.Scopes)
+		c.Set("mfa", false)
+		addLogAttrs(c, "user_id", apiKey.UserID, "api_key_id", apiKey.ID)
+		return true
+	}
+
+	// Get the Authorization header
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		abortWithError(c, apperror.Unauthorized("Authorization header is missing"))
+		return false
+	}
+
+	// Check the header format
+	headerParts := strings.Split(authHeader, " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		abortWithError(c, apperror.Unauthorized("Invalid authorization header format"))
+		return false
+	}
+
+	// Extract the token
+	tokenString := headerParts[1]
+
+	// Validate the token
+	claims, err := tokens.ValidateToken(tokenString)
+	if err != nil {
+		abortWithError(c, apperror.Unauthorized("Invalid or expired token"))
+		return false
+	}
+
+	// Check if it's an access token
+	if claims.Type != string(auth.AccessToken) {
+		abortWithError(c, apperror.Unauthorized("Invalid token type"))
+		return false
+	}
+
+	// Convert UserID from string to uint
+	userID, err := strconv.ParseUint(claims.UserID, 10, 64)
+	if err != nil {
+		abortWithError(c, apperror.Unauthorized("Invalid user ID"))
+		return false
+	}
+
+	// Set user information in the context
+	c.Set("user_id", uint(userID))
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	c.Set("name", claims.Name)
+	c.Set("mfa", claims.MFA)
+	if sessionID, err := strconv.ParseUint(claims.SessionID, 10, 64); err == nil {
+		c.Set("session_id", uint(sessionID))
+	}
+	addLogAttrs(c, "user_id", uint(userID))
+	return true
+}
+
+// RequireToken middleware admits only requests with the given bearer token,
+// for internal endpoints such as metrics
+func RequireToken(token string) gin.HandlerFunc {
+	expected := []byte("Bearer " + token)
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+			abortWithError(c, apperror.Unauthorized("Invalid or missing token"))
 			return
 		}
 
-		// Convert UserID from string to uint
-		userID, err := strconv.ParseUint(claims.UserID, 10, 64)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
-			c.Abort()
+		c.Next()
+	}
+}
+
+// RequireMFA middleware rejects users whose role requires multi-factor
+// authentication unless they logged in with a second factor
+func RequireMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		roleName, _ := role.(string)
+
+		if model.UserRole(roleName).RequiresMFA() && !c.GetBool("mfa") {
+			abortWithError(c, apperror.Forbidden("Two-factor authentication is required for this account").WithCode("mfa_required"))
 			return
 		}
 
-		// Set user information in the context
-		c.Set("user_id", uint(userID))
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
-		c.Set("name", claims.Name)
-
 		c.Next()
 	}
 }
 
-// AdminOnly middleware to restrict access to admin routes
-func AdminOnly() gin.HandlerFunc {
+// PermissionChecker resolves the permissions granted to a role
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, roleName string, permission model.Permission) (bool, error)
+}
+
+// RequirePermission middleware restricts access to users whose role grants
+// all of the given permissions
+func RequirePermission(checker PermissionChecker, permissions ...model.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get the role from the context (set by JWTAuth middleware)
 		role, exists := c.Get("role")
 		if !exists {
-			c.JSON(http.StatusForbidden, gin.H{"error": "User role not found"})
-			c.Abort()
+			abortWithError(c, apperror.Forbidden("User role not found"))
 			return
 		}
 
-		// Check if the role is admin
-		if role != string(model.UserRoleAdmin) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. Admin rights required"})
-			c.Abort()
-			return
+		// API keys are further limited to their granted scopes
+		scopes, isAPIKey := c.Get("api_key_scopes")
+		grantedScopes, _ := scopes.([]model.Permission)
+
+		roleName, _ := role.(string)
+		for _, permission := range permissions {
+			if isAPIKey && !hasScope(grantedScopes, permission) {
+				abortWithError(c, apperror.Forbidden("Access denied. API key is missing scope "+string(permission)))
+				return
+			}
+
+			allowed, err := checker.HasPermission(c.Request.Context(), roleName, permission)
+			if err != nil {
+				abortWithError(c, apperror.OrInternal("Failed to check permissions", err))
+				return
+			}
+
+			if !allowed {
+				abortWithError(c, apperror.Forbidden("Access denied. Missing permission "+string(permission)))
+				return
+			}
 		}
 
 		c.Next()
 	}
 }
 
-// RefreshTokenHandler handles token refresh
-func RefreshTokenHandler(c *gin.Context) {
-	// Get the refresh token from the request
-	refreshTokenString := c.GetHeader("X-Refresh-Token")
-	if refreshTokenString == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token is missing"})
-		return
-	}
+// RequireScope middleware limits API keys to routes whose scopes they were
+// granted. Without scopes the route cannot be used with an API key at all.
+// Requests authenticated with a token pass through.
+func RequireScope(scopes ...model.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, isAPIKey := c.Get("api_key_scopes")
+		if !isAPIKey {
+			c.Next()
+			return
+		}
 
-	// Validate the refresh token
-	claims, err := auth.ValidateToken(refreshTokenString)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
-		return
-	}
+		if len(scopes) == 0 {
+			abortWithError(c, apperror.Forbidden("Access denied. This route cannot be used with an API key"))
+			return
+		}
 
-	// Check if it's a refresh token
-	if claims.Type != string(auth.RefreshToken) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token type"})
-		return
-	}
+		grantedScopes, _ := granted.([]model.Permission)
+		for _, scope := range scopes {
+			if !hasScope(grantedScopes, scope) {
+				abortWithError(c, apperror.Forbidden("Access denied. API key is missing scope "+string(scope)))
+				return
+			}
+		}
 
-	// Convert UserID from string to uint
-	userID, err := strconv.ParseUint(claims.UserID, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
-		return
+		c.Next()
 	}
+}
 
-	// Generate new access and refresh tokens
-	accessToken, refreshToken, err := auth.GenerateAllTokens(
-		uint(userID), 
-		claims.Email, 
-		claims.Name, 
-		claims.Role,
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new tokens"})
-		return
+// hasScope reports whether the permission is among the granted scopes
+func hasScope(scopes []model.Permission, permission model.Permission) bool {
+	for _, scope := range scopes {
+		if scope == permission {
+			return true
+		}
 	}
+	return false
+}
+
+// SessionRefresher renews the tokens of a login session
+type SessionRefresher interface {
+	RefreshSession(ctx context.Context, claims *auth.JWTClaims, userAgent, ipAddress string) (accessToken, refreshToken string, err error)
+}
+
+// RefreshTokenHandler handles token refresh, refusing refresh tokens that
+// belong to revoked sessions
+func RefreshTokenHandler(tokens TokenValidator, sessions SessionRefresher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the refresh token from the request
+		refreshTokenString := c.GetHeader("X-Refresh-Token")
+		if refreshTokenString == "" {
+			abortWithError(c, apperror.Unauthorized("Refresh token is missing"))
+			return
+		}
+
+		// Validate the refresh token
+		claims, err := tokens.ValidateToken(refreshTokenString)
+		if err != nil {
+			abortWithError(c, apperror.Unauthorized("Invalid refresh token"))
+			return
+		}
+
+		// Check if it's a refresh token
+		if claims.Type != string(auth.RefreshToken) {
+			abortWithError(c, apperror.Unauthorized("Invalid token type"))
+			return
+		}
 
-	// Return the new tokens
-	c.JSON(http.StatusOK, gin.H{
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
-	})
-}
\ No newline at end of file
+		// Generate new access and refresh tokens for the session
+		accessToken, refreshToken, err := sessions.RefreshSession(c.Request.Context(), claims, c.Request.UserAgent(), c.ClientIP())
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		// Return the new tokens
+		c.JSON(http.StatusOK, gin.H{
+			"access_token":  accessToken,
+			"refresh_token": refreshToken,
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+	"service-booking/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitStore spends tokens from shared token buckets
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit int, period time.Duration) (*model.RateLimitBucket, bool, error)
+	DeleteIdle(ctx context.Context, keyPrefix string, before time.Time) error
+}
+
+// rateLimitPurgeInterval is how often idle buckets of a policy are deleted
+const rateLimitPurgeInterval = time.Hour
+
+// RateLimitPolicy allows Limit requests per Period, refilled continuously
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// ParseRateLimitPolicy parses a policy written as "<limit>/<period>", e.g. "10/1m"
+func ParseRateLimitPolicy(name, spec string) (RateLimitPolicy, error) {
+	parts := strings.SplitN(strings.TrimSpace(spec), "/", 2)
+	if len(parts) != 2 {
+		return RateLimitPolicy{}, fmt.Errorf("invalid rate limit %q, expected <limit>/<period>", spec)
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || limit < 1 {
+		return RateLimitPolicy{}, fmt.Errorf("invalid rate limit %q: limit must be a positive number", spec)
+	}
+
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return RateLimitPolicy{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", spec)
+	}
+
+	return RateLimitPolicy{Name: name, Limit: limit, Period: period}, nil
+}
+
+// RateLimit middleware limits requests with a token bucket per client. Clients
+// are identified by API key, then user, then IP, so it must run after any
+// authentication middleware of the route.
+func RateLimit(store RateLimitStore, policy RateLimitPolicy) gin.HandlerFunc {
+	rate := float64(policy.Limit) / policy.Period.Seconds()
+	var lastPurge atomic.Int64
+
+	return func(c *gin.Context) {
+		// Buckets unused for a whole period are full again and can be dropped
+		now := time.Now()
+		if last := lastPurge.Load(); now.Sub(time.Unix(0, last)) > rateLimitPurgeInterval && lastPurge.CompareAndSwap(last, now.UnixNano()) {
+			// The purge outlives the request, so it must not be cancelled with it
+			ctx := context.WithoutCancel(c.Request.Context())
+			logger := logging.FromContext(ctx)
+			go func() {
+				if err := store.DeleteIdle(ctx, policy.Name+":", now.Add(-policy.Period)); err != nil {
+					logger.Error("Failed to delete idle rate limit buckets", "error", err)
+				}
+			}()
+		}
+
+		bucket, allowed, err := store.Take(c.Request.Context(), rateLimitKey(c, policy), policy.Limit, policy.Period)
+		if err != nil {
+			// Fail open so an unavailable store does not take the API down
+			logging.FromContext(c.Request.Context()).Error("Rate limit check failed", "error", err)
+			c.Next()
+			return
+		}
+
+		remaining := int(math.Floor(bucket.Tokens))
+		reset := math.Ceil((float64(policy.Limit) - bucket.Tokens) / rate)
+
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Period.Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(int(reset)))
+
+		if !allowed {
+			retryAfter := math.Ceil((1 - bucket.Tokens) / rate)
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter)))
+			abortWithError(c, apperror.RateLimited("Too many requests. Try again later"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitKey identifies the client of the request within the policy
+func rateLimitKey(c *gin.Context, policy RateLimitPolicy) string {
+	if apiKeyID, exists := c.Get("api_key_id"); exists {
+		return fmt.Sprintf("%s:api_key:%v", policy.Name, apiKeyID)
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		return fmt.Sprintf("%s:user:%v", policy.Name, userID)
+	}
+	return fmt.Sprintf("%s:ip:%s", policy.Name, c.ClientIP())
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"service-booking/internal/model"
+	"service-booking/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseRateLimitPolicy(t *testing.T) {
+	policy, err := ParseRateLimitPolicy("auth", " 10 / 1m ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy != (RateLimitPolicy{Name: "auth", Limit: 10, Period: time.Minute}) {
+		t.Errorf("got %+v", policy)
+	}
+
+	for _, spec := range []string{"", "10", "0/1m", "-1/1m", "x/1m", "10/0s", "10/-1m", "10/minute"} {
+		if _, err := ParseRateLimitPolicy("auth", spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+// rateLimitRouter serves GET /limited behind RateLimit. Requests with an
+// X-User header are treated as authenticated as that user.
+func rateLimitRouter(store RateLimitStore, policy RateLimitPolicy) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/limited", func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("user_id", user)
+		}
+	}, RateLimit(store, policy), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func rateLimitRequest(router *gin.Engine, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRateLimit(t *testing.T) {
+	router := rateLimitRouter(repository.NewMemoryRateLimitStore(), RateLimitPolicy{Name: "test", Limit: 2, Period: time.Hour})
+
+	for i, remaining := range []string{"1", "0"} {
+		recorder := rateLimitRequest(router, "")
+		if recorder.Code != http.StatusNoContent {
+			t.Fatalf("request %d: got status %d", i+1, recorder.Code)
+		}
+		if got := recorder.Header().Get("RateLimit-Remaining"); got != remaining {
+			t.Errorf("request %d: RateLimit-Remaining = %s, want %s", i+1, got, remaining)
+		}
+		if got := recorder.Header().Get("RateLimit-Policy"); got != "2;w=3600" {
+			t.Errorf("request %d: RateLimit-Policy = %s", i+1, got)
+		}
+	}
+
+	recorder := rateLimitRequest(router, "")
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("got status %d, want 429", recorder.Code)
+	}
+	if got := recorder.Header().Get("Retry-After"); got != "1800" {
+		t.Errorf("Retry-After = %s, want 1800", got)
+	}
+
+	// Authenticated clients have their own bucket
+	if recorder := rateLimitRequest(router, "1"); recorder.Code != http.StatusNoContent {
+		t.Errorf("authenticated request: got status %d", recorder.Code)
+	}
+}
+
+// failingRateLimitStore is a store that is unavailable
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit int, period time.Duration) (*model.RateLimitBucket, bool, error) {
+	return nil, false, errors.New("store unavailable")
+}
+
+func (failingRateLimitStore) DeleteIdle(ctx context.Context, keyPrefix string, before time.Time) error {
+	return errors.New("store unavailable")
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	router := rateLimitRouter(failingRateLimitStore{}, RateLimitPolicy{Name: "test", Limit: 1, Period: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		if recorder := rateLimitRequest(router, ""); recorder.Code != http.StatusNoContent {
+			t.Fatalf("request %d: got status %d", i+1, recorder.Code)
+		}
+	}
+}
+
+// purgeRecorder is a memory store reporting the idle bucket purges
+type purgeRecorder struct {
+	repository.RateLimitStore
+	purges chan string
+}
+
+func (s *purgeRecorder) DeleteIdle(ctx context.Context, keyPrefix string, before time.Time) error {
+	err := s.RateLimitStore.DeleteIdle(ctx, keyPrefix, before)
+	s.purges <- keyPrefix
+	return err
+}
+
+func TestRateLimitDeletesIdleBuckets(t *testing.T) {
+	store := &purgeRecorder{RateLimitStore: repository.NewMemoryRateLimitStore(), purges: make(chan string, 2)}
+	policy := RateLimitPolicy{Name: "test", Limit: 1, Period: 50 * time.Millisecond}
+	router := rateLimitRouter(store, policy)
+
+	rateLimitRequest(router, "")
+	if prefix := <-store.purges; prefix != "test:" {
+		t.Errorf("purged buckets with prefix %q, want test:", prefix)
+	}
+	if recorder := rateLimitRequest(router, ""); recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("got status %d, want 429", recorder.Code)
+	}
+
+	// The bucket is dropped once it is idle for a whole period
+	time.Sleep(2 * policy.Period)
+	if err := store.DeleteIdle(context.Background(), "test:", time.Now().Add(-policy.Period)); err != nil {
+		t.Fatal(err)
+	}
+	<-store.purges
+	// An hour-long period would leave the old, empty bucket without tokens
+	_, allowed, err := store.Take(context.Background(), "test:ip:192.0.2.1", policy.Limit, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !allowed {
+		t.Error("the idle bucket was not deleted")
+	}
+
+	// Only one purge runs per interval
+	rateLimitRequest(router, "")
+	select {
+	case prefix := <-store.purges:
+		t.Errorf("unexpected second purge of %q", prefix)
+	default:
+	}
+}
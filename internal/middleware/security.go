@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"service-booking/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig holds cross-origin resource sharing configuration
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// DefaultCORSConfig provides CORS configuration from the application config
+func DefaultCORSConfig(cfg *config.Config) CORSConfig {
+	maxAge, err := time.ParseDuration(cfg.CORS.MaxAge)
+	if err != nil {
+		maxAge = 10 * time.Minute
+	}
+
+	return CORSConfig{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		ExposedHeaders:   cfg.CORS.ExposedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           maxAge,
+	}
+}
+
+// CORS middleware allows browsers on the configured origins to call the API.
+// Origins may be exact ("https://sheba.xyz"), a subdomain wildcard
+// ("https://*.sheba.xyz") or "*" for any origin without credentials.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	allowedMethods := strings.Join(cfg.AllowedMethods, ", ")
+	allowedHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		// Responses differ per origin and must not be shared by caches
+		c.Writer.Header().Add("Vary", "Origin")
+
+		allowAny, allowed := matchOrigin(cfg.AllowedOrigins, origin)
+		if !allowed {
+			// Preflights of unknown origins end here; other requests are
+			// served without CORS headers so the browser blocks the response
+			if isPreflight(c) {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAny {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+			if cfg.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if isPreflight(c) {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			c.Header("Access-Control-Allow-Methods", allowedMethods)
+			c.Header("Access-Control-Allow-Headers", allowedHeaders)
+			c.Header("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if exposedHeaders != "" {
+			c.Header("Access-Control-Expose-Headers", exposedHeaders)
+		}
+
+		c.Next()
+	}
+}
+
+// matchOrigin reports whether the origin is allowed and whether that is
+// because any origin is
+func matchOrigin(allowedOrigins []string, origin string) (allowAny bool, allowed bool) {
+	for _, allowedOrigin := range allowedOrigins {
+		switch {
+		case allowedOrigin == "*":
+			return true, true
+		case strings.EqualFold(allowedOrigin, origin):
+			return false, true
+		case strings.Contains(allowedOrigin, "://*."):
+			// https://*.example.com matches any subdomain, but not the apex
+			scheme, domain, _ := strings.Cut(allowedOrigin, "://*")
+			if strings.HasPrefix(strings.ToLower(origin), strings.ToLower(scheme)+"://") &&
+				strings.HasSuffix(strings.ToLower(origin), strings.ToLower(domain)) &&
+				len(origin) > len(scheme)+3+len(domain) {
+				return false, true
+			}
+		}
+	}
+	return false, false
+}
+
+func isPreflight(c *gin.Context) bool {
+	return c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+}
+
+// SecurityHeadersConfig holds the security response headers configuration
+type SecurityHeadersConfig struct {
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	FrameOptions          string
+	ContentSecurityPolicy string
+	ReferrerPolicy        string
+}
+
+// DefaultSecurityHeadersConfig provides security headers configuration from the application config
+func DefaultSecurityHeadersConfig(cfg *config.Config) SecurityHeadersConfig {
+	hstsMaxAge, err := time.ParseDuration(cfg.SecurityHeaders.HSTSMaxAge)
+	if err != nil {
+		hstsMaxAge = 0
+	}
+
+	return SecurityHeadersConfig{
+		HSTSMaxAge:            hstsMaxAge,
+		HSTSIncludeSubdomains: cfg.SecurityHeaders.HSTSIncludeSubdomains,
+		FrameOptions:          cfg.SecurityHeaders.FrameOptions,
+		ContentSecurityPolicy: cfg.SecurityHeaders.ContentSecurityPolicy,
+		ReferrerPolicy:        cfg.SecurityHeaders.ReferrerPolicy,
+	}
+}
+
+// SecurityHeaders middleware sets headers that harden browsers against
+// sniffing, framing and downgrade attacks
+func SecurityHeaders(cfg SecurityHeadersConfig) gin.HandlerFunc {
+	var hsts string
+	if cfg.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", int(cfg.HSTSMaxAge.Seconds()))
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		if hsts != "" {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		if cfg.FrameOptions != "" {
+			header.Set("X-Frame-Options", cfg.FrameOptions)
+		}
+		if cfg.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+		}
+		if cfg.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", cfg.ReferrerPolicy)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+type APIKey struct {
+	ID           uint         `gorm:"primaryKey" json:"id"`
+	Name         string       `gorm:"size:100;not null" json:"name"`
+	Prefix       string       `gorm:"size:20;not null;uniqueIndex" json:"prefix"`
+	KeyHash      string       `gorm:"size:64;not null" json:"-"`
+	Organization string       `gorm:"size:255;not null" json:"organization"`
+	Scopes       []Permission `gorm:"serializer:json;type:text" json:"scopes"`
+	UserID       uint         `gorm:"not null;index" json:"user_id"`
+	User         User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	CreatedBy    uint         `json:"created_by"`
+	ExpiresAt    *time.Time   `json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time   `json:"last_used_at,omitempty"`
+	RevokedAt    *time.Time   `json:"revoked_at,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// IsActive reports whether the key can still be used
+func (k *APIKey) IsActive() bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt)
+}
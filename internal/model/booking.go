@@ -18,8 +18,9 @@ type Booking struct {
 	ID                   uint                 `gorm:"primaryKey" json:"id"`
 	ServiceID            uint                 `gorm:"not null" json:"service_id"`
 	Service              Service              `gorm:"foreignKey:ServiceID" json:"service,omitempty"`
-	UserID               uint                 `gorm:"not null" json:"user_id"`
-	User                 User                 `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	UserID               *uint                `gorm:"index" json:"user_id"` // Nil for guest bookings
+	User                 *User                `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	ProviderID           *uint                `gorm:"index" json:"provider_id,omitempty"`
 	UserName             string               `gorm:"size:255;not null" json:"user_name"`
 	PhoneNumber          string               `gorm:"size:20;not null" json:"phone_number"`
 	Email                string               `gorm:"size:255" json:"email"`
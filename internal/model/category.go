@@ -13,6 +13,7 @@ type Category struct {
 	IsActive         bool       `gorm:"default:true" json:"is_active"`
 	IconURL          string     `json:"icon_url"`
 	DisplayOrder     int        `json:"display_order"`
+	ReferencePrefix  string     `gorm:"size:5" json:"reference_prefix"`
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at"`
 	Services         []Service  `gorm:"foreignKey:CategoryID" json:"services,omitempty"`
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// IdempotencyKey records the response of a request sent with an
+// Idempotency-Key header so retries can be answered without repeating it
+type IdempotencyKey struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Scope        string     `gorm:"size:255;not null;uniqueIndex:idx_idempotency_scope_key" json:"scope"`
+	Key          string     `gorm:"column:idempotency_key;size:255;not null;uniqueIndex:idx_idempotency_scope_key" json:"key"`
+	Fingerprint  string     `gorm:"size:64;not null" json:"fingerprint"`
+	StatusCode   int        `json:"status_code"`
+	ContentType  string     `gorm:"size:255" json:"content_type"`
+	ResponseBody string     `gorm:"type:mediumtext" json:"-"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+	ExpiresAt    time.Time  `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+// LoginAttempt tracks failed logins for a throttling key (an email or an IP)
+type LoginAttempt struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	ThrottleKey   string     `gorm:"size:255;not null;uniqueIndex" json:"throttle_key"`
+	Failures      int        `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+)
+
+type OTPCode struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Phone      string     `gorm:"size:20;not null;index" json:"phone"`
+	CodeHash   string     `gorm:"size:255;not null" json:"-"`
+	Attempts   int        `gorm:"not null;default:0" json:"attempts"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
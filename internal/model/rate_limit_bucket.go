@@ -0,0 +1,36 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimitBucket is a token bucket for a rate limiting key (a policy combined
+// with an IP, a user or an API key)
+type RateLimitBucket struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	BucketKey  string    `gorm:"size:255;not null;uniqueIndex" json:"bucket_key"`
+	Tokens     float64   `gorm:"not null" json:"tokens"`
+	RefilledAt time.Time `gorm:"type:datetime(3);not null" json:"refilled_at"`
+}
+
+// NewRateLimitBucket returns a full bucket
+func NewRateLimitBucket(key string, limit int, now time.Time) RateLimitBucket {
+	return RateLimitBucket{BucketKey: key, Tokens: float64(limit), RefilledAt: now}
+}
+
+// Take refills the bucket for the time elapsed since the last refill and
+// consumes a token when one is available
+func (b *RateLimitBucket) Take(limit int, period time.Duration, now time.Time) bool {
+	if elapsed := now.Sub(b.RefilledAt); elapsed > 0 {
+		refill := elapsed.Seconds() * float64(limit) / period.Seconds()
+		b.Tokens = math.Min(float64(limit), b.Tokens+refill)
+		b.RefilledAt = now
+	}
+
+	if b.Tokens < 1 {
+		return false
+	}
+	b.Tokens--
+	return true
+}
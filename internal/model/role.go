@@ -0,0 +1,84 @@
+package model
+
+import (
+	"time"
+)
+
+type Permission string
+
+const (
+	PermissionBookingRead         Permission = "booking:read"
+	PermissionBookingCreate       Permission = "booking:create"
+	PermissionBookingUpdateStatus Permission = "booking:update_status"
+	PermissionServiceWrite        Permission = "service:write"
+	PermissionCategoryWrite       Permission = "category:write"
+	PermissionRefundApprove       Permission = "refund:approve"
+	PermissionRoleManage          Permission = "role:manage"
+	PermissionAPIKeyManage        Permission = "api_key:manage"
+	PermissionUserUnlock          Permission = "user:unlock"
+)
+
+// AllPermissions lists every permission that can be granted to a role
+var AllPermissions = []Permission{
+	PermissionBookingRead,
+	PermissionBookingCreate,
+	PermissionBookingUpdateStatus,
+	PermissionServiceWrite,
+	PermissionCategoryWrite,
+	PermissionRefundApprove,
+	PermissionRoleManage,
+	PermissionAPIKeyManage,
+	PermissionUserUnlock,
+}
+
+// DefaultRolePermissions are the permissions of the built-in roles
+var DefaultRolePermissions = map[UserRole][]Permission{
+	UserRoleAdmin: AllPermissions,
+	UserRoleOpsAgent: {
+		PermissionBookingRead,
+		PermissionBookingUpdateStatus,
+		PermissionServiceWrite,
+		PermissionCategoryWrite,
+	},
+	UserRoleFinance: {
+		PermissionBookingRead,
+		PermissionRefundApprove,
+	},
+	UserRoleSupport: {
+		PermissionBookingRead,
+		PermissionBookingUpdateStatus,
+		PermissionUserUnlock,
+	},
+	UserRoleProvider: {},
+	UserRoleUser:     {},
+}
+
+type Role struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"size:50;not null;uniqueIndex" json:"name"`
+	Description string       `gorm:"type:text" json:"description"`
+	Permissions []Permission `gorm:"serializer:json;type:text" json:"permissions"`
+	IsSystem    bool         `gorm:"default:false" json:"is_system"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// HasPermission reports whether the role grants the given permission
+func (r *Role) HasPermission(permission Permission) bool {
+	for _, granted := range r.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidPermission reports whether the permission is a known one
+func IsValidPermission(permission Permission) bool {
+	for _, known := range AllPermissions {
+		if known == permission {
+			return true
+		}
+	}
+	return false
+}
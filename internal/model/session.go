@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// Session is a login on a device, created whenever tokens are issued and
+// kept alive by refreshing them
+type Session struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"not null;index" json:"user_id"`
+	UserAgent       string     `gorm:"size:512" json:"user_agent"`
+	IPAddress       string     `gorm:"size:45" json:"ip_address"`
+	RefreshTokenID  string     `gorm:"size:64" json:"-"` // ID of the only refresh token that is still valid
+	LastRefreshedAt *time.Time `json:"last_refreshed_at,omitempty"`
+	ExpiresAt       time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// IsActive reports whether tokens of the session can still be refreshed
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
@@ -7,19 +7,35 @@ import (
 type UserRole string
 
 const (
-	UserRoleAdmin UserRole = "admin"
-	UserRoleUser  UserRole = "user"
+	UserRoleAdmin    UserRole = "admin"
+	UserRoleOpsAgent UserRole = "ops_agent"
+	UserRoleFinance  UserRole = "finance"
+	UserRoleSupport  UserRole = "support"
+	UserRoleProvider UserRole = "provider"
+	UserRoleUser     UserRole = "user" // customer
 )
 
 type User struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	Name      string         `gorm:"size:255;not null" json:"name"`
 	Email     string         `gorm:"size:255;not null;uniqueIndex" json:"email"`
+	// EmailVerifiedAt is set once an identity provider verified the email address
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
 	Password  string         `gorm:"size:255;not null" json:"-"`
 	Phone     string         `gorm:"size:20" json:"phone"`
-	Role      UserRole       `gorm:"size:20;not null;default:user" json:"role"`
+	// PhoneVerifiedAt is set once the user proved the phone number with a code
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty"`
+	Role      UserRole       `gorm:"size:50;not null;default:user" json:"role"`
 	Token	 string         `gorm:"size:255" json:"token"`
 	RefreshToken string      `gorm:"size:255" json:"refresh_token"`
+	TOTPSecret   string      `gorm:"column:totp_secret;size:64" json:"-"`
+	TOTPEnabled  bool        `gorm:"column:totp_enabled;default:false" json:"totp_enabled"`
+	TOTPLastStep int64       `gorm:"column:totp_last_step;default:0" json:"-"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
-}
\ No newline at end of file
+}
+
+// RequiresMFA reports whether users with the role must log in with a second factor
+func (r UserRole) RequiresMFA() bool {
+	return r == UserRoleAdmin
+}
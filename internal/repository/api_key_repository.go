@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	FindAll(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.APIKey, int64, error)
+	FindByID(ctx context.Context, id uint) (*model.APIKey, error)
+	FindByPrefix(ctx context.Context, prefix string) (*model.APIKey, error)
+	Create(ctx context.Context, apiKey *model.APIKey) error
+	Update(ctx context.Context, apiKey *model.APIKey) error
+	UpdateLastUsed(ctx context.Context, id uint, usedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	conn
+}
+
+func NewAPIKeyRepository(db *gorm.DB, timeouts Timeouts) APIKeyRepository {
+	return &apiKeyRepository{conn{db, timeouts}}
+}
+
+func (r *apiKeyRepository) FindAll(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.APIKey, int64, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var apiKeys []model.APIKey
+	var count int64
+
+	offset := (page - 1) * limit
+	query := db.Model(&model.APIKey{})
+
+	// Apply filters
+	if filters != nil {
+		for key, value := range filters {
+			switch key {
+			case "organization":
+				query = query.Where("organization = ?", value)
+			case "user_id":
+				query = query.Where("user_id = ?", value)
+			case "revoked":
+				if value == true {
+					query = query.Where("revoked_at IS NOT NULL")
+				} else {
+					query = query.Where("revoked_at IS NULL")
+				}
+			}
+		}
+	}
+
+	// Count total records
+	err := query.Count(&count).Error
+	if err != nil {
+		return nil, 0, apperror.FromDB(err)
+	}
+
+	err = query.
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&apiKeys).Error
+
+	return apiKeys, count, apperror.FromDB(err)
+}
+
+func (r *apiKeyRepository) FindByID(ctx context.Context, id uint) (*model.APIKey, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var apiKey model.APIKey
+	err := db.First(&apiKey, id).Error
+	return &apiKey, apperror.FromDB(err)
+}
+
+func (r *apiKeyRepository) FindByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var apiKey model.APIKey
+	err := db.
+		Preload("User").
+		Where("prefix = ?", prefix).
+		First(&apiKey).Error
+	if err != nil {
+		return nil, apperror.FromDB(err)
+	}
+	return &apiKey, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, apiKey *model.APIKey) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Create(apiKey).Error)
+}
+
+func (r *apiKeyRepository) Update(ctx context.Context, apiKey *model.APIKey) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Save(apiKey).Error)
+}
+
+func (r *apiKeyRepository) UpdateLastUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Model(&model.APIKey{}).
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).Error)
+}
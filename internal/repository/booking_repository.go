@@ -1,37 +1,45 @@
 package repository
 
 import (
-	
+	"context"
+	"errors"
+
+	"service-booking/internal/apperror"
 	"service-booking/internal/model"
 
 	"gorm.io/gorm"
 )
 
 type BookingRepository interface {
-	FindAll(page, limit int, filters map[string]interface{}) ([]model.Booking, int64, error)
-	FindByID(id uint) (*model.Booking, error)
-	FindByReferenceCode(referenceCode string) (*model.Booking, error)
-	Create(booking *model.Booking) error
-	Update(booking *model.Booking) error
-	UpdateStatus(id uint, status model.BookingStatus) error
-	CreateWithStatusHistory(booking *model.Booking) error
-	UpdateStatusWithHistory(id uint, status model.BookingStatus, statusHistory *model.BookingStatusHistory) error
+	FindAll(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.Booking, int64, error)
+	FindByID(ctx context.Context, id uint) (*model.Booking, error)
+	FindByReferenceCode(ctx context.Context, referenceCode string) (*model.Booking, error)
+	Create(ctx context.Context, booking *model.Booking) error
+	Update(ctx context.Context, booking *model.Booking) error
+	UpdateStatus(ctx context.Context, id uint, status model.BookingStatus) error
+	UpdateProvider(ctx context.Context, id uint, providerID uint) error
+	ClaimGuestBookings(ctx context.Context, phoneNumber string, userID uint) (int64, error)
+	CreateWithStatusHistory(ctx context.Context, booking *model.Booking, referenceCode func() (string, error)) error
+	UpdateStatusWithHistory(ctx context.Context, id uint, from, status model.BookingStatus, statusHistory *model.BookingStatusHistory) (bool, error)
 }
 
 type bookingRepository struct {
-	db *gorm.DB
+	conn
 }
 
-func NewBookingRepository(db *gorm.DB) BookingRepository {
-	return &bookingRepository{db}
+func NewBookingRepository(db *gorm.DB, timeouts Timeouts) BookingRepository {
+	return &bookingRepository{conn{db, timeouts}}
 }
 
-func (r *bookingRepository) FindAll(page, limit int, filters map[string]interface{}) ([]model.Booking, int64, error) {
+func (r *bookingRepository) FindAll(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.Booking, int64, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var bookings []model.Booking
 	var count int64
 
 	offset := (page - 1) * limit
-	query := r.db.Model(&model.Booking{})
+	query := db.Model(&model.Booking{})
 
 	// Apply filters
 	if filters != nil {
@@ -41,6 +49,8 @@ func (r *bookingRepository) FindAll(page, limit int, filters map[string]interfac
 				query = query.Where("status = ?", value)
 			case "user_id":
 				query = query.Where("user_id = ?", value)
+			case "provider_id":
+				query = query.Where("provider_id = ?", value)
 			case "service_id":
 				query = query.Where("service_id = ?", value)
 			case "start_date":
@@ -54,7 +64,7 @@ func (r *bookingRepository) FindAll(page, limit int, filters map[string]interfac
 	// Count total records
 	err := query.Count(&count).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, apperror.FromDB(err)
 	}
 
 	// Fetch paginated results with preloading
@@ -68,24 +78,30 @@ func (r *bookingRepository) FindAll(page, limit int, filters map[string]interfac
 		Limit(limit).
 		Find(&bookings).Error
 
-	return bookings, count, err
+	return bookings, count, apperror.FromDB(err)
 }
 
-func (r *bookingRepository) FindByID(id uint) (*model.Booking, error) {
+func (r *bookingRepository) FindByID(ctx context.Context, id uint) (*model.Booking, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var booking model.Booking
-	err := r.db.
+	err := db.
 		Preload("Service").
 		Preload("User").
 		Preload("StatusHistory", func(db *gorm.DB) *gorm.DB {
 			return db.Order("created_at DESC")
 		}).
 		First(&booking, id).Error
-	return &booking, err
+	return &booking, apperror.FromDB(err)
 }
 
-func (r *bookingRepository) FindByReferenceCode(referenceCode string) (*model.Booking, error) {
+func (r *bookingRepository) FindByReferenceCode(ctx context.Context, referenceCode string) (*model.Booking, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var booking model.Booking
-	err := r.db.
+	err := db.
 		Where("booking_reference_code = ?", referenceCode).
 		Preload("Service").
 		Preload("User").
@@ -93,15 +109,42 @@ func (r *bookingRepository) FindByReferenceCode(referenceCode string) (*model.Bo
 			return db.Order("created_at DESC")
 		}).
 		First(&booking).Error
-	return &booking, err
+	return &booking, apperror.FromDB(err)
 }
 
-func (r *bookingRepository) Create(booking *model.Booking) error {
-	return r.db.Create(booking).Error
+func (r *bookingRepository) Create(ctx context.Context, booking *model.Booking) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Create(booking).Error)
 }
 
-func (r *bookingRepository) CreateWithStatusHistory(booking *model.Booking) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+// maxReferenceCodeAttempts bounds the retries on reference code collisions
+const maxReferenceCodeAttempts = 5
+
+// CreateWithStatusHistory creates a booking with a reference code from
+// referenceCode, retrying with a new code when it is already taken
+func (r *bookingRepository) CreateWithStatusHistory(ctx context.Context, booking *model.Booking, referenceCode func() (string, error)) error {
+	for attempt := 1; ; attempt++ {
+		code, err := referenceCode()
+		if err != nil {
+			return err
+		}
+		booking.BookingReferenceCode = code
+
+		err = r.createWithStatusHistory(ctx, booking)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) || attempt == maxReferenceCodeAttempts {
+			return apperror.FromDB(err)
+		}
+		booking.ID = 0
+	}
+}
+
+func (r *bookingRepository) createWithStatusHistory(ctx context.Context, booking *model.Booking) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Transaction(func(tx *gorm.DB) error {
 		// Create booking
 		if err := tx.Create(booking).Error; err != nil {
 			return err
@@ -115,30 +158,65 @@ func (r *bookingRepository) CreateWithStatusHistory(booking *model.Booking) erro
 			Notes:     "Booking created",
 		}
 		return tx.Create(statusHistory).Error
-	})
+	}))
 }
 
-func (r *bookingRepository) Update(booking *model.Booking) error {
-	return r.db.Save(booking).Error
+func (r *bookingRepository) Update(ctx context.Context, booking *model.Booking) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Save(booking).Error)
 }
 
-func (r *bookingRepository) UpdateStatus(id uint, status model.BookingStatus) error {
-	return r.db.Model(&model.Booking{}).
+func (r *bookingRepository) UpdateStatus(ctx context.Context, id uint, status model.BookingStatus) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Model(&model.Booking{}).
 		Where("id = ?", id).
-		Update("status", status).Error
+		Update("status", status).Error)
+}
+
+func (r *bookingRepository) UpdateProvider(ctx context.Context, id uint, providerID uint) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Model(&model.Booking{}).
+		Where("id = ?", id).
+		Update("provider_id", providerID).Error)
+}
+
+// ClaimGuestBookings assigns the guest bookings made with the phone number to the user
+func (r *bookingRepository) ClaimGuestBookings(ctx context.Context, phoneNumber string, userID uint) (int64, error) {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	result := db.Model(&model.Booking{}).
+		Where("user_id IS NULL AND phone_number = ?", phoneNumber).
+		Update("user_id", userID)
+	return result.RowsAffected, apperror.FromDB(result.Error)
 }
 
+// UpdateStatusWithHistory moves the booking from one status to another and
+// records the change, reporting false if the booking was no longer in the
+// from status
 func (r *bookingRepository) UpdateStatusWithHistory(
-	id uint, 
-	status model.BookingStatus, 
+	ctx context.Context,
+	id uint,
+	from, status model.BookingStatus,
 	statusHistory *model.BookingStatusHistory,
-) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		// Update booking status
-		if err := tx.Model(&model.Booking{}).
-			Where("id = ?", id).
-			Update("status", status).Error; err != nil {
-			return err
+) (bool, error) {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	var updated bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Update booking status unless another change came first
+		result := tx.Model(&model.Booking{}).
+			Where("id = ? AND status = ?", id, from).
+			Update("status", status)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
 		}
 
 		// Deactivate previous active status history
@@ -149,6 +227,11 @@ func (r *bookingRepository) UpdateStatusWithHistory(
 		}
 
 		// Create new status history
-		return tx.Create(statusHistory).Error
+		if err := tx.Create(statusHistory).Error; err != nil {
+			return err
+		}
+		updated = true
+		return nil
 	})
-}
\ No newline at end of file
+	return updated, apperror.FromDB(err)
+}
@@ -1,34 +1,40 @@
 package repository
 
 import (
+	"context"
+
+	"service-booking/internal/apperror"
 	"service-booking/internal/model"
 
 	"gorm.io/gorm"
 )
 
 type CategoryRepository interface {
-	FindAll(page, limit int, filters map[string]interface{}) ([]model.Category, int64, error)
-	FindByID(id uint) (*model.Category, error)
-	Create(category *model.Category) error
-	Update(category *model.Category) error
-	Delete(id uint) error
-	FindByParentCategory(parentCategoryID uint) ([]model.Category, error)
+	FindAll(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.Category, int64, error)
+	FindByID(ctx context.Context, id uint) (*model.Category, error)
+	Create(ctx context.Context, category *model.Category) error
+	Update(ctx context.Context, category *model.Category) error
+	Delete(ctx context.Context, id uint) error
+	FindByParentCategory(ctx context.Context, parentCategoryID uint) ([]model.Category, error)
 }
 
 type categoryRepository struct {
-	db *gorm.DB
+	conn
 }
 
-func NewCategoryRepository(db *gorm.DB) CategoryRepository {
-	return &categoryRepository{db}
+func NewCategoryRepository(db *gorm.DB, timeouts Timeouts) CategoryRepository {
+	return &categoryRepository{conn{db, timeouts}}
 }
 
-func (r *categoryRepository) FindAll(page, limit int, filters map[string]interface{}) ([]model.Category, int64, error) {
+func (r *categoryRepository) FindAll(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.Category, int64, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var categories []model.Category
 	var count int64
 
 	offset := (page - 1) * limit
-	query := r.db.Model(&model.Category{})
+	query := db.Model(&model.Category{})
 
 	// Apply filters
 	if filters != nil {
@@ -47,7 +53,7 @@ func (r *categoryRepository) FindAll(page, limit int, filters map[string]interfa
 	// Count total records
 	err := query.Count(&count).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, apperror.FromDB(err)
 	}
 
 	// Fetch paginated results with preloading
@@ -58,34 +64,49 @@ func (r *categoryRepository) FindAll(page, limit int, filters map[string]interfa
 		Order("display_order").
 		Find(&categories).Error
 
-	return categories, count, err
+	return categories, count, apperror.FromDB(err)
 }
 
-func (r *categoryRepository) FindByID(id uint) (*model.Category, error) {
+func (r *categoryRepository) FindByID(ctx context.Context, id uint) (*model.Category, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var category model.Category
-	err := r.db.
+	err := db.
 		Preload("ParentCategory").
 		Preload("Services").
 		First(&category, id).Error
-	return &category, err
+	return &category, apperror.FromDB(err)
 }
 
-func (r *categoryRepository) Create(category *model.Category) error {
-	return r.db.Create(category).Error
+func (r *categoryRepository) Create(ctx context.Context, category *model.Category) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Create(category).Error)
 }
 
-func (r *categoryRepository) Update(category *model.Category) error {
-	return r.db.Save(category).Error
+func (r *categoryRepository) Update(ctx context.Context, category *model.Category) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Save(category).Error)
 }
 
-func (r *categoryRepository) Delete(id uint) error {
-	return r.db.Delete(&model.Category{}, id).Error
+func (r *categoryRepository) Delete(ctx context.Context, id uint) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Delete(&model.Category{}, id).Error)
 }
 
-func (r *categoryRepository) FindByParentCategory(parentCategoryID uint) ([]model.Category, error) {
+func (r *categoryRepository) FindByParentCategory(ctx context.Context, parentCategoryID uint) ([]model.Category, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var categories []model.Category
-	err := r.db.
+	err := db.
 		Where("parent_category_id = ?", parentCategoryID).
 		Find(&categories).Error
-	return categories, err
+	return categories, apperror.FromDB(err)
 }
\ No newline at end of file
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Timeouts are the default time limits of repository operations. They apply
+// on top of the caller's context, so a request that is cancelled or has a
+// shorter deadline stops its queries earlier. Zero means no limit.
+type Timeouts struct {
+	// Read bounds lookups
+	Read time.Duration
+	// Write bounds inserts, updates, deletes and transactions
+	Write time.Duration
+}
+
+// conn is the database connection of a repository
+type conn struct {
+	db       *gorm.DB
+	timeouts Timeouts
+}
+
+// read returns the connection bound to ctx and the read timeout. cancel
+// must be called once the query is done.
+func (c conn) read(ctx context.Context) (db *gorm.DB, cancel context.CancelFunc) {
+	return c.withTimeout(ctx, c.timeouts.Read)
+}
+
+// write returns the connection bound to ctx and the write timeout. cancel
+// must be called once the statement or transaction is done.
+func (c conn) write(ctx context.Context) (db *gorm.DB, cancel context.CancelFunc) {
+	return c.withTimeout(ctx, c.timeouts.Write)
+}
+
+func (c conn) withTimeout(ctx context.Context, timeout time.Duration) (*gorm.DB, context.CancelFunc) {
+	if timeout <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return c.db.WithContext(ctx), cancel
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return c.db.WithContext(ctx), cancel
+}
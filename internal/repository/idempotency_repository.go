@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, record *model.IdempotencyKey) (*model.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, record *model.IdempotencyKey) error
+	Delete(ctx context.Context, id uint) error
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+type idempotencyRepository struct {
+	conn
+}
+
+func NewIdempotencyRepository(db *gorm.DB, timeouts Timeouts) IdempotencyRepository {
+	return &idempotencyRepository{conn{db, timeouts}}
+}
+
+// Reserve stores the record unless its key is already in use. It returns the
+// reserved record and true, or the existing record and false.
+func (r *idempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyKey) (*model.IdempotencyKey, bool, error) {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	var existing model.IdempotencyKey
+	reserved := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			reserved = true
+			return nil
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("scope = ? AND idempotency_key = ?", record.Scope, record.Key).
+			First(&existing).Error
+		if err != nil {
+			return err
+		}
+
+		// Expired keys are reused as if they were new, and a retry takes over
+		// the key of a request whose lease ran out before it completed
+		now := time.Now()
+		if now.After(existing.ExpiresAt) || (existing.Fingerprint == record.Fingerprint && leaseExpired(&existing, now)) {
+			record.ID = existing.ID
+			record.CreatedAt = time.Now()
+			reserved = true
+			return tx.Save(record).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, apperror.FromDB(err)
+	}
+
+	if reserved {
+		return record, true, nil
+	}
+	return &existing, false, nil
+}
+
+// leaseExpired reports whether an uncompleted record is no longer held by
+// the request that reserved it
+func leaseExpired(record *model.IdempotencyKey, now time.Time) bool {
+	return record.CompletedAt == nil && (record.LockedUntil == nil || now.After(*record.LockedUntil))
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, record *model.IdempotencyKey) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Save(record).Error)
+}
+
+func (r *idempotencyRepository) Delete(ctx context.Context, id uint) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Delete(&model.IdempotencyKey{}, id).Error)
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Where("expires_at < ?", before).Delete(&model.IdempotencyKey{}).Error)
+}
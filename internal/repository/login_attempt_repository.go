@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptStore persists failed login counters. The in-memory store suits
+// a single node; the database store shares counters between replicas.
+type LoginAttemptStore interface {
+	Get(ctx context.Context, key string) (*model.LoginAttempt, error)
+	RecordFailure(ctx context.Context, key string, window time.Duration) (*model.LoginAttempt, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Delete(ctx context.Context, key string) error
+}
+
+type loginAttemptRepository struct {
+	conn
+}
+
+func NewLoginAttemptRepository(db *gorm.DB, timeouts Timeouts) LoginAttemptStore {
+	return &loginAttemptRepository{conn{db, timeouts}}
+}
+
+func (r *loginAttemptRepository) Get(ctx context.Context, key string) (*model.LoginAttempt, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var attempt model.LoginAttempt
+	err := db.Where("throttle_key = ?", key).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperror.FromDB(err)
+	}
+	return &attempt, nil
+}
+
+func (r *loginAttemptRepository) RecordFailure(ctx context.Context, key string, window time.Duration) (*model.LoginAttempt, error) {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	var attempt model.LoginAttempt
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// Lock the counter row so concurrent replicas do not lose failures
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("throttle_key = ?", key).
+			First(&attempt).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			attempt = model.LoginAttempt{ThrottleKey: key, Failures: 1, LastFailureAt: now}
+			return tx.Create(&attempt).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		// Counters reset after a quiet window
+		if now.Sub(attempt.LastFailureAt) > window {
+			attempt.Failures = 0
+			attempt.LockedUntil = nil
+		}
+
+		attempt.Failures++
+		attempt.LastFailureAt = now
+		return tx.Save(&attempt).Error
+	})
+	if err != nil {
+		return nil, apperror.FromDB(err)
+	}
+	return &attempt, nil
+}
+
+func (r *loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Model(&model.LoginAttempt{}).
+		Where("throttle_key = ?", key).
+		Update("locked_until", until).Error)
+}
+
+func (r *loginAttemptRepository) Delete(ctx context.Context, key string) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Where("throttle_key = ?", key).Delete(&model.LoginAttempt{}).Error)
+}
+
+type memoryLoginAttemptEntry struct {
+	attempt model.LoginAttempt
+	// idleAt is when the failures no longer count and can be forgotten
+	idleAt time.Time
+}
+
+type memoryLoginAttemptStore struct {
+	mu        sync.Mutex
+	attempts  map[string]memoryLoginAttemptEntry
+	lastSweep time.Time
+}
+
+func NewMemoryLoginAttemptStore() LoginAttemptStore {
+	return &memoryLoginAttemptStore{attempts: make(map[string]memoryLoginAttemptEntry), lastSweep: time.Now()}
+}
+
+func (s *memoryLoginAttemptStore) Get(ctx context.Context, key string) (*model.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	attempt := entry.attempt
+	return &attempt, nil
+}
+
+func (s *memoryLoginAttemptStore) RecordFailure(ctx context.Context, key string, window time.Duration) (*model.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > memorySweepInterval {
+		for attemptKey, entry := range s.attempts {
+			if now.After(entry.idleAt) {
+				delete(s.attempts, attemptKey)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	entry, ok := s.attempts[key]
+	if !ok || now.Sub(entry.attempt.LastFailureAt) > window {
+		entry.attempt = model.LoginAttempt{ThrottleKey: key, CreatedAt: now}
+	}
+
+	entry.attempt.Failures++
+	entry.attempt.LastFailureAt = now
+	entry.attempt.UpdatedAt = now
+	entry.idleAt = now.Add(window)
+	if entry.attempt.LockedUntil != nil && entry.attempt.LockedUntil.After(entry.idleAt) {
+		entry.idleAt = *entry.attempt.LockedUntil
+	}
+	s.attempts[key] = entry
+
+	attempt := entry.attempt
+	return &attempt, nil
+}
+
+func (s *memoryLoginAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.attempts[key]; ok {
+		entry.attempt.LockedUntil = &until
+		if until.After(entry.idleAt) {
+			entry.idleAt = until
+		}
+		s.attempts[key] = entry
+	}
+	return nil
+}
+
+func (s *memoryLoginAttemptStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type OTPRepository interface {
+	Create(ctx context.Context, otp *model.OTPCode) error
+	FindLatestByPhone(ctx context.Context, phone string) (*model.OTPCode, error)
+	TakeAttempt(ctx context.Context, id uint, maxAttempts int) (bool, error)
+	Consume(ctx context.Context, id uint) (bool, error)
+	InvalidateByPhone(ctx context.Context, phone string) error
+}
+
+type otpRepository struct {
+	conn
+}
+
+func NewOTPRepository(db *gorm.DB, timeouts Timeouts) OTPRepository {
+	return &otpRepository{conn{db, timeouts}}
+}
+
+func (r *otpRepository) Create(ctx context.Context, otp *model.OTPCode) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Create(otp).Error)
+}
+
+func (r *otpRepository) FindLatestByPhone(ctx context.Context, phone string) (*model.OTPCode, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var otp model.OTPCode
+	err := db.
+		Where("phone = ?", phone).
+		Order("created_at DESC").
+		Order("id DESC").
+		First(&otp).Error
+	if err != nil {
+		return nil, apperror.FromDB(err)
+	}
+	return &otp, nil
+}
+
+// TakeAttempt counts a guess at the code, reporting false once maxAttempts
+// guesses were made. Concurrent guesses cannot exceed the limit.
+func (r *otpRepository) TakeAttempt(ctx context.Context, id uint, maxAttempts int) (bool, error) {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	result := db.Model(&model.OTPCode{}).
+		Where("id = ? AND attempts < ?", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	return result.RowsAffected == 1, apperror.FromDB(result.Error)
+}
+
+// Consume marks a code as used, reporting false if it was already used
+func (r *otpRepository) Consume(ctx context.Context, id uint) (bool, error) {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	result := db.Model(&model.OTPCode{}).
+		Where("id = ? AND consumed_at IS NULL", id).
+		Update("consumed_at", time.Now())
+	return result.RowsAffected == 1, apperror.FromDB(result.Error)
+}
+
+func (r *otpRepository) InvalidateByPhone(ctx context.Context, phone string) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Model(&model.OTPCode{}).
+		Where("phone = ? AND consumed_at IS NULL", phone).
+		Update("consumed_at", gorm.Expr("CURRENT_TIMESTAMP")).Error)
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateLimitStore keeps token buckets for rate limiting. The in-memory store
+// suits a single node; the database store shares buckets between replicas.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit int, period time.Duration) (*model.RateLimitBucket, bool, error)
+	DeleteIdle(ctx context.Context, keyPrefix string, before time.Time) error
+}
+
+type rateLimitRepository struct {
+	conn
+}
+
+func NewRateLimitRepository(db *gorm.DB, timeouts Timeouts) RateLimitStore {
+	return &rateLimitRepository{conn{db, timeouts}}
+}
+
+func (r *rateLimitRepository) Take(ctx context.Context, key string, limit int, period time.Duration) (*model.RateLimitBucket, bool, error) {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	var bucket model.RateLimitBucket
+	var allowed bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// Lock the bucket row so concurrent replicas do not spend the same token
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("bucket_key = ?", key).
+			First(&bucket).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Another replica may create the bucket first
+			bucket = model.NewRateLimitBucket(key, limit, now)
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bucket).Error; err != nil {
+				return err
+			}
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("bucket_key = ?", key).
+				First(&bucket).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		allowed = bucket.Take(limit, period, now)
+		return tx.Save(&bucket).Error
+	})
+	if err != nil {
+		return nil, false, apperror.FromDB(err)
+	}
+	return &bucket, allowed, nil
+}
+
+// DeleteIdle deletes the buckets with keys starting with keyPrefix that were
+// last used before the given time
+func (r *rateLimitRepository) DeleteIdle(ctx context.Context, keyPrefix string, before time.Time) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Where("bucket_key LIKE ? AND refilled_at < ?", keyPrefix+"%", before).Delete(&model.RateLimitBucket{}).Error)
+}
+
+// memorySweepInterval is how often idle buckets are dropped from memory
+const memorySweepInterval = time.Minute
+
+type memoryRateLimitEntry struct {
+	bucket model.RateLimitBucket
+	// idleAt is when the bucket is full again and can be forgotten
+	idleAt time.Time
+}
+
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryRateLimitEntry
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{buckets: make(map[string]memoryRateLimitEntry), lastSweep: time.Now()}
+}
+
+func (s *memoryRateLimitStore) Take(ctx context.Context, key string, limit int, period time.Duration) (*model.RateLimitBucket, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > memorySweepInterval {
+		for bucketKey, entry := range s.buckets {
+			if now.After(entry.idleAt) {
+				delete(s.buckets, bucketKey)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	entry, ok := s.buckets[key]
+	if !ok {
+		entry.bucket = model.NewRateLimitBucket(key, limit, now)
+	}
+
+	allowed := entry.bucket.Take(limit, period, now)
+	entry.idleAt = now.Add(period)
+	s.buckets[key] = entry
+
+	bucket := entry.bucket
+	return &bucket, allowed, nil
+}
+
+func (s *memoryRateLimitStore) DeleteIdle(ctx context.Context, keyPrefix string, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, entry := range s.buckets {
+		if strings.HasPrefix(key, keyPrefix) && entry.bucket.RefilledAt.Before(before) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
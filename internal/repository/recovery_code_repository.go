@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository interface {
+	FindUnusedByUser(ctx context.Context, userID uint) ([]model.RecoveryCode, error)
+	ReplaceForUser(ctx context.Context, userID uint, codes []model.RecoveryCode) error
+	DeleteByUser(ctx context.Context, userID uint) error
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+}
+
+type recoveryCodeRepository struct {
+	conn
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB, timeouts Timeouts) RecoveryCodeRepository {
+	return &recoveryCodeRepository{conn{db, timeouts}}
+}
+
+func (r *recoveryCodeRepository) FindUnusedByUser(ctx context.Context, userID uint) ([]model.RecoveryCode, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var codes []model.RecoveryCode
+	err := db.
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Find(&codes).Error
+	return codes, apperror.FromDB(err)
+}
+
+func (r *recoveryCodeRepository) ReplaceForUser(ctx context.Context, userID uint, codes []model.RecoveryCode) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Transaction(func(tx *gorm.DB) error {
+		// Remove previous codes
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	}))
+}
+
+func (r *recoveryCodeRepository) DeleteByUser(ctx context.Context, userID uint) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error)
+}
+
+// MarkUsed consumes a code, reporting false if it was already used
+func (r *recoveryCodeRepository) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	result := db.Model(&model.RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, apperror.FromDB(result.Error)
+}
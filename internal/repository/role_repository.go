@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type RoleRepository interface {
+	FindAll(ctx context.Context) ([]model.Role, error)
+	FindByID(ctx context.Context, id uint) (*model.Role, error)
+	FindByName(ctx context.Context, name string) (*model.Role, error)
+	Create(ctx context.Context, role *model.Role) error
+	Update(ctx context.Context, role *model.Role) error
+	Delete(ctx context.Context, id uint) error
+	CountUsers(ctx context.Context, name string) (int64, error)
+}
+
+type roleRepository struct {
+	conn
+}
+
+func NewRoleRepository(db *gorm.DB, timeouts Timeouts) RoleRepository {
+	return &roleRepository{conn{db, timeouts}}
+}
+
+func (r *roleRepository) FindAll(ctx context.Context) ([]model.Role, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var roles []model.Role
+	err := db.Order("name").Find(&roles).Error
+	return roles, apperror.FromDB(err)
+}
+
+func (r *roleRepository) FindByID(ctx context.Context, id uint) (*model.Role, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var role model.Role
+	err := db.First(&role, id).Error
+	return &role, apperror.FromDB(err)
+}
+
+func (r *roleRepository) FindByName(ctx context.Context, name string) (*model.Role, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var role model.Role
+	err := db.Where("name = ?", name).First(&role).Error
+	if err != nil {
+		return nil, apperror.FromDB(err)
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *model.Role) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Create(role).Error)
+}
+
+func (r *roleRepository) Update(ctx context.Context, role *model.Role) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Save(role).Error)
+}
+
+func (r *roleRepository) Delete(ctx context.Context, id uint) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Delete(&model.Role{}, id).Error)
+}
+
+func (r *roleRepository) CountUsers(ctx context.Context, name string) (int64, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var count int64
+	err := db.Model(&model.User{}).Where("role = ?", name).Count(&count).Error
+	return count, apperror.FromDB(err)
+}
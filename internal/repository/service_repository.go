@@ -1,35 +1,41 @@
 package repository
 
 import (
+	"context"
+
+	"service-booking/internal/apperror"
 	"service-booking/internal/model"
 
 	"gorm.io/gorm"
 )
 
 type ServiceRepository interface {
-	FindAll(page, limit int, filters map[string]interface{}) ([]model.Service, int64, error)
-	FindByID(id uint) (*model.Service, error)
-	Create(service *model.Service) error
-	Update(service *model.Service) error
-	Delete(id uint) error
-	FindByCategory(categoryID uint, page, limit int) ([]model.Service, int64, error)
-	FindFeaturedServices(limit int) ([]model.Service, error)
+	FindAll(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.Service, int64, error)
+	FindByID(ctx context.Context, id uint) (*model.Service, error)
+	Create(ctx context.Context, service *model.Service) error
+	Update(ctx context.Context, service *model.Service) error
+	Delete(ctx context.Context, id uint) error
+	FindByCategory(ctx context.Context, categoryID uint, page, limit int) ([]model.Service, int64, error)
+	FindFeaturedServices(ctx context.Context, limit int) ([]model.Service, error)
 }
 
 type serviceRepository struct {
-	db *gorm.DB
+	conn
 }
 
-func NewServiceRepository(db *gorm.DB) ServiceRepository {
-	return &serviceRepository{db}
+func NewServiceRepository(db *gorm.DB, timeouts Timeouts) ServiceRepository {
+	return &serviceRepository{conn{db, timeouts}}
 }
 
-func (r *serviceRepository) FindAll(page, limit int, filters map[string]interface{}) ([]model.Service, int64, error) {
+func (r *serviceRepository) FindAll(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.Service, int64, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var services []model.Service
 	var count int64
 
 	offset := (page - 1) * limit
-	query := r.db.Model(&model.Service{})
+	query := db.Model(&model.Service{})
 
 	// Apply filters
 	if filters != nil {
@@ -52,7 +58,7 @@ func (r *serviceRepository) FindAll(page, limit int, filters map[string]interfac
 	// Count total records
 	err := query.Count(&count).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, apperror.FromDB(err)
 	}
 
 	// Fetch paginated results with preloading
@@ -62,58 +68,76 @@ func (r *serviceRepository) FindAll(page, limit int, filters map[string]interfac
 		Limit(limit).
 		Find(&services).Error
 
-	return services, count, err
+	return services, count, apperror.FromDB(err)
 }
 
-func (r *serviceRepository) FindByID(id uint) (*model.Service, error) {
+func (r *serviceRepository) FindByID(ctx context.Context, id uint) (*model.Service, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var service model.Service
-	err := r.db.
+	err := db.
 		Preload("Category").
 		First(&service, id).Error
-	return &service, err
+	return &service, apperror.FromDB(err)
 }
 
-func (r *serviceRepository) Create(service *model.Service) error {
-	return r.db.Create(service).Error
+func (r *serviceRepository) Create(ctx context.Context, service *model.Service) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Create(service).Error)
 }
 
-func (r *serviceRepository) Update(service *model.Service) error {
-	return r.db.Save(service).Error
+func (r *serviceRepository) Update(ctx context.Context, service *model.Service) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Save(service).Error)
 }
 
-func (r *serviceRepository) Delete(id uint) error {
-	return r.db.Delete(&model.Service{}, id).Error
+func (r *serviceRepository) Delete(ctx context.Context, id uint) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Delete(&model.Service{}, id).Error)
 }
 
-func (r *serviceRepository) FindByCategory(categoryID uint, page, limit int) ([]model.Service, int64, error) {
+func (r *serviceRepository) FindByCategory(ctx context.Context, categoryID uint, page, limit int) ([]model.Service, int64, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var services []model.Service
 	var count int64
 
 	offset := (page - 1) * limit
 
-	err := r.db.Model(&model.Service{}).
+	err := db.Model(&model.Service{}).
 		Where("category_id = ?", categoryID).
 		Count(&count).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, apperror.FromDB(err)
 	}
 
-	err = r.db.
+	err = db.
 		Preload("Category").
 		Where("category_id = ?", categoryID).
 		Offset(offset).
 		Limit(limit).
 		Find(&services).Error
 
-	return services, count, err
+	return services, count, apperror.FromDB(err)
 }
 
-func (r *serviceRepository) FindFeaturedServices(limit int) ([]model.Service, error) {
+func (r *serviceRepository) FindFeaturedServices(ctx context.Context, limit int) ([]model.Service, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var services []model.Service
-	err := r.db.
+	err := db.
 		Preload("Category").
 		Where("is_featured = ?", true).
 		Limit(limit).
 		Find(&services).Error
-	return services, err
+	return services, apperror.FromDB(err)
 }
\ No newline at end of file
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	FindByID(ctx context.Context, id uint) (*model.Session, error)
+	FindActiveByUser(ctx context.Context, userID uint) ([]model.Session, error)
+	Create(ctx context.Context, session *model.Session) error
+	Update(ctx context.Context, session *model.Session) error
+	RotateRefreshToken(ctx context.Context, session *model.Session, previousTokenID string) (bool, error)
+}
+
+type sessionRepository struct {
+	conn
+}
+
+func NewSessionRepository(db *gorm.DB, timeouts Timeouts) SessionRepository {
+	return &sessionRepository{conn{db, timeouts}}
+}
+
+func (r *sessionRepository) FindByID(ctx context.Context, id uint) (*model.Session, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var session model.Session
+	err := db.First(&session, id).Error
+	return &session, apperror.FromDB(err)
+}
+
+func (r *sessionRepository) FindActiveByUser(ctx context.Context, userID uint) ([]model.Session, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var sessions []model.Session
+	err := db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&sessions).Error
+	return sessions, apperror.FromDB(err)
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *model.Session) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Create(session).Error)
+}
+
+func (r *sessionRepository) Update(ctx context.Context, session *model.Session) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Save(session).Error)
+}
+
+// RotateRefreshToken saves the refresh of a session, reporting false if the
+// refresh token was already used or the session was revoked meanwhile
+func (r *sessionRepository) RotateRefreshToken(ctx context.Context, session *model.Session, previousTokenID string) (bool, error) {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	result := db.Model(&model.Session{}).
+		Where("id = ? AND refresh_token_id = ? AND revoked_at IS NULL", session.ID, previousTokenID).
+		Updates(map[string]interface{}{
+			"refresh_token_id":  session.RefreshTokenID,
+			"user_agent":        session.UserAgent,
+			"ip_address":        session.IPAddress,
+			"last_refreshed_at": session.LastRefreshedAt,
+			"expires_at":        session.ExpiresAt,
+		})
+	return result.RowsAffected == 1, apperror.FromDB(result.Error)
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type UserIdentityRepository interface {
+	FindByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error)
+	FindByUserID(ctx context.Context, userID uint) ([]model.UserIdentity, error)
+	Create(ctx context.Context, identity *model.UserIdentity) error
+}
+
+type userIdentityRepository struct {
+	conn
+}
+
+func NewUserIdentityRepository(db *gorm.DB, timeouts Timeouts) UserIdentityRepository {
+	return &userIdentityRepository{conn{db, timeouts}}
+}
+
+func (r *userIdentityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var identity model.UserIdentity
+	err := db.
+		Preload("User").
+		Where("provider = ? AND subject = ?", provider, subject).
+		First(&identity).Error
+	if err != nil {
+		return nil, apperror.FromDB(err)
+	}
+	return &identity, nil
+}
+
+func (r *userIdentityRepository) FindByUserID(ctx context.Context, userID uint) ([]model.UserIdentity, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var identities []model.UserIdentity
+	err := db.Where("user_id = ?", userID).Find(&identities).Error
+	return identities, apperror.FromDB(err)
+}
+
+func (r *userIdentityRepository) Create(ctx context.Context, identity *model.UserIdentity) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Create(identity).Error)
+}
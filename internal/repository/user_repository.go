@@ -1,67 +1,116 @@
 package repository
 
 import (
+	"context"
+	"time"
+
+	"service-booking/internal/apperror"
 	"service-booking/internal/model"
 
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	FindAll(page, limit int) ([]model.User, int64, error)
-	FindByID(id uint) (*model.User, error)
-	FindByEmail(email string) (*model.User, error)
-	Create(user *model.User) error
-	Update(user *model.User) error
-	Delete(id uint) error
+	FindAll(ctx context.Context, page, limit int) ([]model.User, int64, error)
+	FindByID(ctx context.Context, id uint) (*model.User, error)
+	FindByEmail(ctx context.Context, email string) (*model.User, error)
+	FindByVerifiedPhone(ctx context.Context, phone string) (*model.User, error)
+	MarkPhoneVerified(ctx context.Context, id uint, phone string) (bool, error)
+	Create(ctx context.Context, user *model.User) error
+	Update(ctx context.Context, user *model.User) error
+	Delete(ctx context.Context, id uint) error
 }
 
 type userRepository struct {
-	db *gorm.DB
+	conn
 }
 
-func NewUserRepository(db *gorm.DB) UserRepository {
-	return &userRepository{db}
+func NewUserRepository(db *gorm.DB, timeouts Timeouts) UserRepository {
+	return &userRepository{conn{db, timeouts}}
 }
 
-func (r *userRepository) FindAll(page, limit int) ([]model.User, int64, error) {
+func (r *userRepository) FindAll(ctx context.Context, page, limit int) ([]model.User, int64, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var users []model.User
 	var count int64
 
 	offset := (page - 1) * limit
 
-	err := r.db.Model(&model.User{}).Count(&count).Error
+	err := db.Model(&model.User{}).Count(&count).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, apperror.FromDB(err)
 	}
 
-	err = r.db.Offset(offset).Limit(limit).Find(&users).Error
-	return users, count, err
+	err = db.Offset(offset).Limit(limit).Find(&users).Error
+	return users, count, apperror.FromDB(err)
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id uint) (*model.User, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
+	var user model.User
+	err := db.First(&user, id).Error
+	return &user, apperror.FromDB(err)
 }
 
-func (r *userRepository) FindByID(id uint) (*model.User, error) {
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var user model.User
-	err := r.db.First(&user, id).Error
-	return &user, err
+	err := db.Where("email = ?", email).First(&user).Error
+	if err != nil {
+		return nil, apperror.FromDB(err)
+	}
+	return &user, nil
 }
 
-func (r *userRepository) FindByEmail(email string) (*model.User, error) {
+// FindByVerifiedPhone finds the user who proved owning the phone number.
+// Unverified numbers are only what users typed in and identify no one.
+func (r *userRepository) FindByVerifiedPhone(ctx context.Context, phone string) (*model.User, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+
 	var user model.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := db.Where("phone = ? AND phone_verified_at IS NOT NULL", phone).First(&user).Error
 	if err != nil {
-		return nil, err 
+		return nil, apperror.FromDB(err)
 	}
 	return &user, nil
 }
 
+// MarkPhoneVerified records that the user proved owning the phone number,
+// reporting false if the number is no longer the user's
+func (r *userRepository) MarkPhoneVerified(ctx context.Context, id uint, phone string) (bool, error) {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	result := db.Model(&model.User{}).
+		Where("id = ? AND phone = ?", id, phone).
+		Update("phone_verified_at", time.Now())
+	return result.RowsAffected == 1, apperror.FromDB(result.Error)
+}
+
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
 
-func (r *userRepository) Create(user *model.User) error {
-	return r.db.Create(user).Error
+	return apperror.FromDB(db.Create(user).Error)
 }
 
-func (r *userRepository) Update(user *model.User) error {
-	return r.db.Save(user).Error
+func (r *userRepository) Update(ctx context.Context, user *model.User) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Save(user).Error)
 }
 
-func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&model.User{}, id).Error
+func (r *userRepository) Delete(ctx context.Context, id uint) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+
+	return apperror.FromDB(db.Delete(&model.User{}, id).Error)
 }
\ No newline at end of file
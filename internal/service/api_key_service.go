@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+	"service-booking/internal/repository"
+)
+
+const (
+	apiKeyPrefix = "sbk"
+	// lastUsedResolution limits how often last-used timestamps are written
+	lastUsedResolution = time.Minute
+)
+
+var ErrInvalidAPIKey = apperror.Unauthorized("invalid or expired API key")
+
+// APIKeyService interface defines the methods for partner API key management
+type APIKeyService interface {
+	GetAPIKeys(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.APIKey, int64, error)
+	IssueAPIKey(ctx context.Context, apiKey *model.APIKey) (string, error)
+	RevokeAPIKey(ctx context.Context, id uint) error
+	Authenticate(ctx context.Context, rawKey string) (*model.APIKey, error)
+}
+
+// apiKeyService implements APIKeyService
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	userRepo   repository.UserRepository
+}
+
+// NewAPIKeyService creates a new instance of APIKeyService
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, userRepo repository.UserRepository) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+	}
+}
+
+func (s *apiKeyService) GetAPIKeys(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.APIKey, int64, error) {
+	return s.apiKeyRepo.FindAll(ctx, page, limit, filters)
+}
+
+// IssueAPIKey stores a new key and returns its plain-text value, which is
+// only available at creation time
+func (s *apiKeyService) IssueAPIKey(ctx context.Context, apiKey *model.APIKey) (string, error) {
+	if strings.TrimSpace(apiKey.Name) == "" {
+		return "", apperror.Validation("name is required")
+	}
+	if strings.TrimSpace(apiKey.Organization) == "" {
+		return "", apperror.Validation("organization is required")
+	}
+	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(time.Now()) {
+		return "", apperror.Validation("expiry must be in the future")
+	}
+	if err := validatePermissions(apiKey.Scopes); err != nil {
+		return "", err
+	}
+
+	// Validate the account the key acts as. Keys cannot present a second
+	// factor, so they never act as accounts that must use one.
+	user, err := s.userRepo.FindByID(ctx, apiKey.UserID)
+	if err != nil {
+		return "", apperror.IfNotFound(err, apperror.Validation("user not found"))
+	}
+	if user.Role.RequiresMFA() {
+		return "", apperror.Validation("API keys cannot act as accounts that require two-factor authentication")
+	}
+
+	prefix, err := randomHex(6)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	apiKey.Prefix = prefix
+	apiKey.KeyHash = hashAPIKeySecret(secret)
+	apiKey.LastUsedAt = nil
+	apiKey.RevokedAt = nil
+
+	if err := s.apiKeyRepo.Create(ctx, apiKey); err != nil {
+		return "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return fmt.Sprintf("%s_%s_%s", apiKeyPrefix, prefix, secret), nil
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, id uint) error {
+	apiKey, err := s.apiKeyRepo.FindByID(ctx, id)
+	if err != nil {
+		return apperror.IfNotFound(err, apperror.NotFound("API key not found"))
+	}
+
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	apiKey.RevokedAt = &now
+	return s.apiKeyRepo.Update(ctx, apiKey)
+}
+
+// Authenticate resolves a plain-text key to its active record, including the
+// user the key acts as
+func (s *apiKeyService) Authenticate(ctx context.Context, rawKey string) (*model.APIKey, error) {
+	parts := strings.Split(strings.TrimSpace(rawKey), "_")
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.apiKeyRepo.FindByPrefix(ctx, parts[1])
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	expectedHash := []byte(apiKey.KeyHash)
+	actualHash := []byte(hashAPIKeySecret(parts[2]))
+	if subtle.ConstantTimeCompare(expectedHash, actualHash) != 1 || !apiKey.IsActive() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Track usage without writing on every request
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedResolution {
+		if err := s.apiKeyRepo.UpdateLastUsed(ctx, apiKey.ID, now); err == nil {
+			apiKey.LastUsedAt = &now
+		}
+	}
+
+	return apiKey, nil
+}
+
+// hashAPIKeySecret hashes the random part of a key. Keys carry enough entropy
+// that a fast hash is sufficient and keeps per-request verification cheap.
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
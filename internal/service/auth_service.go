@@ -1,53 +1,84 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 
+	"service-booking/internal/apperror"
 	"service-booking/internal/model"
 	"service-booking/internal/repository"
 	"service-booking/pkg/auth"
+	"service-booking/pkg/phone"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrUserNotFound       = apperror.NotFound("user not found")
+	ErrInvalidCredentials = apperror.Unauthorized("invalid credentials")
+)
+
 // AuthService interface defines the methods for authentication and user management
 type AuthService interface {
-	Register(user *model.User) (*model.User, error)
-	Authenticate(email, password string) (*model.User, error)
-	GetUserByID(id uint) (*model.User, error)
-	UpdateProfile(userID uint, updateData interface{}) (*model.User, error)
-	ChangePassword(userID uint, currentPassword, newPassword string) error
+	Register(ctx context.Context, user *model.User) (*model.User, error)
+	Authenticate(ctx context.Context, email, password string) (*model.User, error)
+	GetUserByID(ctx context.Context, id uint) (*model.User, error)
+	UpdateProfile(ctx context.Context, userID uint, updateData interface{}) (*model.User, error)
+	ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string) error
+	ClaimGuestBookings(ctx context.Context, userID uint, verifiedPhone string) (int64, error)
 }
 
 // authService implements AuthService
 type authService struct {
-	userRepo repository.UserRepository
+	userRepo           repository.UserRepository
+	bookingRepo        repository.BookingRepository
+	defaultCountryCode string
 }
 
-// NewAuthService creates a new instance of AuthService
-func NewAuthService(userRepo repository.UserRepository) AuthService {
-	return &authService{userRepo}
+// NewAuthService creates a new instance of AuthService. Phone numbers without
+// an international prefix are read as numbers of defaultCountryCode.
+func NewAuthService(userRepo repository.UserRepository, bookingRepo repository.BookingRepository, defaultCountryCode string) AuthService {
+	return &authService{userRepo, bookingRepo, defaultCountryCode}
 }
 
 // Register handles user registration
-func (s *authService) Register(user *model.User) (*model.User, error) {
+func (s *authService) Register(ctx context.Context, user *model.User) (*model.User, error) {
 	if user.Email == "" {
-		return nil, errors.New("email is required")
+		return nil, apperror.Validation("email is required")
 	}
 
 	// Normalize email
 	user.Email = strings.TrimSpace(strings.ToLower(user.Email))
 
 	// Check if email already exists
-	_, err := s.userRepo.FindByEmail(user.Email)
+	_, err := s.userRepo.FindByEmail(ctx, user.Email)
 		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-    		return nil, fmt.Errorf("failed to check existing user: %v", err)
+    		return nil, fmt.Errorf("failed to check existing user: %w", err)
 		}
 		if err == nil {
-    		return nil, errors.New("email already in use")
+    		return nil, apperror.Conflict("email already in use")
+	}
+
+	// Normalize phone so it can be used for OTP login. The number stays
+	// unverified until proven with a code, so it only conflicts with a
+	// number someone else proved.
+	if user.Phone != "" {
+		normalizedPhone, err := phone.NormalizeE164(user.Phone, s.defaultCountryCode)
+		if err != nil {
+			return nil, apperror.Wrap(apperror.KindValidation, "invalid phone number", err)
+		}
+		user.Phone = normalizedPhone
+		user.PhoneVerifiedAt = nil
+
+		_, err = s.userRepo.FindByVerifiedPhone(ctx, user.Phone)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to check existing user: %w", err)
+		}
+		if err == nil {
+			return nil, apperror.Conflict("phone already in use")
+		}
 	}
 
 	// Set default role if not specified
@@ -56,64 +87,53 @@ func (s *authService) Register(user *model.User) (*model.User, error) {
 	}
 
 	// Create the user
-	err = s.userRepo.Create(user)
+	err = s.userRepo.Create(ctx, user)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %v", err)
+		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
 	return user, nil
 }
 
 
-func (s *authService) Authenticate(email, password string) (*model.User, error) {
+func (s *authService) Authenticate(ctx context.Context, email, password string) (*model.User, error) {
     // Normalize email
     email = strings.TrimSpace(strings.ToLower(email))
 
     // Find user by email
-    user, err := s.userRepo.FindByEmail(email)
+    user, err := s.userRepo.FindByEmail(ctx, email)
     if err != nil {
-        fmt.Printf("User not found for email: %s\n", email)
-        return nil, errors.New("invalid credentials")
+        return nil, apperror.IfNotFound(err, ErrInvalidCredentials)
     }
-    // Verify password
+    // Accounts without a password (phone or social login) cannot log in here
     if user.Password == "" {
-        fmt.Println("Stored password is empty")
-        return nil, errors.New("invalid credentials")
+        return nil, ErrInvalidCredentials
     }
 
-	newHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		fmt.Printf("Failed to generate new hash: %v\n", err)
-		
-	}
-	
-
-	user.Password = string(newHash)
-
     // Verify password using bcrypt's comparison
     passwordMatch := auth.ComparePasswords(user.Password, password)
     if !passwordMatch {
-        return nil, errors.New("invalid credentials")
+        return nil, ErrInvalidCredentials
     }
 
     return user, nil
 }
 
 // GetUserByID retrieves a user by their ID
-func (s *authService) GetUserByID(id uint) (*model.User, error) {
-	user, err := s.userRepo.FindByID(id)
+func (s *authService) GetUserByID(ctx context.Context, id uint) (*model.User, error) {
+	user, err := s.userRepo.FindByID(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("user not found: %v", err)
+		return nil, apperror.IfNotFound(err, ErrUserNotFound)
 	}
 	return user, nil
 }
 
 // UpdateProfile updates user profile information
-func (s *authService) UpdateProfile(userID uint, updateData interface{}) (*model.User, error) {
+func (s *authService) UpdateProfile(ctx context.Context, userID uint, updateData interface{}) (*model.User, error) {
 	// Find the existing user
-	user, err := s.userRepo.FindByID(userID)
+	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, apperror.IfNotFound(err, ErrUserNotFound)
 	}
 
 	// Type assert and update fields based on the input
@@ -128,9 +148,9 @@ func (s *authService) UpdateProfile(userID uint, updateData interface{}) (*model
 			normalizedEmail := strings.TrimSpace(strings.ToLower(email))
 			
 			// Check if email is already in use by another user
-			existingUser, _ := s.userRepo.FindByEmail(normalizedEmail)
+			existingUser, _ := s.userRepo.FindByEmail(ctx, normalizedEmail)
 			if existingUser != nil && existingUser.ID != userID {
-				return nil, errors.New("email already in use")
+				return nil, apperror.Conflict("email already in use")
 			}
 			
 			user.Email = normalizedEmail
@@ -148,56 +168,92 @@ func (s *authService) UpdateProfile(userID uint, updateData interface{}) (*model
 			normalizedEmail := strings.TrimSpace(strings.ToLower(data.Email))
 			
 			// Check if email is already in use by another user
-			existingUser, _ := s.userRepo.FindByEmail(normalizedEmail)
+			existingUser, _ := s.userRepo.FindByEmail(ctx, normalizedEmail)
 			if existingUser != nil && existingUser.ID != userID {
-				return nil, errors.New("email already in use")
+				return nil, apperror.Conflict("email already in use")
 			}
 			
 			user.Email = normalizedEmail
 		}
 	default:
-		return nil, errors.New("invalid update data type")
+		return nil, apperror.Validation("invalid update data type")
 	}
 
 	// Update the user
-	err = s.userRepo.Update(user)
+	err = s.userRepo.Update(ctx, user)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update profile: %v", err)
+		return nil, fmt.Errorf("failed to update profile: %w", err)
 	}
 
 	return user, nil
 }
 
 // ChangePassword handles password change for a user
-func (s *authService) ChangePassword(userID uint, currentPassword, newPassword string) error {
+func (s *authService) ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string) error {
 	// Find the user
-	user, err := s.userRepo.FindByID(userID)
+	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
-		return errors.New("user not found")
+		return apperror.IfNotFound(err, ErrUserNotFound)
 	}
 
 	// Verify current password
 	if !auth.ComparePasswords(user.Password, currentPassword) {
-		return errors.New("current password is incorrect")
+		return apperror.Validation("current password is incorrect")
 	}
 
 	// Validate new password
 	if len(newPassword) < 8 {
-		return errors.New("new password must be at least 8 characters long")
+		return apperror.Validation("new password must be at least 8 characters long")
 	}
 
 	// Hash the new password
 	hashedPassword, err := auth.HashPassword(newPassword)
 	if err != nil {
-		return fmt.Errorf("failed to hash new password: %v", err)
+		return fmt.Errorf("failed to hash new password: %w", err)
 	}
 
 	// Update the password
 	user.Password = hashedPassword
-	err = s.userRepo.Update(user)
+	err = s.userRepo.Update(ctx, user)
 	if err != nil {
-		return fmt.Errorf("failed to update password: %v", err)
+		return fmt.Errorf("failed to update password: %w", err)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// ClaimGuestBookings moves the guest bookings made with a verified phone
+// number into the user's account. The phone must be the user's own and is
+// marked as verified, unless another account proved it first.
+func (s *authService) ClaimGuestBookings(ctx context.Context, userID uint, verifiedPhone string) (int64, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return 0, apperror.IfNotFound(err, ErrUserNotFound)
+	}
+	if user.Phone == "" || user.Phone != verifiedPhone {
+		return 0, apperror.Forbidden("phone number does not match the account")
+	}
+
+	owner, err := s.userRepo.FindByVerifiedPhone(ctx, verifiedPhone)
+	if err != nil && !apperror.Is(err, apperror.KindNotFound) {
+		return 0, fmt.Errorf("failed to check phone owner: %w", err)
+	}
+	if owner != nil && owner.ID != userID {
+		return 0, apperror.Conflict("phone already in use")
+	}
+	if owner == nil {
+		verified, err := s.userRepo.MarkPhoneVerified(ctx, userID, verifiedPhone)
+		if err != nil {
+			return 0, fmt.Errorf("failed to verify phone: %w", err)
+		}
+		if !verified {
+			return 0, apperror.Forbidden("phone number does not match the account")
+		}
+	}
+
+	claimed, err := s.bookingRepo.ClaimGuestBookings(ctx, verifiedPhone, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim guest bookings: %w", err)
+	}
+	return claimed, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+)
+
+var (
+	// ErrBookingNotFound is returned for missing bookings and for bookings
+	// the actor is not allowed to see, so foreign IDs cannot be probed
+	ErrBookingNotFound = apperror.NotFound("booking not found")
+	// ErrBookingForbidden is returned when the actor can see a booking but
+	// not perform the requested change
+	ErrBookingForbidden = apperror.Forbidden("not allowed to perform this action on the booking")
+	// ErrBookingStatusUnchanged is returned when the booking already has the status
+	ErrBookingStatusUnchanged = apperror.Conflict("booking already has this status")
+	// ErrBookingStatusChanged is returned when another change of the status
+	// was saved since the booking was loaded
+	ErrBookingStatusChanged = apperror.Conflict("booking status was changed meanwhile, reload and try again")
+)
+
+// Actor identifies the authenticated user performing a booking operation
+type Actor struct {
+	UserID uint
+	Role   string
+	// MFA is set when the actor logged in with a second factor
+	MFA bool
+}
+
+// providerTransitions are the status changes an assigned provider may make,
+// keyed by the current status
+var providerTransitions = map[model.BookingStatus]model.BookingStatus{
+	model.BookingStatusConfirmed:  model.BookingStatusInProgress,
+	model.BookingStatusInProgress: model.BookingStatusCompleted,
+}
+
+// ownerCancellable are the statuses in which owners may cancel their
+// bookings, before the provider has started the work
+var ownerCancellable = map[model.BookingStatus]bool{
+	model.BookingStatusPending:   true,
+	model.BookingStatusConfirmed: true,
+}
+
+// isStaff reports whether the actor's role grants the permission on all
+// bookings. Roles that require MFA only get their rights after a second
+// factor, otherwise they act as the customers they are.
+func (s *bookingService) isStaff(ctx context.Context, actor Actor, permission model.Permission) (bool, error) {
+	if model.UserRole(actor.Role).RequiresMFA() && !actor.MFA {
+		return false, nil
+	}
+	return s.roleService.HasPermission(ctx, actor.Role, permission)
+}
+
+// isOwner reports whether the actor made the booking. Guest bookings have no owner
+func isOwner(actor Actor, booking *model.Booking) bool {
+	return booking.UserID != nil && *booking.UserID == actor.UserID
+}
+
+// isParticipant reports whether the actor owns the booking or is its assigned provider
+func isParticipant(actor Actor, booking *model.Booking) bool {
+	if isOwner(actor, booking) {
+		return true
+	}
+	return booking.ProviderID != nil && *booking.ProviderID == actor.UserID
+}
+
+// canRead reports whether the actor may see the booking
+func (s *bookingService) canRead(ctx context.Context, actor Actor, booking *model.Booking) (bool, error) {
+	if isParticipant(actor, booking) {
+		return true, nil
+	}
+	return s.isStaff(ctx, actor, model.PermissionBookingRead)
+}
+
+// canChangeStatus reports whether the actor may move the booking to the
+// status. Customers may only cancel their own bookings while they are
+// ownerCancellable, assigned providers may progress them along
+// providerTransitions, and staff may set any status.
+func (s *bookingService) canChangeStatus(ctx context.Context, actor Actor, booking *model.Booking, status model.BookingStatus) (bool, error) {
+	staff, err := s.isStaff(ctx, actor, model.PermissionBookingUpdateStatus)
+	if err != nil || staff {
+		return staff, err
+	}
+
+	if booking.ProviderID != nil && *booking.ProviderID == actor.UserID {
+		if next, ok := providerTransitions[booking.Status]; ok && next == status {
+			return true, nil
+		}
+	}
+
+	return isOwner(actor, booking) && status == model.BookingStatusCancelled && ownerCancellable[booking.Status], nil
+}
+
+// scopeFilters restricts booking listings for actors who cannot read all bookings
+func (s *bookingService) scopeFilters(ctx context.Context, actor Actor, filters map[string]interface{}) (map[string]interface{}, error) {
+	staff, err := s.isStaff(ctx, actor, model.PermissionBookingRead)
+	if err != nil {
+		return nil, err
+	}
+	if staff {
+		return filters, nil
+	}
+
+	if filters == nil {
+		filters = make(map[string]interface{})
+	}
+
+	if actor.Role == string(model.UserRoleProvider) {
+		delete(filters, "user_id")
+		filters["provider_id"] = actor.UserID
+	} else {
+		filters["user_id"] = actor.UserID
+	}
+
+	return filters, nil
+}
@@ -1,65 +1,155 @@
 package service
 
 import (
-	"errors"
-	"fmt"
+	"context"
+	"strings"
 	"time"
 
+	"service-booking/internal/apperror"
 	"service-booking/internal/model"
 	"service-booking/internal/repository"
+	"service-booking/pkg/phone"
+	"service-booking/pkg/refcode"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var ErrInvalidReferenceCode = apperror.Validation("invalid booking reference code")
+
 type BookingService interface {
-	GetBookings(page, limit int, filters map[string]interface{}) ([]model.Booking, int64, error)
-	GetBookingByID(id uint) (*model.Booking, error)
-	GetBookingByReferenceCode(referenceCode string) (*model.Booking, error)
-	CreateBooking(booking *model.Booking) error
-	UpdateBooking(booking *model.Booking) error
-	UpdateBookingStatus(id uint, status model.BookingStatus, userID uint, notes string) error
-	CancelBooking(id uint, userID uint) error
+	GetBookings(ctx context.Context, actor Actor, page, limit int, filters map[string]interface{}) ([]model.Booking, int64, error)
+	GetBookingByID(ctx context.Context, actor Actor, id uint) (*model.Booking, error)
+	GetBookingByReferenceCode(ctx context.Context, referenceCode string) (*model.Booking, error)
+	CreateBooking(ctx context.Context, booking *model.Booking) error
+	UpdateBooking(ctx context.Context, booking *model.Booking) error
+	UpdateBookingStatus(ctx context.Context, actor Actor, id uint, status model.BookingStatus, notes string) error
+	CancelBooking(ctx context.Context, actor Actor, id uint, notes string) error
+	AssignProvider(ctx context.Context, id uint, providerID uint) error
+}
+
+// BookingRecorder is notified of booking events, e.g. to count them in metrics
+type BookingRecorder interface {
+	BookingCreated(booking *model.Booking, category string)
+	BookingStatusChanged(booking *model.Booking, from, to model.BookingStatus, category, role string)
+}
+
+// nopBookingRecorder ignores booking events
+type nopBookingRecorder struct{}
+
+func (nopBookingRecorder) BookingCreated(*model.Booking, string) {}
+
+func (nopBookingRecorder) BookingStatusChanged(*model.Booking, model.BookingStatus, model.BookingStatus, string, string) {
 }
 
 type bookingService struct {
-	bookingRepo repository.BookingRepository
-	serviceRepo repository.ServiceRepository
-	userRepo    repository.UserRepository
+	bookingRepo     repository.BookingRepository
+	serviceRepo     repository.ServiceRepository
+	userRepo        repository.UserRepository
+	roleService     RoleService
+	referenceCodes  refcode.Generator
+	referencePrefix string
+	countryCode     string
+	recorder        BookingRecorder
 }
 
+// NewBookingService creates a new instance of BookingService. Reference codes
+// use the prefix of the booked service's category, or referencePrefix.
+// Contact numbers without an international prefix are read as numbers of
+// defaultCountryCode. The recorder may be nil.
 func NewBookingService(
 	bookingRepo repository.BookingRepository,
 	serviceRepo repository.ServiceRepository,
 	userRepo repository.UserRepository,
+	roleService RoleService,
+	referenceCodes refcode.Generator,
+	referencePrefix string,
+	defaultCountryCode string,
+	recorder BookingRecorder,
 ) BookingService {
+	if recorder == nil {
+		recorder = nopBookingRecorder{}
+	}
+
 	return &bookingService{
-		bookingRepo: bookingRepo,
-		serviceRepo: serviceRepo,
-		userRepo:    userRepo,
+		bookingRepo:     bookingRepo,
+		serviceRepo:     serviceRepo,
+		userRepo:        userRepo,
+		roleService:     roleService,
+		referenceCodes:  referenceCodes,
+		referencePrefix: referencePrefix,
+		countryCode:     defaultCountryCode,
+		recorder:        recorder,
 	}
 }
 
-func (s *bookingService) GetBookings(page, limit int, filters map[string]interface{}) ([]model.Booking, int64, error) {
-	return s.bookingRepo.FindAll(page, limit, filters)
+func (s *bookingService) GetBookings(ctx context.Context, actor Actor, page, limit int, filters map[string]interface{}) ([]model.Booking, int64, error) {
+	filters, err := s.scopeFilters(ctx, actor, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return s.bookingRepo.FindAll(ctx, page, limit, filters)
 }
 
-func (s *bookingService) GetBookingByID(id uint) (*model.Booking, error) {
-	return s.bookingRepo.FindByID(id)
+func (s *bookingService) GetBookingByID(ctx context.Context, actor Actor, id uint) (*model.Booking, error) {
+	booking, err := s.bookingRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, apperror.IfNotFound(err, ErrBookingNotFound)
+	}
+
+	allowed, err := s.canRead(ctx, actor, booking)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrBookingNotFound
+	}
+
+	return booking, nil
 }
 
-func (s *bookingService) GetBookingByReferenceCode(referenceCode string) (*model.Booking, error) {
-	return s.bookingRepo.FindByReferenceCode(referenceCode)
+func (s *bookingService) GetBookingByReferenceCode(ctx context.Context, referenceCode string) (*model.Booking, error) {
+	// Codes with a wrong check symbol are rejected without a database lookup
+	code, err := refcode.Normalize(referenceCode)
+	if err != nil {
+		if !isLegacyReferenceCode(referenceCode) {
+			return nil, ErrInvalidReferenceCode
+		}
+		code = referenceCode
+	}
+
+	booking, err := s.bookingRepo.FindByReferenceCode(ctx, code)
+	if err != nil {
+		return nil, apperror.IfNotFound(err, ErrBookingNotFound)
+	}
+	return booking, nil
 }
 
-func (s *bookingService) CreateBooking(booking *model.Booking) error {
+func (s *bookingService) CreateBooking(ctx context.Context, booking *model.Booking) error {
 	// Validate service
-	service, err := s.serviceRepo.FindByID(booking.ServiceID)
+	service, err := s.serviceRepo.FindByID(ctx, booking.ServiceID)
 	if err != nil {
-		return errors.New("service not found")
+		return apperror.IfNotFound(err, apperror.Validation("service not found"))
 	}
 
-	// Validate user
-	_, err = s.userRepo.FindByID(booking.UserID)
-	if err != nil {
-		return errors.New("user not found")
+	// Validate user; guest bookings are tied to their verified phone instead
+	if booking.UserID != nil {
+		_, err = s.userRepo.FindByID(ctx, *booking.UserID)
+		if err != nil {
+			return apperror.IfNotFound(err, apperror.Validation("user not found"))
+		}
+	} else if booking.PhoneNumber == "" {
+		return apperror.Validation("phone number is required for guest bookings")
+	}
+
+	// Contact numbers are stored in E.164 like verified ones
+	if booking.PhoneNumber != "" {
+		normalizedPhone, err := phone.NormalizeE164(booking.PhoneNumber, s.countryCode)
+		if err != nil {
+			return apperror.Wrap(apperror.KindValidation, "invalid phone number", err)
+		}
+		booking.PhoneNumber = normalizedPhone
 	}
 
 	// Set default status
@@ -70,31 +160,45 @@ func (s *bookingService) CreateBooking(booking *model.Booking) error {
 	// Calculate total price
 	booking.TotalPrice = service.Price * float64(booking.Duration)
 
-	// Generate booking reference code (if not provided)
-	if booking.BookingReferenceCode == "" {
-		booking.BookingReferenceCode = generateBookingReferenceCode()
+	// Reference codes are always generated, using the category prefix if set
+	prefix := s.referencePrefix
+	if service.Category.ReferencePrefix != "" {
+		prefix = service.Category.ReferencePrefix
+	}
+	referenceCode := func() (string, error) {
+		return s.referenceCodes.Generate(prefix)
 	}
 
 	// Create booking and initial status history
-	return s.bookingRepo.CreateWithStatusHistory(booking)
+	if err := s.bookingRepo.CreateWithStatusHistory(ctx, booking, referenceCode); err != nil {
+		return err
+	}
+
+	trace.SpanFromContext(ctx).AddEvent("booking created", trace.WithAttributes(
+		attribute.Int64("booking.id", int64(booking.ID)),
+		attribute.String("booking.reference", booking.BookingReferenceCode),
+	))
+	s.recorder.BookingCreated(booking, service.Category.Name)
+	return nil
 }
 
-func (s *bookingService) UpdateBooking(booking *model.Booking) error {
+func (s *bookingService) UpdateBooking(ctx context.Context, booking *model.Booking) error {
 	// Validate service if service ID is changed
 	if booking.ServiceID > 0 {
-		_, err := s.serviceRepo.FindByID(booking.ServiceID)
+		_, err := s.serviceRepo.FindByID(ctx, booking.ServiceID)
 		if err != nil {
-			return errors.New("service not found")
+			return apperror.IfNotFound(err, apperror.Validation("service not found"))
 		}
 	}
 
-	return s.bookingRepo.Update(booking)
+	return s.bookingRepo.Update(ctx, booking)
 }
 
 func (s *bookingService) UpdateBookingStatus(
+	ctx context.Context,
+	actor Actor,
 	id uint, 
 	status model.BookingStatus, 
-	userID uint, 
 	notes string,
 ) error {
 	// Validate status
@@ -115,7 +219,24 @@ func (s *bookingService) UpdateBookingStatus(
 	}
 
 	if !isValidStatus {
-		return errors.New("invalid booking status")
+		return apperror.Validation("invalid booking status")
+	}
+
+	// Check the actor may change this booking
+	booking, err := s.GetBookingByID(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+
+	allowed, err := s.canChangeStatus(ctx, actor, booking, status)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrBookingForbidden
+	}
+	if status == booking.Status {
+		return ErrBookingStatusUnchanged
 	}
 
 	// Create status history entry
@@ -124,26 +245,82 @@ func (s *bookingService) UpdateBookingStatus(
 		Status:        status,
 		IsActive:      true,
 		Notes:         notes,
-		CreatedBy:     userID,
+		CreatedBy:     actor.UserID,
 		EstimatedCompletionTime: calculateEstimatedCompletionTime(status),
 	}
 
-	return s.bookingRepo.UpdateStatusWithHistory(id, status, statusHistory)
+	updated, err := s.bookingRepo.UpdateStatusWithHistory(ctx, id, booking.Status, status, statusHistory)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrBookingStatusChanged
+	}
+
+	trace.SpanFromContext(ctx).AddEvent("booking status changed", trace.WithAttributes(
+		attribute.Int64("booking.id", int64(id)),
+		attribute.String("booking.status.from", string(booking.Status)),
+		attribute.String("booking.status.to", string(status)),
+	))
+	s.recorder.BookingStatusChanged(booking, booking.Status, status, s.categoryName(ctx, booking.ServiceID), actor.Role)
+	return nil
 }
 
-func (s *bookingService) CancelBooking(id uint, userID uint) error {
+func (s *bookingService) CancelBooking(ctx context.Context, actor Actor, id uint, notes string) error {
+	if notes == "" {
+		notes = "Booking cancelled by user"
+	}
+
 	return s.UpdateBookingStatus(
+		ctx,
+		actor,
 		id, 
 		model.BookingStatusCancelled, 
-		userID, 
-		"Booking cancelled by user",
+		notes,
 	)
 }
 
+func (s *bookingService) AssignProvider(ctx context.Context, id uint, providerID uint) error {
+	if _, err := s.bookingRepo.FindByID(ctx, id); err != nil {
+		return apperror.IfNotFound(err, ErrBookingNotFound)
+	}
+
+	// Validate provider
+	provider, err := s.userRepo.FindByID(ctx, providerID)
+	if err != nil {
+		return apperror.IfNotFound(err, apperror.Validation("provider not found"))
+	}
+	if provider.Role != model.UserRoleProvider {
+		return apperror.Validation("user is not a provider")
+	}
+
+	return s.bookingRepo.UpdateProvider(ctx, id, provider.ID)
+}
+
 // Helper functions
-func generateBookingReferenceCode() string {
-	// Implement a unique booking reference code generation logic
-	return fmt.Sprintf("SB-%d", time.Now().UnixNano())
+
+// categoryName returns the category of the service for booking events
+func (s *bookingService) categoryName(ctx context.Context, serviceID uint) string {
+	service, err := s.serviceRepo.FindByID(ctx, serviceID)
+	if err != nil || service.Category.Name == "" {
+		return "unknown"
+	}
+	return service.Category.Name
+}
+
+// isLegacyReferenceCode reports whether the code has the SB-<UnixNano> format
+// of bookings created before reference codes had check symbols
+func isLegacyReferenceCode(code string) bool {
+	digits, ok := strings.CutPrefix(code, "SB-")
+	if !ok || len(digits) < 16 || len(digits) > 20 {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 func calculateEstimatedCompletionTime(status model.BookingStatus) *time.Time {
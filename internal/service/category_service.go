@@ -1,18 +1,22 @@
 package service
 
 import (
-	"errors"
+	"context"
+
+	"service-booking/internal/apperror"
 	"service-booking/internal/model"
 	"service-booking/internal/repository"
+	"service-booking/pkg/refcode"
+	"strings"
 )
 
 type CategoryService interface {
-	GetCategories(page, limit int, filters map[string]interface{}) ([]model.Category, int64, error)
-	GetCategoryByID(id uint) (*model.Category, error)
-	CreateCategory(category *model.Category) error
-	UpdateCategory(category *model.Category) error
-	DeleteCategory(id uint) error
-	GetSubCategories(parentCategoryID uint) ([]model.Category, error)
+	GetCategories(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.Category, int64, error)
+	GetCategoryByID(ctx context.Context, id uint) (*model.Category, error)
+	CreateCategory(ctx context.Context, category *model.Category) error
+	UpdateCategory(ctx context.Context, category *model.Category) error
+	DeleteCategory(ctx context.Context, id uint) error
+	GetSubCategories(ctx context.Context, parentCategoryID uint) ([]model.Category, error)
 }
 
 type categoryService struct {
@@ -23,57 +27,82 @@ func NewCategoryService(categoryRepo repository.CategoryRepository) CategoryServ
 	return &categoryService{categoryRepo: categoryRepo}
 }
 
-func (s *categoryService) GetCategories(page, limit int, filters map[string]interface{}) ([]model.Category, int64, error) {
-	return s.categoryRepo.FindAll(page, limit, filters)
+func (s *categoryService) GetCategories(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.Category, int64, error) {
+	return s.categoryRepo.FindAll(ctx, page, limit, filters)
 }
 
-func (s *categoryService) GetCategoryByID(id uint) (*model.Category, error) {
-	return s.categoryRepo.FindByID(id)
+func (s *categoryService) GetCategoryByID(ctx context.Context, id uint) (*model.Category, error) {
+	category, err := s.categoryRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, apperror.IfNotFound(err, apperror.NotFound("category not found"))
+	}
+	return category, nil
 }
 
-func (s *categoryService) CreateCategory(category *model.Category) error {
+func (s *categoryService) CreateCategory(ctx context.Context, category *model.Category) error {
 	// Validate parent category if specified
 	if category.ParentCategoryID != nil {
-		_, err := s.categoryRepo.FindByID(*category.ParentCategoryID)
+		_, err := s.categoryRepo.FindByID(ctx, *category.ParentCategoryID)
 		if err != nil {
-			return errors.New("invalid parent category")
+			return apperror.IfNotFound(err, apperror.Validation("invalid parent category"))
 		}
 	}
 
+	if err := validateReferencePrefix(category); err != nil {
+		return err
+	}
+
 	// Set default values
 	if category.IsActive == false {
 		category.IsActive = true
 	}
 
-	return s.categoryRepo.Create(category)
+	return s.categoryRepo.Create(ctx, category)
 }
 
-func (s *categoryService) UpdateCategory(category *model.Category) error {
+func (s *categoryService) UpdateCategory(ctx context.Context, category *model.Category) error {
 	// Validate parent category if changed
 	if category.ParentCategoryID != nil {
-		_, err := s.categoryRepo.FindByID(*category.ParentCategoryID)
+		_, err := s.categoryRepo.FindByID(ctx, *category.ParentCategoryID)
 		if err != nil {
-			return errors.New("invalid parent category")
+			return apperror.IfNotFound(err, apperror.Validation("invalid parent category"))
 		}
 	}
 
-	return s.categoryRepo.Update(category)
+	if err := validateReferencePrefix(category); err != nil {
+		return err
+	}
+
+	return s.categoryRepo.Update(ctx, category)
 }
 
-func (s *categoryService) DeleteCategory(id uint) error {
+func (s *categoryService) DeleteCategory(ctx context.Context, id uint) error {
 	// Check for existing subcategories
-	subCategories, err := s.categoryRepo.FindByParentCategory(id)
+	subCategories, err := s.categoryRepo.FindByParentCategory(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	if len(subCategories) > 0 {
-		return errors.New("cannot delete category with subcategories")
+		return apperror.Conflict("cannot delete category with subcategories")
 	}
 
-	return s.categoryRepo.Delete(id)
+	return s.categoryRepo.Delete(ctx, id)
+}
+
+func (s *categoryService) GetSubCategories(ctx context.Context, parentCategoryID uint) ([]model.Category, error) {
+	return s.categoryRepo.FindByParentCategory(ctx, parentCategoryID)
 }
 
-func (s *categoryService) GetSubCategories(parentCategoryID uint) ([]model.Category, error) {
-	return s.categoryRepo.FindByParentCategory(parentCategoryID)
+// validateReferencePrefix normalizes the booking reference prefix of a category
+func validateReferencePrefix(category *model.Category) error {
+	if category.ReferencePrefix == "" {
+		return nil
+	}
+
+	category.ReferencePrefix = strings.ToUpper(strings.TrimSpace(category.ReferencePrefix))
+	if !refcode.ValidPrefix(category.ReferencePrefix) {
+		return apperror.Validation("reference prefix must be 1 to 5 letters")
+	}
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"service-booking/config"
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+	"service-booking/pkg/phone"
+	"service-booking/pkg/sms"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// guestAccessAudience keeps booking links from being accepted as other tokens
+const guestAccessAudience = "booking-access"
+
+// GuestAccessConfig holds guest booking access configuration
+type GuestAccessConfig struct {
+	LinkBaseURL        string
+	TokenTTL           time.Duration
+	SigningKey         []byte
+	DefaultCountryCode string
+}
+
+// DefaultGuestAccessConfig provides guest access configuration from the
+// application config. Links are signed with signingKey.
+func DefaultGuestAccessConfig(cfg *config.Config, signingKey []byte) GuestAccessConfig {
+	return GuestAccessConfig{
+		LinkBaseURL:        cfg.GuestAccess.LinkBaseURL,
+		TokenTTL:           parseDurationOr(cfg.GuestAccess.TokenTTL, 30*24*time.Hour),
+		SigningKey:         signingKey,
+		DefaultCountryCode: cfg.OTP.DefaultCountryCode,
+	}
+}
+
+// guestAccessClaims are the claims of a booking magic link token
+type guestAccessClaims struct {
+	ReferenceCode string `json:"ref"`
+	jwt.RegisteredClaims
+}
+
+// GuestAccessService interface defines the methods for looking up a booking
+// without an account
+type GuestAccessService interface {
+	SendAccessLink(booking *model.Booking) error
+	IssueAccessToken(booking *model.Booking) (string, error)
+	LookupByPhone(ctx context.Context, referenceCode, rawPhone string) (*model.Booking, error)
+	LookupByToken(ctx context.Context, referenceCode, token string) (*model.Booking, error)
+}
+
+// guestAccessService implements GuestAccessService
+type guestAccessService struct {
+	bookingService BookingService
+	sender         sms.Sender
+	config         GuestAccessConfig
+}
+
+// NewGuestAccessService creates a new instance of GuestAccessService
+func NewGuestAccessService(bookingService BookingService, sender sms.Sender, guestConfig GuestAccessConfig) GuestAccessService {
+	return &guestAccessService{
+		bookingService: bookingService,
+		sender:         sender,
+		config:         guestConfig,
+	}
+}
+
+// SendAccessLink texts the booking phone number a link to view the booking
+func (s *guestAccessService) SendAccessLink(booking *model.Booking) error {
+	token, err := s.IssueAccessToken(booking)
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(s.config.LinkBaseURL)
+	if err != nil {
+		return fmt.Errorf("invalid booking link base URL: %w", err)
+	}
+	query := link.Query()
+	query.Set("code", booking.BookingReferenceCode)
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	message := fmt.Sprintf("Your booking %s is received. View it at %s", booking.BookingReferenceCode, link.String())
+	return s.sender.Send(booking.PhoneNumber, message)
+}
+
+// IssueAccessToken signs a token granting read access to the booking
+func (s *guestAccessService) IssueAccessToken(booking *model.Booking) (string, error) {
+	now := time.Now()
+	claims := guestAccessClaims{
+		ReferenceCode: booking.BookingReferenceCode,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(booking.ID), 10),
+			Audience:  jwt.ClaimStrings{guestAccessAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.TokenTTL)),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.config.SigningKey)
+}
+
+// LookupByPhone returns the booking when the phone number matches the one it
+// was made with. Mismatches are reported as not found.
+func (s *guestAccessService) LookupByPhone(ctx context.Context, referenceCode, rawPhone string) (*model.Booking, error) {
+	booking, err := s.bookingService.GetBookingByReferenceCode(ctx, referenceCode)
+	if err != nil {
+		return nil, err
+	}
+
+	given, err := phone.NormalizeE164(rawPhone, s.config.DefaultCountryCode)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.KindValidation, "invalid phone number", err)
+	}
+	expected, err := phone.NormalizeE164(booking.PhoneNumber, s.config.DefaultCountryCode)
+	if err != nil {
+		return nil, ErrBookingNotFound
+	}
+
+	if subtle.ConstantTimeCompare([]byte(given), []byte(expected)) != 1 {
+		return nil, ErrBookingNotFound
+	}
+	return booking, nil
+}
+
+// LookupByToken returns the booking a magic link token was issued for
+func (s *guestAccessService) LookupByToken(ctx context.Context, referenceCode, token string) (*model.Booking, error) {
+	claims := &guestAccessClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.config.SigningKey, nil
+	}, jwt.WithAudience(guestAccessAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrBookingNotFound
+	}
+
+	booking, err := s.bookingService.GetBookingByReferenceCode(ctx, referenceCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token must be for this booking
+	if claims.ReferenceCode != booking.BookingReferenceCode ||
+		claims.Subject != strconv.FormatUint(uint64(booking.ID), 10) {
+		return nil, ErrBookingNotFound
+	}
+	return booking, nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"service-booking/config"
+	"service-booking/internal/model"
+	"service-booking/internal/repository"
+)
+
+// ThrottledError is returned when a login is rejected without checking the password
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// LoginThrottleConfig holds login brute-force protection configuration
+type LoginThrottleConfig struct {
+	FreeAttempts    int
+	MaxFailures     int
+	IPMaxFailures   int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// DefaultLoginThrottleConfig provides login throttling configuration from the application config
+func DefaultLoginThrottleConfig(cfg *config.Config) LoginThrottleConfig {
+	throttleConfig := LoginThrottleConfig{
+		FreeAttempts:    cfg.LoginThrottle.FreeAttempts,
+		MaxFailures:     cfg.LoginThrottle.MaxFailures,
+		IPMaxFailures:   cfg.LoginThrottle.IPMaxFailures,
+		BaseDelay:       parseDurationOr(cfg.LoginThrottle.BaseDelay, time.Second),
+		MaxDelay:        parseDurationOr(cfg.LoginThrottle.MaxDelay, 5*time.Minute),
+		LockoutDuration: parseDurationOr(cfg.LoginThrottle.LockoutDuration, 15*time.Minute),
+		Window:          parseDurationOr(cfg.LoginThrottle.Window, 15*time.Minute),
+	}
+
+	if throttleConfig.MaxFailures < 1 {
+		throttleConfig.MaxFailures = 10
+	}
+	if throttleConfig.IPMaxFailures < 1 {
+		throttleConfig.IPMaxFailures = 100
+	}
+
+	return throttleConfig
+}
+
+// LoginThrottleService interface defines the methods for login brute-force protection
+type LoginThrottleService interface {
+	Check(ctx context.Context, email, ip string) error
+	RecordFailure(ctx context.Context, email, ip string) error
+	RecordSuccess(ctx context.Context, email string) error
+	Unlock(ctx context.Context, email string) error
+}
+
+// loginThrottleService implements LoginThrottleService with exponential
+// backoff per email and per IP and a temporary lockout after repeated failures
+type loginThrottleService struct {
+	store  repository.LoginAttemptStore
+	config LoginThrottleConfig
+}
+
+// NewLoginThrottleService creates a new instance of LoginThrottleService
+func NewLoginThrottleService(store repository.LoginAttemptStore, throttleConfig LoginThrottleConfig) LoginThrottleService {
+	return &loginThrottleService{
+		store:  store,
+		config: throttleConfig,
+	}
+}
+
+// Check returns a ThrottledError when the email or IP must wait before trying again
+func (s *loginThrottleService) Check(ctx context.Context, email, ip string) error {
+	var retryAfter time.Duration
+	for _, key := range s.keys(email, ip) {
+		attempt, err := s.store.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to check login attempts: %w", err)
+		}
+		if wait := s.waitTime(attempt); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &ThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login and locks keys that reached their limit
+func (s *loginThrottleService) RecordFailure(ctx context.Context, email, ip string) error {
+	for _, key := range s.keys(email, ip) {
+		attempt, err := s.store.RecordFailure(ctx, key, s.config.Window)
+		if err != nil {
+			return fmt.Errorf("failed to record login attempt: %w", err)
+		}
+
+		maxFailures := s.config.MaxFailures
+		if strings.HasPrefix(key, "ip:") {
+			maxFailures = s.config.IPMaxFailures
+		}
+
+		if attempt.Failures >= maxFailures {
+			if err := s.store.Lock(ctx, key, time.Now().Add(s.config.LockoutDuration)); err != nil {
+				return fmt.Errorf("failed to lock login: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// RecordSuccess clears the failures of an account. IP counters are kept so a
+// valid account cannot be used to reset throttling of a guessing client.
+func (s *loginThrottleService) RecordSuccess(ctx context.Context, email string) error {
+	return s.store.Delete(ctx, emailThrottleKey(email))
+}
+
+// Unlock lifts the lockout of an account
+func (s *loginThrottleService) Unlock(ctx context.Context, email string) error {
+	return s.store.Delete(ctx, emailThrottleKey(email))
+}
+
+// waitTime returns how long the key must wait before its next attempt
+func (s *loginThrottleService) waitTime(attempt *model.LoginAttempt) time.Duration {
+	if attempt == nil {
+		return 0
+	}
+
+	now := time.Now()
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+		return attempt.LockedUntil.Sub(now)
+	}
+	if now.Sub(attempt.LastFailureAt) > s.config.Window {
+		return 0
+	}
+
+	excess := attempt.Failures - s.config.FreeAttempts
+	if excess <= 0 {
+		return 0
+	}
+
+	delay := s.config.BaseDelay
+	for i := 1; i < excess && delay < s.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.config.MaxDelay {
+		delay = s.config.MaxDelay
+	}
+
+	return time.Until(attempt.LastFailureAt.Add(delay))
+}
+
+func (s *loginThrottleService) keys(email, ip string) []string {
+	keys := []string{emailThrottleKey(email)}
+	if ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	return keys
+}
+
+func emailThrottleKey(email string) string {
+	return "email:" + strings.TrimSpace(strings.ToLower(email))
+}
+
+// parseDurationOr parses a duration, falling back to a default value
+func parseDurationOr(value string, defaultValue time.Duration) time.Duration {
+	if duration, err := time.ParseDuration(value); err == nil {
+		return duration
+	}
+	return defaultValue
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"service-booking/internal/repository"
+)
+
+func testThrottleConfig() LoginThrottleConfig {
+	return LoginThrottleConfig{
+		FreeAttempts:    2,
+		MaxFailures:     6,
+		IPMaxFailures:   8,
+		BaseDelay:       time.Minute,
+		MaxDelay:        4 * time.Minute,
+		LockoutDuration: time.Hour,
+		Window:          time.Hour,
+	}
+}
+
+// recordFailures fails n logins of an email from an IP
+func recordFailures(t *testing.T, throttle LoginThrottleService, email, ip string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := throttle.RecordFailure(context.Background(), email, ip); err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+	}
+}
+
+// retryAfter returns the wait reported by Check, or zero when not throttled
+func retryAfter(t *testing.T, throttle LoginThrottleService, email, ip string) time.Duration {
+	t.Helper()
+	err := throttle.Check(context.Background(), email, ip)
+	if err == nil {
+		return 0
+	}
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("Check: %v", err)
+	}
+	return throttled.RetryAfter
+}
+
+func TestLoginThrottleBackoff(t *testing.T) {
+	throttle := NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), testThrottleConfig())
+
+	recordFailures(t, throttle, "jane@example.com", "192.0.2.1", 2)
+	if wait := retryAfter(t, throttle, "jane@example.com", "192.0.2.1"); wait != 0 {
+		t.Fatalf("free attempts are throttled for %s", wait)
+	}
+
+	// The delay doubles with every failure beyond the free attempts and is
+	// capped at MaxDelay
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		recordFailures(t, throttle, "jane@example.com", "192.0.2.1", 1)
+		wait := retryAfter(t, throttle, "jane@example.com", "192.0.2.1")
+		if wait <= want-time.Second || wait > want {
+			t.Errorf("got wait %s, want %s", wait, want)
+		}
+	}
+
+	// Emails are compared case-insensitively
+	if wait := retryAfter(t, throttle, " JANE@example.com ", ""); wait == 0 {
+		t.Error("expected the email to be throttled regardless of case")
+	}
+}
+
+func TestLoginThrottleLockout(t *testing.T) {
+	throttle := NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), testThrottleConfig())
+
+	recordFailures(t, throttle, "jane@example.com", "192.0.2.1", 6)
+	if wait := retryAfter(t, throttle, "jane@example.com", ""); wait <= 59*time.Minute {
+		t.Fatalf("got wait %s, want the lockout duration", wait)
+	}
+
+	if err := throttle.Unlock(context.Background(), "jane@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if wait := retryAfter(t, throttle, "jane@example.com", ""); wait != 0 {
+		t.Errorf("unlocked email is throttled for %s", wait)
+	}
+}
+
+func TestLoginThrottleSuccessKeepsIPFailures(t *testing.T) {
+	throttle := NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), testThrottleConfig())
+
+	recordFailures(t, throttle, "jane@example.com", "192.0.2.1", 3)
+	if err := throttle.RecordSuccess(context.Background(), "jane@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if wait := retryAfter(t, throttle, "jane@example.com", "198.51.100.1"); wait != 0 {
+		t.Errorf("email is still throttled for %s after a successful login", wait)
+	}
+	if wait := retryAfter(t, throttle, "john@example.com", "192.0.2.1"); wait == 0 {
+		t.Error("expected the IP to stay throttled after a successful login")
+	}
+}
+
+func TestLoginThrottleIPLockout(t *testing.T) {
+	throttle := NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), testThrottleConfig())
+
+	// A client guessing many accounts is backed off by IP, then locked out
+	for _, email := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		recordFailures(t, throttle, email+"@example.com", "192.0.2.1", 1)
+	}
+	if wait := retryAfter(t, throttle, "other@example.com", "192.0.2.1"); wait == 0 || wait > 4*time.Minute {
+		t.Fatalf("got wait %s, want a backoff delay", wait)
+	}
+
+	recordFailures(t, throttle, "h@example.com", "192.0.2.1", 1)
+	if wait := retryAfter(t, throttle, "other@example.com", "192.0.2.1"); wait <= 59*time.Minute {
+		t.Errorf("got wait %s, want the lockout duration", wait)
+	}
+}
+
+func TestLoginThrottleWindow(t *testing.T) {
+	config := testThrottleConfig()
+	config.Window = 50 * time.Millisecond
+	config.BaseDelay = time.Second
+	throttle := NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), config)
+
+	recordFailures(t, throttle, "jane@example.com", "", 3)
+	if wait := retryAfter(t, throttle, "jane@example.com", ""); wait == 0 {
+		t.Fatal("expected the email to be throttled")
+	}
+
+	time.Sleep(2 * config.Window)
+	if wait := retryAfter(t, throttle, "jane@example.com", ""); wait != 0 {
+		t.Errorf("failures outside the window still throttle for %s", wait)
+	}
+}
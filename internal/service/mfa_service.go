@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+	"service-booking/internal/repository"
+	"service-booking/pkg/auth"
+	"service-booking/pkg/totp"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpIssuer        = "Sheba Service Booking"
+	totpSkew          = 1
+	recoveryCodeCount = 10
+	// recoveryCodeAlphabet omits characters that are easily confused
+	recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+var (
+	ErrMFAAlreadyEnabled = apperror.Conflict("two-factor authentication is already enabled")
+	ErrMFANotEnrolled    = apperror.Conflict("two-factor authentication is not enabled")
+	ErrMFAInvalidCode    = apperror.Unauthorized("invalid verification code")
+)
+
+// MFAService interface defines the methods for TOTP two-factor authentication
+type MFAService interface {
+	BeginEnrollment(ctx context.Context, userID uint) (secret string, uri string, err error)
+	ConfirmEnrollment(ctx context.Context, userID uint, code string) ([]string, error)
+	Disable(ctx context.Context, userID uint, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error)
+	Verify(ctx context.Context, userID uint, code string) error
+}
+
+// mfaService implements MFAService
+type mfaService struct {
+	userRepo         repository.UserRepository
+	recoveryCodeRepo repository.RecoveryCodeRepository
+}
+
+// NewMFAService creates a new instance of MFAService
+func NewMFAService(userRepo repository.UserRepository, recoveryCodeRepo repository.RecoveryCodeRepository) MFAService {
+	return &mfaService{
+		userRepo:         userRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+	}
+}
+
+// BeginEnrollment generates a new secret for the user. It only takes effect
+// once confirmed with a code from the authenticator app.
+func (s *mfaService) BeginEnrollment(ctx context.Context, userID uint) (string, string, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return "", "", apperror.IfNotFound(err, ErrUserNotFound)
+	}
+	if user.TOTPEnabled {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return "", "", fmt.Errorf("failed to store secret: %w", err)
+	}
+
+	return secret, totp.URI(secret, totpIssuer, user.Email), nil
+}
+
+// ConfirmEnrollment enables two-factor authentication and returns the
+// plain-text recovery codes, which are only available at this point
+func (s *mfaService) ConfirmEnrollment(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, apperror.IfNotFound(err, ErrUserNotFound)
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	if err := s.validateTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	user.TOTPEnabled = true
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	return s.issueRecoveryCodes(ctx, user.ID)
+}
+
+// Disable turns off two-factor authentication after checking a current code
+func (s *mfaService) Disable(ctx context.Context, userID uint, code string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return apperror.IfNotFound(err, ErrUserNotFound)
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnrolled
+	}
+
+	if err := s.validateTOTP(ctx, user, code); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	return s.recoveryCodeRepo.DeleteByUser(ctx, user.ID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current code
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, apperror.IfNotFound(err, ErrUserNotFound)
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrMFANotEnrolled
+	}
+
+	if err := s.validateTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(ctx, user.ID)
+}
+
+// Verify checks the second login factor, accepting either a TOTP code or an
+// unused recovery code
+func (s *mfaService) Verify(ctx context.Context, userID uint, code string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return apperror.IfNotFound(err, ErrUserNotFound)
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return s.validateTOTP(ctx, user, code)
+	}
+
+	return s.useRecoveryCode(ctx, user.ID, code)
+}
+
+// validateTOTP checks a code and records its time step so it cannot be replayed
+func (s *mfaService) validateTOTP(ctx context.Context, user *model.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok || step <= user.TOTPLastStep {
+		return ErrMFAInvalidCode
+	}
+
+	user.TOTPLastStep = step
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to record verification: %w", err)
+	}
+	return nil
+}
+
+// useRecoveryCode consumes a matching recovery code
+func (s *mfaService) useRecoveryCode(ctx context.Context, userID uint, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrMFAInvalidCode
+	}
+
+	codes, err := s.recoveryCodeRepo.FindUnusedByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find recovery codes: %w", err)
+	}
+
+	for _, recoveryCode := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(recoveryCode.CodeHash), []byte(normalized)) != nil {
+			continue
+		}
+
+		used, err := s.recoveryCodeRepo.MarkUsed(ctx, recoveryCode.ID)
+		if err != nil {
+			return fmt.Errorf("failed to use recovery code: %w", err)
+		}
+		if !used {
+			return ErrMFAInvalidCode
+		}
+		return nil
+	}
+
+	return ErrMFAInvalidCode
+}
+
+// issueRecoveryCodes replaces the user's recovery codes with new ones
+func (s *mfaService) issueRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	plainCodes := make([]string, 0, recoveryCodeCount)
+	codes := make([]model.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		codeHash, err := auth.HashPassword(normalizeRecoveryCode(code))
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash recovery code: %w", err)
+		}
+
+		plainCodes = append(plainCodes, code)
+		codes = append(codes, model.RecoveryCode{UserID: userID, CodeHash: codeHash})
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceForUser(ctx, userID, codes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+
+	return plainCodes, nil
+}
+
+// generateRecoveryCode returns a random code formatted as XXXXX-XXXXX
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	var code strings.Builder
+	for i, b := range buf {
+		if i == 5 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+	}
+	return code.String(), nil
+}
+
+// normalizeRecoveryCode ignores case and separators typed by the user
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return code
+}
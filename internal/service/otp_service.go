@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"service-booking/config"
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+	"service-booking/internal/repository"
+	"service-booking/pkg/auth"
+	"service-booking/pkg/phone"
+	"service-booking/pkg/sms"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOTPResendTooSoon = apperror.RateLimited("please wait before requesting another code")
+	ErrOTPInvalid       = apperror.Unauthorized("invalid or expired code")
+	ErrOTPTooManyTries  = apperror.Unauthorized("too many incorrect attempts, request a new code")
+)
+
+// OTPConfig holds one-time password configuration
+type OTPConfig struct {
+	Length             int
+	TTL                time.Duration
+	MaxAttempts        int
+	ResendInterval     time.Duration
+	DefaultCountryCode string
+}
+
+// DefaultOTPConfig provides OTP configuration from the application config
+func DefaultOTPConfig(cfg *config.Config) OTPConfig {
+	otpConfig := OTPConfig{
+		Length:             cfg.OTP.Length,
+		TTL:                5 * time.Minute,
+		MaxAttempts:        cfg.OTP.MaxAttempts,
+		ResendInterval:     time.Minute,
+		DefaultCountryCode: cfg.OTP.DefaultCountryCode,
+	}
+
+	if ttl, err := time.ParseDuration(cfg.OTP.TTL); err == nil {
+		otpConfig.TTL = ttl
+	}
+	if interval, err := time.ParseDuration(cfg.OTP.ResendInterval); err == nil {
+		otpConfig.ResendInterval = interval
+	}
+	if otpConfig.Length < 4 || otpConfig.Length > 10 {
+		otpConfig.Length = 6
+	}
+	if otpConfig.MaxAttempts < 1 {
+		otpConfig.MaxAttempts = 5
+	}
+	if otpConfig.DefaultCountryCode == "" {
+		otpConfig.DefaultCountryCode = "880"
+	}
+
+	return otpConfig
+}
+
+// OTPService interface defines the methods for passwordless phone login
+type OTPService interface {
+	RequestOTP(ctx context.Context, rawPhone string) error
+	VerifyOTP(ctx context.Context, rawPhone, code string) (*model.User, error)
+	VerifyPhone(ctx context.Context, rawPhone, code string) (string, error)
+}
+
+// otpService implements OTPService
+type otpService struct {
+	otpRepo     repository.OTPRepository
+	userRepo    repository.UserRepository
+	bookingRepo repository.BookingRepository
+	sender      sms.Sender
+	config      OTPConfig
+}
+
+// NewOTPService creates a new instance of OTPService
+func NewOTPService(
+	otpRepo repository.OTPRepository,
+	userRepo repository.UserRepository,
+	bookingRepo repository.BookingRepository,
+	sender sms.Sender,
+	otpConfig OTPConfig,
+) OTPService {
+	return &otpService{
+		otpRepo:     otpRepo,
+		userRepo:    userRepo,
+		bookingRepo: bookingRepo,
+		sender:      sender,
+		config:      otpConfig,
+	}
+}
+
+// RequestOTP generates a new code for the phone number and sends it by SMS
+func (s *otpService) RequestOTP(ctx context.Context, rawPhone string) error {
+	phoneNumber, err := phone.NormalizeE164(rawPhone, s.config.DefaultCountryCode)
+	if err != nil {
+		return apperror.Wrap(apperror.KindValidation, "invalid phone number", err)
+	}
+
+	// Enforce a cooldown between consecutive codes
+	latest, err := s.otpRepo.FindLatestByPhone(ctx, phoneNumber)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check previous code: %w", err)
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < s.config.ResendInterval {
+		return ErrOTPResendTooSoon
+	}
+
+	code, err := generateNumericCode(s.config.Length)
+	if err != nil {
+		return fmt.Errorf("failed to generate code: %w", err)
+	}
+
+	codeHash, err := auth.HashPassword(code)
+	if err != nil {
+		return fmt.Errorf("failed to hash code: %w", err)
+	}
+
+	// Only the most recent code is valid
+	if err := s.otpRepo.InvalidateByPhone(ctx, phoneNumber); err != nil {
+		return fmt.Errorf("failed to invalidate previous codes: %w", err)
+	}
+
+	otp := &model.OTPCode{
+		Phone:     phoneNumber,
+		CodeHash:  codeHash,
+		ExpiresAt: time.Now().Add(s.config.TTL),
+	}
+	if err := s.otpRepo.Create(ctx, otp); err != nil {
+		return fmt.Errorf("failed to store code: %w", err)
+	}
+
+	message := fmt.Sprintf("Your Sheba verification code is %s. It expires in %d minutes.",
+		code, int(s.config.TTL.Minutes()))
+	if err := s.sender.Send(phoneNumber, message); err != nil {
+		return fmt.Errorf("failed to send code: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyOTP checks the code and returns the user who verified the phone
+// number before, creating one on first successful verification. Guest
+// bookings made with the phone number are claimed into the account.
+func (s *otpService) VerifyOTP(ctx context.Context, rawPhone, code string) (*model.User, error) {
+	phoneNumber, err := s.VerifyPhone(ctx, rawPhone, code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.findOrCreateUserByPhone(ctx, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.bookingRepo.ClaimGuestBookings(ctx, phoneNumber, user.ID); err != nil {
+		slog.Error("Failed to claim guest bookings", "user_id", user.ID, "error", err)
+	}
+
+	return user, nil
+}
+
+// VerifyPhone checks the code and returns the normalized phone number
+// without logging anyone in, e.g. to let a guest book without an account
+func (s *otpService) VerifyPhone(ctx context.Context, rawPhone, code string) (string, error) {
+	phoneNumber, err := phone.NormalizeE164(rawPhone, s.config.DefaultCountryCode)
+	if err != nil {
+		return "", apperror.Wrap(apperror.KindValidation, "invalid phone number", err)
+	}
+
+	otp, err := s.otpRepo.FindLatestByPhone(ctx, phoneNumber)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrOTPInvalid
+		}
+		return "", fmt.Errorf("failed to find code: %w", err)
+	}
+
+	if otp.ConsumedAt != nil || time.Now().After(otp.ExpiresAt) {
+		return "", ErrOTPInvalid
+	}
+	// The attempt is counted before the code is checked, so parallel
+	// guesses cannot get past the limit
+	allowed, err := s.otpRepo.TakeAttempt(ctx, otp.ID, s.config.MaxAttempts)
+	if err != nil {
+		return "", fmt.Errorf("failed to record attempt: %w", err)
+	}
+	if !allowed {
+		return "", ErrOTPTooManyTries
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(otp.CodeHash), []byte(strings.TrimSpace(code))) != nil {
+		if otp.Attempts+1 >= s.config.MaxAttempts {
+			return "", ErrOTPTooManyTries
+		}
+		return "", ErrOTPInvalid
+	}
+
+	// A code is only good for one verification
+	consumed, err := s.otpRepo.Consume(ctx, otp.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to consume code: %w", err)
+	}
+	if !consumed {
+		return "", ErrOTPInvalid
+	}
+
+	return phoneNumber, nil
+}
+
+// findOrCreateUserByPhone provisions a customer account for a verified
+// phone number. Accounts that merely registered the number are not logged
+// into, as anyone can type in someone else's number.
+func (s *otpService) findOrCreateUserByPhone(ctx context.Context, phoneNumber string) (*model.User, error) {
+	user, err := s.userRepo.FindByVerifiedPhone(ctx, phoneNumber)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	// Phone-only accounts get a reserved placeholder email to satisfy the
+	// unique email constraint; they have no password and cannot use /auth/login
+	now := time.Now()
+	user = &model.User{
+		Name:            phoneNumber,
+		Email:           strings.TrimPrefix(phoneNumber, "+") + "@phone.invalid",
+		Phone:           phoneNumber,
+		PhoneVerifiedAt: &now,
+		Role:            model.UserRoleUser,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// generateNumericCode returns a cryptographically random numeric code
+func generateNumericCode(length int) (string, error) {
+	var code strings.Builder
+	for i := 0; i < length; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code.WriteString(digit.String())
+	}
+	return code.String(), nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+	"service-booking/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// RoleService interface defines the methods for role and permission management
+type RoleService interface {
+	GetRoles(ctx context.Context) ([]model.Role, error)
+	GetRoleByID(ctx context.Context, id uint) (*model.Role, error)
+	CreateRole(ctx context.Context, role *model.Role) error
+	UpdateRole(ctx context.Context, role *model.Role) error
+	DeleteRole(ctx context.Context, id uint) error
+	AssignRole(ctx context.Context, userID uint, roleName string) (*model.User, error)
+	HasPermission(ctx context.Context, roleName string, permission model.Permission) (bool, error)
+}
+
+// roleService implements RoleService
+type roleService struct {
+	roleRepo repository.RoleRepository
+	userRepo repository.UserRepository
+}
+
+// NewRoleService creates a new instance of RoleService
+func NewRoleService(roleRepo repository.RoleRepository, userRepo repository.UserRepository) RoleService {
+	return &roleService{
+		roleRepo: roleRepo,
+		userRepo: userRepo,
+	}
+}
+
+func (s *roleService) GetRoles(ctx context.Context) ([]model.Role, error) {
+	return s.roleRepo.FindAll(ctx)
+}
+
+func (s *roleService) GetRoleByID(ctx context.Context, id uint) (*model.Role, error) {
+	role, err := s.roleRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, apperror.IfNotFound(err, errRoleNotFound)
+	}
+	return role, nil
+}
+
+func (s *roleService) CreateRole(ctx context.Context, role *model.Role) error {
+	role.Name = strings.TrimSpace(strings.ToLower(role.Name))
+	if role.Name == "" {
+		return apperror.Validation("role name is required")
+	}
+
+	if err := validatePermissions(role.Permissions); err != nil {
+		return err
+	}
+
+	// Check if role already exists
+	_, err := s.roleRepo.FindByName(ctx, role.Name)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing role: %w", err)
+	}
+	if err == nil {
+		return apperror.Conflict("role already exists")
+	}
+
+	// Only built-in roles are system roles
+	role.IsSystem = false
+
+	return s.roleRepo.Create(ctx, role)
+}
+
+func (s *roleService) UpdateRole(ctx context.Context, role *model.Role) error {
+	existingRole, err := s.roleRepo.FindByID(ctx, role.ID)
+	if err != nil {
+		return apperror.IfNotFound(err, errRoleNotFound)
+	}
+
+	if err := validatePermissions(role.Permissions); err != nil {
+		return err
+	}
+
+	// Role names are referenced by users and tokens, so they cannot change
+	existingRole.Description = role.Description
+	existingRole.Permissions = role.Permissions
+
+	// The admin role always keeps every permission
+	if existingRole.Name == string(model.UserRoleAdmin) {
+		existingRole.Permissions = model.AllPermissions
+	}
+
+	if err := s.roleRepo.Update(ctx, existingRole); err != nil {
+		return err
+	}
+
+	*role = *existingRole
+	return nil
+}
+
+func (s *roleService) DeleteRole(ctx context.Context, id uint) error {
+	role, err := s.roleRepo.FindByID(ctx, id)
+	if err != nil {
+		return apperror.IfNotFound(err, errRoleNotFound)
+	}
+
+	if role.IsSystem {
+		return apperror.Forbidden("cannot delete a system role")
+	}
+
+	// Check for users still holding the role
+	count, err := s.roleRepo.CountUsers(ctx, role.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return apperror.Conflict("cannot delete role assigned to users")
+	}
+
+	return s.roleRepo.Delete(ctx, id)
+}
+
+func (s *roleService) AssignRole(ctx context.Context, userID uint, roleName string) (*model.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, apperror.IfNotFound(err, ErrUserNotFound)
+	}
+
+	roleName = strings.TrimSpace(strings.ToLower(roleName))
+	if _, err := s.findRole(ctx, roleName); err != nil {
+		return nil, apperror.IfNotFound(err, apperror.Validation("unknown role"))
+	}
+
+	user.Role = model.UserRole(roleName)
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	return user, nil
+}
+
+// HasPermission reports whether the named role grants the permission.
+// Admins are granted every permission.
+func (s *roleService) HasPermission(ctx context.Context, roleName string, permission model.Permission) (bool, error) {
+	if roleName == string(model.UserRoleAdmin) {
+		return true, nil
+	}
+
+	role, err := s.findRole(ctx, roleName)
+	if err != nil {
+		if errors.Is(err, errRoleNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return role.HasPermission(permission), nil
+}
+
+var errRoleNotFound = apperror.NotFound("role not found")
+
+// findRole loads a role by name, falling back to the built-in defaults for
+// system roles that have not been stored yet
+func (s *roleService) findRole(ctx context.Context, name string) (*model.Role, error) {
+	role, err := s.roleRepo.FindByName(ctx, name)
+	if err == nil {
+		return role, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find role: %w", err)
+	}
+
+	if permissions, ok := model.DefaultRolePermissions[model.UserRole(name)]; ok {
+		return &model.Role{Name: name, Permissions: permissions, IsSystem: true}, nil
+	}
+
+	return nil, errRoleNotFound
+}
+
+// validatePermissions rejects unknown permission names
+func validatePermissions(permissions []model.Permission) error {
+	for _, permission := range permissions {
+		if !model.IsValidPermission(permission) {
+			return apperror.Validation(fmt.Sprintf("unknown permission: %s", permission))
+		}
+	}
+	return nil
+}
@@ -1,19 +1,21 @@
 package service
 
 import (
-	"errors"
+	"context"
+
+	"service-booking/internal/apperror"
 	"service-booking/internal/model"
 	"service-booking/internal/repository"
 )
 
 type ServiceService interface {
-	GetServices(page, limit int, filters map[string]interface{}) ([]model.Service, int64, error)
-	GetServiceByID(id uint) (*model.Service, error)
-	CreateService(service *model.Service) error
-	UpdateService(service *model.Service) error
-	DeleteService(id uint) error
-	GetServicesByCategory(categoryID uint, page, limit int) ([]model.Service, int64, error)
-	GetFeaturedServices(limit int) ([]model.Service, error)
+	GetServices(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.Service, int64, error)
+	GetServiceByID(ctx context.Context, id uint) (*model.Service, error)
+	CreateService(ctx context.Context, service *model.Service) error
+	UpdateService(ctx context.Context, service *model.Service) error
+	DeleteService(ctx context.Context, id uint) error
+	GetServicesByCategory(ctx context.Context, categoryID uint, page, limit int) ([]model.Service, int64, error)
+	GetFeaturedServices(ctx context.Context, limit int) ([]model.Service, error)
 }
 
 type serviceService struct {
@@ -31,19 +33,23 @@ func NewServiceService(
 	}
 }
 
-func (s *serviceService) GetServices(page, limit int, filters map[string]interface{}) ([]model.Service, int64, error) {
-	return s.serviceRepo.FindAll(page, limit, filters)
+func (s *serviceService) GetServices(ctx context.Context, page, limit int, filters map[string]interface{}) ([]model.Service, int64, error) {
+	return s.serviceRepo.FindAll(ctx, page, limit, filters)
 }
 
-func (s *serviceService) GetServiceByID(id uint) (*model.Service, error) {
-	return s.serviceRepo.FindByID(id)
+func (s *serviceService) GetServiceByID(ctx context.Context, id uint) (*model.Service, error) {
+	service, err := s.serviceRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, apperror.IfNotFound(err, apperror.NotFound("service not found"))
+	}
+	return service, nil
 }
 
-func (s *serviceService) CreateService(service *model.Service) error {
+func (s *serviceService) CreateService(ctx context.Context, service *model.Service) error {
 	// Validate category
-	_, err := s.categoryRepo.FindByID(service.CategoryID)
+	_, err := s.categoryRepo.FindByID(ctx, service.CategoryID)
 	if err != nil {
-		return errors.New("invalid category")
+		return apperror.IfNotFound(err, apperror.Validation("invalid category"))
 	}
 
 	// Set default values
@@ -51,36 +57,36 @@ func (s *serviceService) CreateService(service *model.Service) error {
 		service.IsActive = true
 	}
 
-	return s.serviceRepo.Create(service)
+	return s.serviceRepo.Create(ctx, service)
 }
 
-func (s *serviceService) UpdateService(service *model.Service) error {
+func (s *serviceService) UpdateService(ctx context.Context, service *model.Service) error {
 	// Validate category if changed
 	if service.CategoryID > 0 {
-		_, err := s.categoryRepo.FindByID(service.CategoryID)
+		_, err := s.categoryRepo.FindByID(ctx, service.CategoryID)
 		if err != nil {
-			return errors.New("invalid category")
+			return apperror.IfNotFound(err, apperror.Validation("invalid category"))
 		}
 	}
 
-	return s.serviceRepo.Update(service)
+	return s.serviceRepo.Update(ctx, service)
 }
 
-func (s *serviceService) DeleteService(id uint) error {
+func (s *serviceService) DeleteService(ctx context.Context, id uint) error {
 	// Optional: Check if service has any active bookings before deletion
-	return s.serviceRepo.Delete(id)
+	return s.serviceRepo.Delete(ctx, id)
 }
 
-func (s *serviceService) GetServicesByCategory(categoryID uint, page, limit int) ([]model.Service, int64, error) {
+func (s *serviceService) GetServicesByCategory(ctx context.Context, categoryID uint, page, limit int) ([]model.Service, int64, error) {
 	// Validate category
-	_, err := s.categoryRepo.FindByID(categoryID)
+	_, err := s.categoryRepo.FindByID(ctx, categoryID)
 	if err != nil {
-		return nil, 0, errors.New("invalid category")
+		return nil, 0, apperror.IfNotFound(err, apperror.NotFound("category not found"))
 	}
 
-	return s.serviceRepo.FindByCategory(categoryID, page, limit)
+	return s.serviceRepo.FindByCategory(ctx, categoryID, page, limit)
 }
 
-func (s *serviceService) GetFeaturedServices(limit int) ([]model.Service, error) {
-	return s.serviceRepo.FindFeaturedServices(limit)
+func (s *serviceService) GetFeaturedServices(ctx context.Context, limit int) ([]model.Service, error) {
+	return s.serviceRepo.FindFeaturedServices(ctx, limit)
 }
\ No newline at end of file
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+	"service-booking/internal/repository"
+	"service-booking/pkg/auth"
+
+	"gorm.io/gorm"
+)
+
+// maxUserAgentLength matches the size of the user_agent column
+const maxUserAgentLength = 512
+
+var (
+	ErrSessionNotFound = apperror.NotFound("session not found")
+	ErrSessionRevoked  = apperror.Unauthorized("session has been revoked or has expired")
+)
+
+// SessionService interface defines the methods for tracking logins per device
+type SessionService interface {
+	StartSession(ctx context.Context, user *model.User, userAgent, ipAddress string, opts ...auth.TokenOption) (accessToken, refreshToken string, err error)
+	RefreshSession(ctx context.Context, claims *auth.JWTClaims, userAgent, ipAddress string) (accessToken, refreshToken string, err error)
+	GetSessions(ctx context.Context, userID uint) ([]model.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uint) error
+}
+
+// sessionService implements SessionService
+type sessionService struct {
+	sessionRepo repository.SessionRepository
+	userRepo    repository.UserRepository
+	tokens      *auth.TokenService
+}
+
+// NewSessionService creates a new instance of SessionService
+func NewSessionService(sessionRepo repository.SessionRepository, userRepo repository.UserRepository, tokens *auth.TokenService) SessionService {
+	return &sessionService{
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+		tokens:      tokens,
+	}
+}
+
+// StartSession records a new session for the device and issues tokens bound to it
+func (s *sessionService) StartSession(ctx context.Context, user *model.User, userAgent, ipAddress string, opts ...auth.TokenOption) (string, string, error) {
+	tokenID, err := randomHex(16)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+
+	session, err := s.createSession(ctx, user.ID, userAgent, ipAddress, tokenID)
+	if err != nil {
+		return "", "", err
+	}
+
+	opts = append(opts, auth.WithSessionID(session.ID), auth.WithTokenID(tokenID))
+	return s.tokens.GenerateAllTokens(user.ID, user.Email, user.Name, string(user.Role), opts...)
+}
+
+// RefreshSession issues new tokens for a valid refresh token, refusing tokens
+// of revoked or expired sessions. Every refresh token can be used once; a
+// token that was already replaced ends the session, as it was likely
+// stolen. The user is reloaded so role changes apply from the next refresh.
+func (s *sessionService) RefreshSession(ctx context.Context, claims *auth.JWTClaims, userAgent, ipAddress string) (string, string, error) {
+	userID, err := strconv.ParseUint(claims.UserID, 10, 64)
+	if err != nil {
+		return "", "", apperror.Unauthorized("invalid user ID")
+	}
+
+	sessionID, err := strconv.ParseUint(claims.SessionID, 10, 64)
+	if err != nil || claims.ID == "" {
+		return "", "", ErrSessionRevoked
+	}
+
+	session, err := s.sessionRepo.FindByID(ctx, uint(sessionID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", "", ErrSessionRevoked
+		}
+		return "", "", fmt.Errorf("failed to find session: %w", err)
+	}
+	if session.UserID != uint(userID) || !session.IsActive() {
+		return "", "", ErrSessionRevoked
+	}
+
+	user, err := s.userRepo.FindByID(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", "", ErrSessionRevoked
+		}
+		return "", "", fmt.Errorf("failed to find user: %w", err)
+	}
+
+	tokenID, err := randomHex(16)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+
+	now := time.Now()
+	session.RefreshTokenID = tokenID
+	session.UserAgent = truncateUserAgent(userAgent)
+	session.IPAddress = ipAddress
+	session.LastRefreshedAt = &now
+	session.ExpiresAt = now.Add(s.tokens.Config().RefreshTokenDuration)
+	rotated, err := s.sessionRepo.RotateRefreshToken(ctx, session, claims.ID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to update session: %w", err)
+	}
+	if !rotated {
+		if err := s.RevokeSession(ctx, session.UserID, session.ID); err != nil {
+			return "", "", err
+		}
+		return "", "", ErrSessionRevoked
+	}
+
+	// The second factor only carries over while the user is still enrolled
+	return s.tokens.GenerateAllTokens(
+		user.ID,
+		user.Email,
+		user.Name,
+		string(user.Role),
+		auth.WithMFA(claims.MFA && user.TOTPEnabled),
+		auth.WithSessionID(session.ID),
+		auth.WithTokenID(tokenID),
+	)
+}
+
+// GetSessions returns the active sessions of a user
+func (s *sessionService) GetSessions(ctx context.Context, userID uint) ([]model.Session, error) {
+	return s.sessionRepo.FindActiveByUser(ctx, userID)
+}
+
+// RevokeSession ends a session of the user so its refresh token stops working
+func (s *sessionService) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	session, err := s.sessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("failed to find session: %w", err)
+	}
+
+	// Sessions of other users are reported as missing
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	session.RevokedAt = &now
+	if err := s.sessionRepo.Update(ctx, session); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+func (s *sessionService) createSession(ctx context.Context, userID uint, userAgent, ipAddress, refreshTokenID string) (*model.Session, error) {
+	session := &model.Session{
+		UserID:         userID,
+		UserAgent:      truncateUserAgent(userAgent),
+		IPAddress:      ipAddress,
+		RefreshTokenID: refreshTokenID,
+		ExpiresAt:      time.Now().Add(s.tokens.Config().RefreshTokenDuration),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return session, nil
+}
+
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > maxUserAgentLength {
+		return userAgent[:maxUserAgentLength]
+	}
+	return userAgent
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"service-booking/internal/apperror"
+	"service-booking/internal/model"
+	"service-booking/internal/repository"
+	"service-booking/pkg/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// loginStateTTL bounds how long a user may take at the identity provider
+const loginStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownProvider       = apperror.NotFound("unknown identity provider")
+	ErrInvalidLoginState     = apperror.Validation("invalid or expired login state")
+	ErrSocialEmailUnverified = apperror.Forbidden("identity provider did not verify the email address")
+	ErrSocialLinkRequired    = apperror.Conflict("an account with this email address exists; log in and link the identity provider from your profile").WithCode("social_link_required")
+	ErrIdentityLinked        = apperror.Conflict("this identity provider account is linked to another user")
+)
+
+// LoginState is kept by the client between starting and completing a social
+// login. It binds the callback to the browser that started the flow.
+type LoginState struct {
+	Provider     string `json:"provider"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	// LinkUserID is the signed-in user linking the provider account, or zero
+	// for a login
+	LinkUserID uint `json:"link_user_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// SocialAuthService interface defines the methods for OpenID Connect login
+type SocialAuthService interface {
+	Providers() []string
+	BeginLogin(provider string) (string, *LoginState, error)
+	BeginLink(provider string, userID uint) (string, *LoginState, error)
+	CompleteLogin(ctx context.Context, provider, code string, state *LoginState) (*model.User, error)
+	CompleteLink(ctx context.Context, provider, code string, state *LoginState) error
+	EncodeLoginState(state *LoginState) (string, error)
+	DecodeLoginState(token string) (*LoginState, error)
+}
+
+// socialAuthService implements SocialAuthService
+type socialAuthService struct {
+	userRepo     repository.UserRepository
+	identityRepo repository.UserIdentityRepository
+	clients      map[string]*oidc.Client
+	stateSecret  []byte
+}
+
+// NewSocialAuthService creates a new instance of SocialAuthService
+func NewSocialAuthService(
+	userRepo repository.UserRepository,
+	identityRepo repository.UserIdentityRepository,
+	clients map[string]*oidc.Client,
+	stateSecret []byte,
+) SocialAuthService {
+	return &socialAuthService{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		clients:      clients,
+		stateSecret:  stateSecret,
+	}
+}
+
+// Providers returns the names of the configured providers
+func (s *socialAuthService) Providers() []string {
+	providers := make([]string, 0, len(s.clients))
+	for name := range s.clients {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// BeginLogin returns the provider authorization URL and the state the client
+// must present when completing the login
+func (s *socialAuthService) BeginLogin(provider string) (string, *LoginState, error) {
+	client, ok := s.clients[provider]
+	if !ok {
+		return "", nil, ErrUnknownProvider
+	}
+
+	state := &LoginState{Provider: provider}
+	var err error
+	if state.State, err = oidc.RandomString(24); err != nil {
+		return "", nil, fmt.Errorf("failed to generate state: %w", err)
+	}
+	if state.Nonce, err = oidc.RandomString(24); err != nil {
+		return "", nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if state.CodeVerifier, err = oidc.RandomString(48); err != nil {
+		return "", nil, fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+
+	authURL, err := client.AuthCodeURL(state.State, state.Nonce, oidc.CodeChallengeS256(state.CodeVerifier))
+	if err != nil {
+		return "", nil, apperror.Unavailable("identity provider is unavailable", err)
+	}
+
+	return authURL, state, nil
+}
+
+// BeginLink starts a login that links the provider account to a signed-in user
+func (s *socialAuthService) BeginLink(provider string, userID uint) (string, *LoginState, error) {
+	authURL, state, err := s.BeginLogin(provider)
+	if err != nil {
+		return "", nil, err
+	}
+	state.LinkUserID = userID
+	return authURL, state, nil
+}
+
+// CompleteLogin exchanges the authorization code and returns the linked user,
+// linking or creating an account by verified email on first login. Accounts
+// whose email was never verified, and accounts of roles that require a second
+// factor, are only linked from a signed-in session with BeginLink.
+func (s *socialAuthService) CompleteLogin(ctx context.Context, provider, code string, state *LoginState) (*model.User, error) {
+	if state != nil && state.LinkUserID != 0 {
+		return nil, ErrInvalidLoginState
+	}
+	claims, err := s.verifyIdentity(provider, code, state)
+	if err != nil {
+		return nil, err
+	}
+
+	// Returning users are found by their provider account
+	identity, err := s.identityRepo.FindByProviderSubject(ctx, provider, claims.Subject)
+	if err == nil {
+		return &identity.User, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+
+	// Linking by email is only safe when the provider verified it
+	email := strings.TrimSpace(strings.ToLower(claims.Email))
+	if email == "" || !claims.EmailVerified {
+		return nil, ErrSocialEmailUnverified
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user != nil {
+		// Anyone can register an unverified email, and a provider account
+		// must not be enough to take over a privileged account
+		if user.EmailVerifiedAt == nil || user.Role.RequiresMFA() {
+			return nil, ErrSocialLinkRequired
+		}
+	} else {
+		name := claims.Name
+		if name == "" {
+			name = email
+		}
+
+		// Social accounts have no password and cannot use /auth/login
+		verifiedAt := time.Now()
+		user = &model.User{
+			Name:            name,
+			Email:           email,
+			EmailVerifiedAt: &verifiedAt,
+			Role:            model.UserRoleUser,
+		}
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+	}
+
+	if err := s.link(ctx, user.ID, provider, claims); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// CompleteLink exchanges the authorization code and links the provider
+// account to the user who started the flow with BeginLink
+func (s *socialAuthService) CompleteLink(ctx context.Context, provider, code string, state *LoginState) error {
+	if state == nil || state.LinkUserID == 0 {
+		return ErrInvalidLoginState
+	}
+	claims, err := s.verifyIdentity(provider, code, state)
+	if err != nil {
+		return err
+	}
+
+	identity, err := s.identityRepo.FindByProviderSubject(ctx, provider, claims.Subject)
+	if err == nil {
+		if identity.UserID != state.LinkUserID {
+			return ErrIdentityLinked
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to find identity: %w", err)
+	}
+
+	if _, err := s.userRepo.FindByID(ctx, state.LinkUserID); err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	return s.link(ctx, state.LinkUserID, provider, claims)
+}
+
+// verifyIdentity exchanges the authorization code and verifies the ID token
+func (s *socialAuthService) verifyIdentity(provider, code string, state *LoginState) (*oidc.IDTokenClaims, error) {
+	client, ok := s.clients[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	if state == nil || state.Provider != provider {
+		return nil, ErrInvalidLoginState
+	}
+
+	tokens, err := client.Exchange(code, state.CodeVerifier)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.KindUnauthorized, "could not verify identity", err)
+	}
+
+	claims, err := client.VerifyIDToken(tokens.IDToken, state.Nonce)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.KindUnauthorized, "could not verify identity", err)
+	}
+	return claims, nil
+}
+
+// link records the provider account of a user
+func (s *socialAuthService) link(ctx context.Context, userID uint, provider string, claims *oidc.IDTokenClaims) error {
+	identity := &model.UserIdentity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    strings.TrimSpace(strings.ToLower(claims.Email)),
+	}
+	if err := s.identityRepo.Create(ctx, identity); err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+// EncodeLoginState signs the login state so it can be stored by the client
+func (s *socialAuthService) EncodeLoginState(state *LoginState) (string, error) {
+	state.ExpiresAt = jwt.NewNumericDate(time.Now().Add(loginStateTTL))
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, state)
+	return token.SignedString(s.stateSecret)
+}
+
+// DecodeLoginState verifies a login state produced by EncodeLoginState
+func (s *socialAuthService) DecodeLoginState(token string) (*LoginState, error) {
+	state := &LoginState{}
+	_, err := jwt.ParseWithClaims(token, state, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.stateSecret, nil
+	}, jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrInvalidLoginState
+	}
+
+	return state, nil
+}
@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// querySpanKey stores the span of a query on the GORM statement
+const querySpanKey = "tracing:query_span"
+
+// InstrumentDB records a span for every query on the connection, as a child
+// of the span in the query's context. system is the database, e.g. mysql.
+func (t *Tracing) InstrumentDB(conn *gorm.DB, system string) error {
+	tracer := t.Tracer()
+
+	callbacks := conn.Callback()
+	hooks := []struct {
+		operation     string
+		before, after callbackRegistrar
+	}{
+		{"create", callbacks.Create().Before("gorm:create"), callbacks.Create().After("gorm:create")},
+		{"query", callbacks.Query().Before("gorm:query"), callbacks.Query().After("gorm:query")},
+		{"update", callbacks.Update().Before("gorm:update"), callbacks.Update().After("gorm:update")},
+		{"delete", callbacks.Delete().Before("gorm:delete"), callbacks.Delete().After("gorm:delete")},
+		{"row", callbacks.Row().Before("gorm:row"), callbacks.Row().After("gorm:row")},
+		{"raw", callbacks.Raw().Before("gorm:raw"), callbacks.Raw().After("gorm:raw")},
+	}
+
+	for _, hook := range hooks {
+		if err := hook.before.Register("tracing:before_"+hook.operation, startSpan(tracer, system, hook.operation)); err != nil {
+			return err
+		}
+		if err := hook.after.Register("tracing:after_"+hook.operation, endSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callbackRegistrar registers a GORM callback at a position in a chain
+type callbackRegistrar interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// startSpan starts the span of a query
+func startSpan(tracer trace.Tracer, system, operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			return
+		}
+
+		_, span := tracer.Start(ctx, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", system),
+				attribute.String("db.operation.name", operation),
+			),
+		)
+		db.InstanceSet(querySpanKey, span)
+	}
+}
+
+// endSpan ends the span of a query with its SQL and outcome. The SQL has
+// placeholders instead of values, so no personal data is exported.
+func endSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(querySpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.collection.name", db.Statement.Table),
+		attribute.String("db.query.text", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
@@ -0,0 +1,110 @@
+// Package tracing records OpenTelemetry traces of HTTP requests and
+// database queries. Every App has its own tracer provider, so instances in
+// one process do not share exporters.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName names the tracer of the application
+const instrumentationName = "service-booking"
+
+// Config selects where spans are exported
+type Config struct {
+	// Exporter is none, stdout or otlp
+	Exporter string
+	// Endpoint is the host:port of the OTLP/HTTP collector
+	Endpoint string
+	// Insecure sends spans to the collector without TLS
+	Insecure bool
+	// ServiceName identifies the application in traces
+	ServiceName string
+	// SampleRatio is the share of new traces that are recorded. Requests
+	// with a sampled parent are always recorded.
+	SampleRatio float64
+}
+
+// Tracing holds the tracer provider of one application instance
+type Tracing struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	shutdown   func(context.Context) error
+}
+
+// New creates the tracer provider. With the none exporter spans are not
+// recorded, but incoming trace context is still propagated.
+func New(ctx context.Context, cfg Config) (*Tracing, error) {
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("invalid tracing sample ratio %g: must be between 0 and 1", cfg.SampleRatio)
+	}
+
+	t := &Tracing{
+		// W3C trace-context and baggage headers
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		shutdown:   func(context.Context) error { return nil },
+	}
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch cfg.Exporter {
+	case "none", "":
+		t.provider = noop.NewTracerProvider()
+		return t, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("invalid tracing exporter %q: must be none, stdout or otlp", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	t.provider = provider
+	t.shutdown = provider.Shutdown
+	return t, nil
+}
+
+// Tracer returns the tracer of the application
+func (t *Tracing) Tracer() trace.Tracer {
+	return t.provider.Tracer(instrumentationName)
+}
+
+// Propagator reads and writes the trace context of requests
+func (t *Tracing) Propagator() propagation.TextMapPropagator {
+	return t.propagator
+}
+
+// Shutdown exports the buffered spans and stops the exporter
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	return t.shutdown(ctx)
+}
@@ -1,14 +1,19 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"os"
 	"fmt"
+	"strconv"
+	"time"
 	"github.com/joho/godotenv"
 	"github.com/gin-gonic/gin"
 
 	"service-booking/config"
 	"service-booking/db"
+	"service-booking/internal/app"
+	"service-booking/pkg/logging"
 	"service-booking/routes"
 )
 
@@ -26,41 +31,94 @@ func main() {
 	setGinMode(env)
 
 	// Load configuration
-	if err := config.LoadConfig(); err != nil {
-		log.Fatalf("Error loading config: %v", err)
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("Error loading config", err)
 	}
 
-	// Register the MySQL database
-	db.RegisterMySQL()
+	// Log as structured JSON, or text, from here on
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
+	slog.SetDefault(logger)
 
-	// Defer database connection closure
-	sqlDB, err := db.GetDB().DB()
+	// Open the database
+	conn, err := db.Open(cfg)
 	if err != nil {
-		log.Fatalf("Failed to get database connection: %v", err)
+		fatal("Failed to connect to database", err)
+	}
+
+	// Apply or inspect schema migrations instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrate(conn, cfg, os.Args[2:])
+		db.Close(conn)
+		if err != nil {
+			fatal("Migration failed", err)
+		}
+		return
 	}
-	defer sqlDB.Close()
 
-	// Set up routes and start the server
-	router := routes.SetupRouter()
+	// In-memory databases start empty on every run
+	if cfg.Database.Driver == "memory" {
+		if err := runMigrate(conn, cfg, []string{"up"}); err != nil {
+			fatal("Migration failed", err)
+		}
+	}
+
+	// Wire the application and set up routes
+	application, err := app.New(cfg, conn)
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
+	router, err := routes.SetupRouter(application)
+	if err != nil {
+		fatal("Failed to set up routes", err)
+	}
 
 	// Determine port (environment variable takes precedence)
 	port := os.Getenv("HTTP_PORT")
 	if port == "" {
-		port = config.String("httpport")
+		port = strconv.Itoa(cfg.HttpPort)
 	}
 
 	// Prepare server address
 	addr := ":" + port
 
+	timeouts, err := parseServerTimeouts(cfg)
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
+
 	// Log server startup
-	log.Printf("Starting %s server on port %s...", env, port)
+	slog.Info("Starting server", "environment", env, "port", port)
 
-	// Run the server
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	// Run the server until SIGTERM, then close the database pool once
+	// in-flight requests are done
+	err = serve(addr, router, timeouts)
+	flushTraces(application, timeouts.shutdown)
+	db.Close(conn)
+	if err != nil {
+		fatal("Server failed", err)
 	}
 }
 
+// flushTraces exports the spans still buffered when the server stops
+func flushTraces(application *app.App, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := application.Tracing.Shutdown(ctx); err != nil {
+		slog.Error("Failed to export traces", "error", err)
+	}
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
 // loadEnvFile loads environment variables from .env file
 func loadEnvFile(env string) {
 	// Try to load environment-specific .env file first
@@ -73,12 +131,12 @@ func loadEnvFile(env string) {
 	if err := godotenv.Load(envFile); err != nil {
 		// Only log if it's not just that the file doesn't exist
 		if !os.IsNotExist(err) {
-			log.Printf("Error loading %s file: %v", envFile, err)
+			slog.Warn("Error loading env file", "file", envFile, "error", err)
 		}
 		
 		// Attempt to load default .env
 		if err := godotenv.Load(); err != nil {
-			log.Println("No .env file found")
+			slog.Info("No .env file found")
 		}
 	}
 }
@@ -1,14 +1,14 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"os"
+	"log/slog"
 	"strconv"
 	"time"
 
-	"service-booking/config"
-
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -19,6 +19,8 @@ type TokenType string
 const (
 	AccessToken  TokenType = "access"
 	RefreshToken TokenType = "refresh"
+	MFAToken     TokenType = "mfa" // Short-lived token for the second login step
+	GuestToken   TokenType = "guest" // Proof of a verified phone number for guest bookings
 )
 
 // JWTClaims custom claims structure
@@ -28,90 +30,85 @@ type JWTClaims struct {
 	Email  string `json:"email"`
 	Name   string `json:"name"`
 	Type   string `json:"type"` // New field to distinguish token type
+	MFA    bool   `json:"mfa,omitempty"` // Whether the login completed a second factor
+	SessionID string `json:"sid,omitempty"` // Session the token belongs to
 	jwt.RegisteredClaims
 }
 
+// TokenOption customizes the claims of generated tokens
+type TokenOption func(*JWTClaims)
+
+// WithMFA marks tokens as issued after a completed second factor
+func WithMFA(mfa bool) TokenOption {
+	return func(claims *JWTClaims) {
+		claims.MFA = mfa
+	}
+}
+
 // TokenConfig holds JWT configuration
 type TokenConfig struct {
 	SecretKey             string
 	AccessTokenDuration   time.Duration
 	RefreshTokenDuration  time.Duration
+	MFATokenDuration      time.Duration
+	GuestTokenDuration    time.Duration
 }
 
-// DefaultTokenConfig provides default JWT configuration
-func DefaultTokenConfig() TokenConfig {
-	secretKey := getSecretKey()
-	return TokenConfig{
-		SecretKey:             secretKey,
-		AccessTokenDuration:   getAccessTokenDuration(),
-		RefreshTokenDuration:  getRefreshTokenDuration(),
-	}
+// TokenService issues and validates the tokens of one application
+// instance, so instances with different secrets can share a process
+type TokenService struct {
+	config TokenConfig
 }
 
-// getSecretKey retrieves the secret key and token durations from environment or config
-func getSecretKey() string {
-	// First check environment variable
-	if secretKey := os.Getenv("JWT_SECRET_KEY"); secretKey != "" {
-		return secretKey
+// NewTokenService creates a TokenService, filling in missing durations
+func NewTokenService(config TokenConfig) *TokenService {
+	if config.SecretKey == "" {
+		// Tokens signed with a generated key do not survive a restart and
+		// are not accepted by other replicas
+		config.SecretKey = generateFallbackSecretKey()
 	}
-
-	// Fallback to config file
-	secretKey := config.String("jwt_secret_key")
-	if secretKey == "" {
-		// Generate a fallback secret key (DO NOT USE IN PRODUCTION)
-		return generateFallbackSecretKey()
+	if config.AccessTokenDuration <= 0 {
+		config.AccessTokenDuration = 24 * time.Hour
 	}
-	return secretKey
-}
-
-// getAccessTokenDuration retrieves access token duration from config
-func getAccessTokenDuration() time.Duration {
-	// Check environment variable first
-	
-
-	// Fallback to config file
-	accessDurationStr := config.String("access_token_duration")
-	if accessDurationStr != "" {
-		if duration, err := time.ParseDuration(accessDurationStr); err == nil {
-			return duration
-		}
+	if config.RefreshTokenDuration <= 0 {
+		config.RefreshTokenDuration = 7 * 24 * time.Hour
 	}
-
-	// Default to 24 hours if no configuration is found
-	return 24 * time.Hour
-}
-
-// getRefreshTokenDuration retrieves refresh token duration from config
-func getRefreshTokenDuration() time.Duration {
-
-	// Fallback to config file
-	refreshDurationStr := config.String("refresh_token_duration")
-	if refreshDurationStr != "" {
-		if duration, err := time.ParseDuration(refreshDurationStr); err == nil {
-			return duration
-		}
+	if config.MFATokenDuration <= 0 {
+		config.MFATokenDuration = 5 * time.Minute
 	}
-
-	// Default to 7 days if no configuration is found
-	return 7 * 24 * time.Hour
+	if config.GuestTokenDuration <= 0 {
+		config.GuestTokenDuration = 30 * time.Minute
+	}
+	return &TokenService{config: config}
 }
 
+// Config returns the configuration of the token service
+func (s *TokenService) Config() TokenConfig {
+	return s.config
+}
 
-
-// generateFallbackSecretKey creates a fallback secret key
+// generateFallbackSecretKey creates a random secret key for this process
 func generateFallbackSecretKey() string {
-	fmt.Println("WARNING: Using generated fallback secret key. Set JWT_SECRET_KEY in production!")
-	return "fallback-secret-key-change-immediately"
+	slog.Warn("Using a generated secret key. Set JWT_SECRET_KEY to keep tokens valid across restarts")
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic("auth: failed to generate secret key: " + err.Error())
+	}
+	return hex.EncodeToString(buf)
 }
 
 // GenerateToken creates a JWT token with specified type and duration
-func GenerateToken(userID uint, email, name, role string, tokenType TokenType, config TokenConfig) (string, error) {
+func GenerateToken(userID uint, email, name, role string, tokenType TokenType, config TokenConfig, opts ...TokenOption) (string, error) {
 	var expirationTime time.Time
 	switch tokenType {
 	case AccessToken:
 		expirationTime = time.Now().Add(config.AccessTokenDuration)
 	case RefreshToken:
 		expirationTime = time.Now().Add(config.RefreshTokenDuration)
+	case MFAToken:
+		expirationTime = time.Now().Add(config.MFATokenDuration)
+	case GuestToken:
+		expirationTime = time.Now().Add(config.GuestTokenDuration)
 	default:
 		return "", errors.New("invalid token type")
 	}
@@ -129,20 +126,37 @@ func GenerateToken(userID uint, email, name, role string, tokenType TokenType, c
 		},
 	}
 
+	for _, opt := range opts {
+		opt(&claims)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(config.SecretKey))
 }
 
-// GenerateAllTokens creates both access and refresh tokens
-func GenerateAllTokens(userID uint, email, name, role string) (accessToken, refreshToken string, err error) {
-	config := DefaultTokenConfig()
+// WithSessionID ties tokens to a session so they can be revoked per device
+func WithSessionID(sessionID uint) TokenOption {
+	return func(claims *JWTClaims) {
+		claims.SessionID = strconv.FormatUint(uint64(sessionID), 10)
+	}
+}
+
+// WithTokenID sets the ID of the tokens, which lets a session tell its
+// current refresh token from the ones it replaced
+func WithTokenID(id string) TokenOption {
+	return func(claims *JWTClaims) {
+		claims.ID = id
+	}
+}
 
-	accessToken, err = GenerateToken(userID, email, name, role, AccessToken, config)
+// GenerateAllTokens creates both access and refresh tokens
+func (s *TokenService) GenerateAllTokens(userID uint, email, name, role string, opts ...TokenOption) (accessToken, refreshToken string, err error) {
+	accessToken, err = GenerateToken(userID, email, name, role, AccessToken, s.config, opts...)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken, err = GenerateToken(userID, email, name, role, RefreshToken, config)
+	refreshToken, err = GenerateToken(userID, email, name, role, RefreshToken, s.config, opts...)
 	if err != nil {
 		return "", "", err
 	}
@@ -150,16 +164,40 @@ func GenerateAllTokens(userID uint, email, name, role string) (accessToken, refr
 	return accessToken, refreshToken, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
-func ValidateToken(tokenString string) (*JWTClaims, error) {
-	config := DefaultTokenConfig()
+// GenerateMFAToken creates the short-lived challenge token returned by the
+// first login step to users enrolled in multi-factor authentication
+func (s *TokenService) GenerateMFAToken(userID uint, email, name, role string) (string, error) {
+	return GenerateToken(userID, email, name, role, MFAToken, s.config)
+}
+
+// GenerateGuestToken creates a token proving that the guest verified the
+// phone number with a one-time code
+func (s *TokenService) GenerateGuestToken(phone string) (string, error) {
+	return GenerateToken(0, "", "", "", GuestToken, s.config, func(claims *JWTClaims) {
+		claims.Subject = phone
+	})
+}
 
+// ValidateGuestToken returns the verified phone number of a guest token
+func (s *TokenService) ValidateGuestToken(tokenString string) (string, error) {
+	claims, err := s.ValidateToken(tokenString)
+	if err != nil {
+		return "", err
+	}
+	if claims.Type != string(GuestToken) || claims.Subject == "" {
+		return "", errors.New("invalid token type")
+	}
+	return claims.Subject, nil
+}
+
+// ValidateToken validates a JWT token and returns the claims
+func (s *TokenService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		// Validate signing method
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(config.SecretKey), nil
+		return []byte(s.config.SecretKey), nil
 	})
 
 	if err != nil {
@@ -183,41 +221,12 @@ func HashPassword(password string) (string, error) {
 }
 
 // ComparePasswords compares a hashed password with a plain text password
-// In pkg/auth/jwt.go
 func ComparePasswords(hashedPassword, plainPassword string) bool {
-	fmt.Println("")
-    fmt.Println("Comparing Passwords:")
-    fmt.Printf("Stored Hashed Password: %s\n", hashedPassword)
-    fmt.Printf("Plain Password: %s\n", plainPassword)
-
-    // Use bcrypt's built-in comparison method
-    err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(plainPassword))
-    
-    if err != nil {
-        fmt.Printf("Password comparison error: %v\n", err)
-        return false
-    }
-
-    fmt.Println("Password match successful")
-    return true
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(plainPassword)) == nil
 }
 
-// Verify method to test password generation and comparison
+// VerifyPasswordGeneration reports whether plainPassword hashes to
+// hashedPassword
 func VerifyPasswordGeneration(plainPassword, hashedPassword string) bool {
-
-	fmt.Println("verifying....")
-    fmt.Println("Verifying Password Generation:")
-    fmt.Printf("Plain Password: %s\n", plainPassword)
-    fmt.Printf("Stored Hashed Password: %s\n", hashedPassword)
-
-    // Compare the plain password with the stored hash
-    err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(plainPassword))
-    
-    if err != nil {
-        fmt.Printf("Verification failed: %v\n", err)
-        return false
-    }
-
-    fmt.Println("Verification successful")
-    return true
+	return ComparePasswords(hashedPassword, plainPassword)
 }
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// derivedKeyLength is the size of keys derived for HMAC signing
+const derivedKeyLength = 32
+
+// Purposes of the keys derived from the secret key. Every purpose gets its
+// own key, so a value signed for one cannot be passed off as another.
+const (
+	KeyPurposeLoginState  = "oidc-login-state"
+	KeyPurposeBookingLink = "booking-access-link"
+	KeyPurposeIdempotency = "idempotency-response"
+)
+
+// DeriveKey returns the key for a purpose, derived from the secret key with
+// HKDF so the secret itself only signs JWTs
+func (s *TokenService) DeriveKey(purpose string) []byte {
+	key := make([]byte, derivedKeyLength)
+	reader := hkdf.New(sha256.New, []byte(s.config.SecretKey), nil, []byte("service-booking "+purpose))
+	if _, err := io.ReadFull(reader, key); err != nil {
+		// HKDF-SHA256 can produce far more than derivedKeyLength bytes
+		panic("auth: failed to derive key: " + err.Error())
+	}
+	return key
+}
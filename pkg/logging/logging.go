@@ -0,0 +1,110 @@
+// Package logging builds structured slog loggers that redact secrets and
+// personal data, and carries a request-scoped logger in a context.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// redacted replaces the value of secret attributes
+const redacted = "[REDACTED]"
+
+// secretKeys are attribute names whose values are never logged. Keys ending
+// in "_" plus one of them (e.g. refresh_token) are secret too.
+var secretKeys = []string{
+	"password",
+	"token",
+	"secret",
+	"authorization",
+	"cookie",
+	"api_key",
+	"code",
+	"otp",
+	"dsn",
+}
+
+// personalKeys are attribute names whose values are masked
+var personalKeys = []string{
+	"phone",
+	"email",
+}
+
+// New creates a logger writing to w. level is debug, info, warn or error and
+// format is json or text.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var slogLevel slog.Level
+	if err := slogLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       slogLevel,
+		ReplaceAttr: Redact,
+	}
+
+	switch strings.ToLower(format) {
+	case "json", "":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q: must be json or text", format)
+}
+
+// Redact hides the values of secret attributes and masks personal data. It
+// is a slog.HandlerOptions.ReplaceAttr function.
+func Redact(groups []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+
+	if matchesKey(key, secretKeys) {
+		return slog.String(attr.Key, redacted)
+	}
+	if matchesKey(key, personalKeys) {
+		return slog.String(attr.Key, mask(attr.Value.String()))
+	}
+	return attr
+}
+
+// matchesKey reports whether key is one of names or ends in "_" plus one
+func matchesKey(key string, names []string) bool {
+	for _, name := range names {
+		if key == name || strings.HasSuffix(key, "_"+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mask keeps the first and last two characters of a value, and the domain
+// of an email address, e.g. "+8801711111111" becomes "+8**********11"
+func mask(value string) string {
+	if at := strings.LastIndex(value, "@"); at > 0 {
+		return mask(value[:at]) + value[at:]
+	}
+	if len(value) <= 4 {
+		return strings.Repeat("*", len(value))
+	}
+	return value[:2] + strings.Repeat("*", len(value)-4) + value[len(value)-2:]
+}
+
+// contextKey is the key of the logger in a context
+type contextKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger of ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
@@ -0,0 +1,470 @@
+// Package migrate applies versioned SQL migrations to a database.
+//
+// Migrations are read from files named "<version>_<name>.up.sql" with an
+// optional matching "<version>_<name>.down.sql", e.g.
+// "0002_booking_status_histories.up.sql". They are applied in version order
+// and recorded in the schema_migrations table together with a checksum of
+// the up script, so an edited migration is detected instead of silently
+// diverging from databases that already ran it.
+//
+// Statements within a file are separated by a semicolon at the end of a
+// line. MySQL commits DDL statements implicitly, so a migration is marked
+// dirty while it runs; a migration that fails halfway stays dirty and blocks
+// further runs until the database has been repaired by hand.
+//
+// On MySQL a GET_LOCK advisory lock ensures only one replica migrates at a
+// time. SQLite databases belong to a single process and are not locked.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLockTimeout bounds how long a migrator waits for another one to finish
+const DefaultLockTimeout = 5 * time.Minute
+
+var (
+	ErrLocked           = errors.New("another migration is in progress")
+	ErrDirty            = errors.New("database is dirty after a failed migration")
+	ErrChecksumMismatch = errors.New("applied migration has changed")
+	ErrUnknownVersion   = errors.New("applied migration is missing")
+	ErrNoDownMigration  = errors.New("migration cannot be reverted")
+)
+
+// Dialect is the SQL dialect of the database
+type Dialect string
+
+const (
+	MySQL  Dialect = "mysql"
+	SQLite Dialect = "sqlite"
+)
+
+// fileName matches migration files, e.g. 0001_initial_schema.up.sql
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status describes a known migration and whether it has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+	Dirty     bool
+}
+
+// appliedMigration is a row of the schema_migrations table
+type appliedMigration struct {
+	Version   int64
+	Checksum  string
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+	db          *sql.DB
+	migrations  []Migration
+	dialect     Dialect
+	lockName    string
+	lockTimeout time.Duration
+}
+
+// Option configures a Migrator
+type Option func(*Migrator)
+
+// WithDialect sets the SQL dialect of the database, which defaults to MySQL
+func WithDialect(dialect Dialect) Option {
+	return func(m *Migrator) {
+		m.dialect = dialect
+	}
+}
+
+// WithLockName sets the name of the advisory lock, which defaults to
+// "schema_migrations". Databases sharing a server should use distinct names.
+func WithLockName(name string) Option {
+	return func(m *Migrator) {
+		m.lockName = name
+	}
+}
+
+// WithLockTimeout sets how long to wait for another migrator to finish
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// New returns a migrator for the migrations found in fsys
+func New(db *sql.DB, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migrator{
+		db:          db,
+		migrations:  migrations,
+		dialect:     MySQL,
+		lockName:    "schema_migrations",
+		lockTimeout: DefaultLockTimeout,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// Load reads the migrations in the root of fsys, ordered by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+			migration.Checksum = checksum(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies all pending migrations and returns the ones it applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// the ones it reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration and whether it has been applied. It
+// only reads the database, so none are applied before schema_migrations
+// has been created.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	exists, err := m.tableExists(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	done := map[int64]appliedMigration{}
+	if exists {
+		if done, err = appliedMigrations(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if row, ok := done[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Dirty = row.Dirty
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// withLock runs fn on a connection holding the advisory lock. GET_LOCK is
+// bound to the session, so every statement must run on the same connection.
+// SQLite databases are not locked.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	if m.dialect == SQLite {
+		if err := ensureTable(ctx, conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	}
+
+	var acquired sql.NullInt64
+	timeout := int(m.lockTimeout.Seconds())
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", m.lockName, timeout).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		return ErrLocked
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", m.lockName)
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// verify checks the applied migrations against the known ones
+func (m *Migrator) verify(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	done, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, row := range done {
+		if row.Dirty {
+			return nil, fmt.Errorf("%w: version %d", ErrDirty, version)
+		}
+		migration, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: version %d", ErrUnknownVersion, version)
+		}
+		if migration.Checksum != row.Checksum {
+			return nil, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, version, migration.Name)
+		}
+	}
+	return done, nil
+}
+
+// apply runs the up script of a migration and records it
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if _, err := conn.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, name, checksum, dirty, applied_at) VALUES (?, ?, ?, ?, ?)",
+		migration.Version, migration.Name, migration.Checksum, true, time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+
+	if err := execScript(ctx, conn, migration.Up); err != nil {
+		return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := conn.ExecContext(ctx,
+		"UPDATE schema_migrations SET dirty = ? WHERE version = ?", false, migration.Version,
+	); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+// revert runs the down script of a migration and forgets it
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("%w: %d_%s", ErrNoDownMigration, migration.Version, migration.Name)
+	}
+
+	if _, err := conn.ExecContext(ctx,
+		"UPDATE schema_migrations SET dirty = ? WHERE version = ?", true, migration.Version,
+	); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+
+	if err := execScript(ctx, conn, migration.Down); err != nil {
+		return fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := conn.ExecContext(ctx,
+		"DELETE FROM schema_migrations WHERE version = ?", migration.Version,
+	); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+// ensureTable creates the schema_migrations table if needed
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version BIGINT NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  checksum CHAR(64) NOT NULL,
+  dirty TINYINT(1) NOT NULL DEFAULT 0,
+  applied_at DATETIME NOT NULL,
+  PRIMARY KEY (version)
+)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// tableExists reports whether the schema_migrations table has been created
+func (m *Migrator) tableExists(ctx context.Context, conn *sql.Conn) (bool, error) {
+	query := "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'schema_migrations'"
+	if m.dialect == SQLite {
+		query = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'"
+	}
+
+	var count int
+	if err := conn.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+	return count > 0, nil
+}
+
+// appliedMigrations loads the schema_migrations table by version
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, checksum, dirty, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var row appliedMigration
+		if err := rows.Scan(&row.Version, &row.Checksum, &row.Dirty, &row.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		done[row.Version] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return done, nil
+}
+
+// execScript runs the statements of a migration script one by one
+func execScript(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, statement := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a script on semicolons that end a line, dropping
+// comment lines
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// checksum returns the SHA-256 of a script
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
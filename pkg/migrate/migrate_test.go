@@ -0,0 +1,220 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	// registers the "sqlite" database/sql driver
+	_ "github.com/glebarez/sqlite"
+)
+
+var databaseCount atomic.Int64
+
+// openDB opens an empty in-memory SQLite database
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:migrate%d?mode=memory&cache=shared", databaseCount.Add(1)))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// scripts returns a two-step migration set
+func scripts() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_items.up.sql":       {Data: []byte("CREATE TABLE items (id INTEGER PRIMARY KEY);\n")},
+		"0001_items.down.sql":     {Data: []byte("DROP TABLE items;\n")},
+		"0002_item_name.up.sql":   {Data: []byte("-- names are optional\nALTER TABLE items ADD COLUMN name TEXT;\nCREATE INDEX idx_items_name\n  ON items (name);\n")},
+		"0002_item_name.down.sql": {Data: []byte("DROP INDEX idx_items_name;\nALTER TABLE items DROP COLUMN name;\n")},
+	}
+}
+
+func newMigrator(t *testing.T, db *sql.DB, fsys fstest.MapFS) *Migrator {
+	t.Helper()
+	m, err := New(db, fsys, WithDialect(SQLite))
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	return m
+}
+
+func TestLoadOrdersByVersion(t *testing.T) {
+	fsys := scripts()
+	fsys["0010_later.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;\n")}
+	fsys["README.md"] = &fstest.MapFile{Data: []byte("not a migration")}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int64
+	for _, migration := range migrations {
+		versions = append(versions, migration.Version)
+	}
+	if fmt.Sprint(versions) != "[1 2 10]" {
+		t.Errorf("got versions %v, want [1 2 10]", versions)
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"bad name":     {"initial.up.sql": {Data: []byte("SELECT 1;")}},
+		"no up script": {"0001_items.down.sql": {Data: []byte("SELECT 1;")}},
+		"two names":    {"0001_a.up.sql": {Data: []byte("SELECT 1;")}, "0001_b.down.sql": {Data: []byte("SELECT 1;")}},
+		"version zero": {"0000_items.up.sql": {Data: []byte("SELECT 1;")}},
+	}
+	for name, fsys := range tests {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements("-- comment\nCREATE TABLE a (\n  id INTEGER\n);\n\nINSERT INTO a VALUES ('x;y');\nSELECT 1")
+	want := []string{"CREATE TABLE a (\n  id INTEGER\n)", "INSERT INTO a VALUES ('x;y')", "SELECT 1"}
+	if fmt.Sprintf("%q", statements) != fmt.Sprintf("%q", want) {
+		t.Errorf("got %q, want %q", statements, want)
+	}
+}
+
+func TestUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	m := newMigrator(t, db, scripts())
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(applied) != 2 {
+		t.Fatalf("applied %d migrations, want 2", len(applied))
+	}
+	if _, err := db.Exec("INSERT INTO items (name) VALUES ('a')"); err != nil {
+		t.Fatalf("schema not migrated: %v", err)
+	}
+
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("second up applied %d migrations: %v", len(applied), err)
+	}
+
+	reverted, err := m.Down(ctx, 1)
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Fatalf("reverted %+v, want version 2", reverted)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Errorf("got applied %v and %v, want true and false", statuses[0].Applied, statuses[1].Applied)
+	}
+}
+
+func TestStatusIsReadOnly(t *testing.T) {
+	db := openDB(t)
+	m := newMigrator(t, db, scripts())
+
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].Applied || statuses[1].Applied {
+		t.Errorf("got %+v, want two pending migrations", statuses)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_migrations'").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Error("status created the schema_migrations table")
+	}
+}
+
+func TestUpRejectsChangedMigration(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	if _, err := newMigrator(t, db, scripts()).Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	changed := scripts()
+	changed["0001_items.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);\n")}
+	if _, err := newMigrator(t, db, changed).Up(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("got %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestUpRejectsUnknownVersion(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	if _, err := newMigrator(t, db, scripts()).Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	older := scripts()
+	delete(older, "0002_item_name.up.sql")
+	delete(older, "0002_item_name.down.sql")
+	if _, err := newMigrator(t, db, older).Up(ctx); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("got %v, want ErrUnknownVersion", err)
+	}
+}
+
+func TestFailedMigrationLeavesDatabaseDirty(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	broken := scripts()
+	broken["0003_broken.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE tags (id INTEGER);\nNOT SQL;\n")}
+	m := newMigrator(t, db, broken)
+
+	applied, err := m.Up(ctx)
+	if err == nil {
+		t.Fatal("expected the broken migration to fail")
+	}
+	if len(applied) != 2 {
+		t.Errorf("applied %d migrations before the failure, want 2", len(applied))
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if !statuses[2].Dirty {
+		t.Error("expected the failed migration to be dirty")
+	}
+
+	if _, err := m.Up(ctx); !errors.Is(err, ErrDirty) {
+		t.Errorf("got %v, want ErrDirty", err)
+	}
+	if _, err := m.Down(ctx, 1); !errors.Is(err, ErrDirty) {
+		t.Errorf("got %v, want ErrDirty", err)
+	}
+}
+
+func TestDownWithoutScript(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	fsys := scripts()
+	delete(fsys, "0002_item_name.down.sql")
+	m := newMigrator(t, db, fsys)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if _, err := m.Down(ctx, 1); !errors.Is(err, ErrNoDownMigration) {
+		t.Errorf("got %v, want ErrNoDownMigration", err)
+	}
+}
@@ -0,0 +1,129 @@
+package oidc_test
+
+import (
+	"errors"
+	"testing"
+
+	"service-booking/pkg/oidc"
+	"service-booking/pkg/oidc/oidctest"
+)
+
+const redirectURL = "http://localhost/callback"
+
+func TestCodeChallengeS256(t *testing.T) {
+	// RFC 7636 appendix B
+	got := oidc.CodeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// login runs the authorization code flow against the stub provider and
+// returns the raw ID token
+func login(t *testing.T, provider *oidctest.Server, client *oidc.Client, nonce string) string {
+	t.Helper()
+
+	verifier, err := oidc.RandomString(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := client.AuthCodeURL("state-value", nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	callback, err := provider.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if state := callback.Query().Get("state"); state != "state-value" {
+		t.Fatalf("callback state = %q", state)
+	}
+
+	tokens, err := client.Exchange(callback.Query().Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	return tokens.IDToken
+}
+
+func TestLoginFlow(t *testing.T) {
+	provider := oidctest.NewServer(oidctest.User{
+		Subject:       "subject-1",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		Name:          "Jane Doe",
+	})
+	defer provider.Close()
+
+	client := oidc.NewClient(oidc.Config{
+		Issuer:      provider.Issuer(),
+		ClientID:    "client",
+		RedirectURL: redirectURL,
+	}, nil)
+
+	claims, err := client.VerifyIDToken(login(t, provider, client, "nonce-value"), "nonce-value")
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if claims.Subject != "subject-1" || claims.Email != "jane@example.com" || !claims.EmailVerified {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestVerifyIDTokenRejectsNonceMismatch(t *testing.T) {
+	provider := oidctest.NewServer(oidctest.User{Subject: "subject-1", Email: "jane@example.com"})
+	defer provider.Close()
+
+	client := oidc.NewClient(oidc.Config{
+		Issuer:      provider.Issuer(),
+		ClientID:    "client",
+		RedirectURL: redirectURL,
+	}, nil)
+
+	_, err := client.VerifyIDToken(login(t, provider, client, "nonce-value"), "other-nonce")
+	if !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("got %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestVerifyIDTokenRejectsOtherAudience(t *testing.T) {
+	provider := oidctest.NewServer(oidctest.User{Subject: "subject-1", Email: "jane@example.com"})
+	defer provider.Close()
+
+	config := oidc.Config{
+		Issuer:      provider.Issuer(),
+		ClientID:    "client",
+		RedirectURL: redirectURL,
+	}
+	idToken := login(t, provider, oidc.NewClient(config, nil), "nonce-value")
+
+	config.ClientID = "other-client"
+	_, err := oidc.NewClient(config, nil).VerifyIDToken(idToken, "nonce-value")
+	if !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("got %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	provider := oidctest.NewServer(oidctest.User{Subject: "subject-1"})
+	defer provider.Close()
+
+	client := oidc.NewClient(oidc.Config{
+		Issuer:      provider.Issuer(),
+		ClientID:    "client",
+		RedirectURL: redirectURL,
+	}, nil)
+
+	authURL, err := client.AuthCodeURL("state-value", "nonce-value", oidc.CodeChallengeS256("verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback, err := provider.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Exchange(callback.Query().Get("code"), "other-verifier"); err == nil {
+		t.Error("expected the exchange to fail")
+	}
+}
@@ -0,0 +1,60 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidPhone is returned when a phone number cannot be normalized
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// NormalizeE164 converts a phone number to E.164 format (e.g. +8801712345678).
+// Numbers without an international prefix ("+" or "00") are treated as
+// national numbers of defaultCountryCode, dropping the trunk prefix "0".
+func NormalizeE164(raw, defaultCountryCode string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", ErrInvalidPhone
+	}
+
+	international := false
+	switch {
+	case strings.HasPrefix(raw, "+"):
+		international = true
+		raw = raw[1:]
+	case strings.HasPrefix(raw, "00"):
+		international = true
+		raw = raw[2:]
+	}
+
+	// Strip common separators, rejecting anything else
+	var digits strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+			continue
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+
+	number := digits.String()
+	if !international {
+		countryCode := strings.TrimPrefix(defaultCountryCode, "+")
+		switch {
+		case strings.HasPrefix(number, "0"):
+			number = countryCode + strings.TrimPrefix(number, "0")
+		case !strings.HasPrefix(number, countryCode):
+			number = countryCode + number
+		}
+	}
+
+	// E.164 allows at most 15 digits and never starts with 0
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+
+	return "+" + number, nil
+}
@@ -0,0 +1,49 @@
+package phone
+
+import "testing"
+
+func TestNormalizeE164(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"+8801712345678", "+8801712345678"},
+		{"008801712345678", "+8801712345678"},
+		{"01712345678", "+8801712345678"},
+		{"1712345678", "+8801712345678"},
+		{"8801712345678", "+8801712345678"},
+		{" +880 1712-345.678 ", "+8801712345678"},
+		{"(017) 1234 5678", "+8801712345678"},
+		{"+44 20 7946 0958", "+442079460958"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeE164(tt.raw, "880")
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeE164(%q) = %q, %v, want %q", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeE164AcceptsPlusInCountryCode(t *testing.T) {
+	got, err := NormalizeE164("01712345678", "+880")
+	if err != nil || got != "+8801712345678" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
+
+func TestNormalizeE164RejectsInvalidNumbers(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"   ",
+		"+",
+		"123",
+		"+8801712345678901",
+		"+0123456789",
+		"01712-ABC-678",
+		"+880 1712 345678 ext 1",
+	} {
+		if got, err := NormalizeE164(raw, "880"); err != ErrInvalidPhone {
+			t.Errorf("NormalizeE164(%q) = %q, %v, want ErrInvalidPhone", raw, got, err)
+		}
+	}
+}
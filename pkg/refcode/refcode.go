@@ -0,0 +1,127 @@
+// Package refcode generates and validates short booking reference codes.
+//
+// A code is a prefix followed by random Crockford base32 symbols and a check
+// symbol, e.g. "SB-7K3M-9QXD-T". Crockford base32 has no I, L, O or U, so codes
+// are easy to read out over the phone, and the check symbol (Luhn mod 32)
+// catches single typos and most swapped neighbours without a database lookup.
+package refcode
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+const (
+	// alphabet is the Crockford base32 alphabet
+	alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	// groupSize is the number of symbols between hyphens
+	groupSize = 4
+	// DefaultLength is the number of random symbols, about 40 bits
+	DefaultLength = 8
+)
+
+var ErrInvalidCode = errors.New("invalid reference code")
+
+// Generator creates reference codes
+type Generator interface {
+	Generate(prefix string) (string, error)
+}
+
+// randomGenerator creates codes from a cryptographic random source
+type randomGenerator struct {
+	length int
+}
+
+// NewGenerator returns a generator of codes with the given number of random symbols
+func NewGenerator(length int) Generator {
+	if length < 1 {
+		length = DefaultLength
+	}
+	return &randomGenerator{length: length}
+}
+
+func (g *randomGenerator) Generate(prefix string) (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+	symbols := make([]byte, g.length)
+	for i := range symbols {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		symbols[i] = alphabet[n.Int64()]
+	}
+
+	return format(strings.ToUpper(prefix), string(symbols)+string(checkSymbol(string(symbols)))), nil
+}
+
+// ValidPrefix reports whether a prefix can be used in codes: one to five
+// letters
+func ValidPrefix(prefix string) bool {
+	if len(prefix) < 1 || len(prefix) > 5 {
+		return false
+	}
+	for _, r := range prefix {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Normalize returns the canonical form of a code typed by a person: upper
+// case, with ambiguous letters mapped to digits and hyphens regrouped.
+// It returns ErrInvalidCode when the code is malformed or the check symbol
+// does not match.
+func Normalize(code string) (string, error) {
+	prefix, body, ok := strings.Cut(strings.ToUpper(strings.TrimSpace(code)), "-")
+	if !ok || !ValidPrefix(prefix) {
+		return "", ErrInvalidCode
+	}
+
+	body = strings.NewReplacer("-", "", " ", "", "I", "1", "L", "1", "O", "0").Replace(body)
+	if len(body) < 2 {
+		return "", ErrInvalidCode
+	}
+	for _, r := range body {
+		if !strings.ContainsRune(alphabet, r) {
+			return "", ErrInvalidCode
+		}
+	}
+
+	payload, check := body[:len(body)-1], body[len(body)-1]
+	if checkSymbol(payload) != check {
+		return "", ErrInvalidCode
+	}
+
+	return format(prefix, body), nil
+}
+
+// format joins the prefix and the symbols grouped by hyphens
+func format(prefix, body string) string {
+	var code strings.Builder
+	code.WriteString(prefix)
+	for i := 0; i < len(body); i += groupSize {
+		end := i + groupSize
+		if end > len(body) {
+			end = len(body)
+		}
+		code.WriteByte('-')
+		code.WriteString(body[i:end])
+	}
+	return code.String()
+}
+
+// checkSymbol computes the Luhn mod 32 check symbol of the payload
+func checkSymbol(payload string) byte {
+	n := len(alphabet)
+	factor := 2
+	sum := 0
+	for i := len(payload) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(alphabet, payload[i])
+		factor = 3 - factor
+		sum += addend/n + addend%n
+	}
+	return alphabet[(n-sum%n)%n]
+}
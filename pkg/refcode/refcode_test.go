@@ -0,0 +1,107 @@
+package refcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratedCodesAreValid(t *testing.T) {
+	generator := NewGenerator(DefaultLength)
+	for i := 0; i < 100; i++ {
+		code, err := generator.Generate("sb")
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		if !strings.HasPrefix(code, "SB-") || len(code) != len("SB-XXXX-XXXX-X") {
+			t.Fatalf("unexpected code format %q", code)
+		}
+		normalized, err := Normalize(code)
+		if err != nil || normalized != code {
+			t.Fatalf("Normalize(%q) = %q, %v", code, normalized, err)
+		}
+	}
+}
+
+func TestNormalizeReadsTypedCodes(t *testing.T) {
+	// The payload "1100" has the check symbol that makes it valid
+	code := "SB-1100-" + string(checkSymbol("1100"))
+	typed := []string{
+		code,
+		strings.ToLower(code),
+		"  " + code + " ",
+		"SB-IL00-" + string(checkSymbol("1100")),
+		"SB-11OO" + string(checkSymbol("1100")),
+		"sb-1 1 0 0-" + strings.ToLower(string(checkSymbol("1100"))),
+	}
+	for _, input := range typed {
+		got, err := Normalize(input)
+		if err != nil || got != code {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", input, got, err, code)
+		}
+	}
+}
+
+func TestNormalizeCatchesSingleTypos(t *testing.T) {
+	payload := "7K3M9QXD"
+	body := payload + string(checkSymbol(payload))
+
+	for i := range body {
+		for _, r := range alphabet {
+			if byte(r) == body[i] {
+				continue
+			}
+			typo := body[:i] + string(r) + body[i+1:]
+			if _, err := Normalize("SB-" + typo); err == nil {
+				t.Errorf("typo %q at position %d was accepted", typo, i)
+			}
+		}
+	}
+}
+
+func TestNormalizeCatchesSwappedNeighbours(t *testing.T) {
+	payload := "7K3M9QXD"
+	body := payload + string(checkSymbol(payload))
+
+	for i := 0; i+1 < len(body); i++ {
+		if body[i] == body[i+1] {
+			continue
+		}
+		swapped := body[:i] + string(body[i+1]) + string(body[i]) + body[i+2:]
+		if _, err := Normalize("SB-" + swapped); err == nil {
+			t.Errorf("swap %q at position %d was accepted", swapped, i)
+		}
+	}
+}
+
+func TestNormalizeRejectsMalformedCodes(t *testing.T) {
+	for _, code := range []string{
+		"",
+		"SB",
+		"SB-",
+		"SB-1",
+		"TOOLONG-1100-" + string(checkSymbol("1100")),
+		"S1-1100-" + string(checkSymbol("1100")),
+		"SB-11U0-0",
+		"SB-1100-" + string(checkSymbol("1100")) + "!",
+	} {
+		if got, err := Normalize(code); err != ErrInvalidCode {
+			t.Errorf("Normalize(%q) = %q, %v, want ErrInvalidCode", code, got, err)
+		}
+	}
+}
+
+func TestValidPrefix(t *testing.T) {
+	for prefix, want := range map[string]bool{
+		"S":      true,
+		"SB":     true,
+		"ABCDE":  true,
+		"":       false,
+		"ABCDEF": false,
+		"sb":     false,
+		"S1":     false,
+	} {
+		if got := ValidPrefix(prefix); got != want {
+			t.Errorf("ValidPrefix(%q) = %v, want %v", prefix, got, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package sms
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Sender delivers text messages to a phone number in E.164 format
+type Sender interface {
+	Send(to, message string) error
+}
+
+// Message is a text message recorded by FakeSender
+type Message struct {
+	To   string
+	Body string
+}
+
+// fakeSenderCapacity bounds the messages kept by FakeSender
+const fakeSenderCapacity = 100
+
+// FakeSender is a Sender for local development and tests. It keeps the
+// latest messages in memory for inspection instead of delivering them.
+type FakeSender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewFakeSender creates a new instance of FakeSender
+func NewFakeSender() *FakeSender {
+	return &FakeSender{}
+}
+
+// Send records the message, dropping the oldest one when full. Only the
+// recipient is logged, as messages hold codes and access links.
+func (s *FakeSender) Send(to, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.messages) == fakeSenderCapacity {
+		s.messages = append(s.messages[:0], s.messages[1:]...)
+	}
+	s.messages = append(s.messages, Message{To: to, Body: message})
+	slog.Info("SMS recorded by fake sender", "phone", to)
+	return nil
+}
+
+// Messages returns the messages kept so far, oldest first
+func (s *FakeSender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := make([]Message, len(s.messages))
+	copy(messages, s.messages)
+	return messages
+}
+
+// LastMessage returns the most recent message sent to a phone number
+func (s *FakeSender) LastMessage(to string) (Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if s.messages[i].To == to {
+			return s.messages[i], true
+		}
+	}
+	return Message{}, false
+}
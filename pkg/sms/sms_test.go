@@ -0,0 +1,74 @@
+package sms
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFakeSenderKeepsLatestMessages(t *testing.T) {
+	sender := NewFakeSender()
+	for i := 0; i < fakeSenderCapacity+5; i++ {
+		if err := sender.Send("+8801711111111", fmt.Sprintf("message %d", i)); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	messages := sender.Messages()
+	if len(messages) != fakeSenderCapacity {
+		t.Fatalf("got %d messages, want %d", len(messages), fakeSenderCapacity)
+	}
+	if messages[0].Body != "message 5" {
+		t.Errorf("oldest message: got %q, want %q", messages[0].Body, "message 5")
+	}
+	if last, ok := sender.LastMessage("+8801711111111"); !ok || last.Body != fmt.Sprintf("message %d", fakeSenderCapacity+4) {
+		t.Errorf("unexpected last message %q", last.Body)
+	}
+}
+
+func TestTwilioSender(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		got = r
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	sender, err := NewTwilioSender("AC123", "secret", "+15005550006")
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+	sender.baseURL = server.URL
+
+	if err := sender.Send("+8801711111111", "Your code is 123456"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if got.URL.Path != "/Accounts/AC123/Messages.json" {
+		t.Errorf("got path %q", got.URL.Path)
+	}
+	if user, pass, _ := got.BasicAuth(); user != "AC123" || pass != "secret" {
+		t.Errorf("got credentials %q:%q", user, pass)
+	}
+	if got.PostForm.Get("To") != "+8801711111111" || got.PostForm.Get("From") != "+15005550006" || got.PostForm.Get("Body") != "Your code is 123456" {
+		t.Errorf("unexpected form %v", got.PostForm)
+	}
+}
+
+func TestTwilioSenderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"invalid number"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sender, err := NewTwilioSender("AC123", "secret", "+15005550006")
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+	sender.baseURL = server.URL
+
+	if err := sender.Send("+1", "hello"); err == nil {
+		t.Error("expected an error for a rejected message")
+	}
+}
@@ -0,0 +1,68 @@
+package sms
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// twilioBaseURL is the Twilio REST API
+const twilioBaseURL = "https://api.twilio.com/2010-04-01"
+
+// TwilioSender delivers messages through the Twilio Messages API
+type TwilioSender struct {
+	accountSID string
+	authToken  string
+	from       string
+	baseURL    string
+	client     *http.Client
+}
+
+// NewTwilioSender creates a sender for the account, sending from the given
+// number or messaging service SID
+func NewTwilioSender(accountSID, authToken, from string) (*TwilioSender, error) {
+	if accountSID == "" || authToken == "" || from == "" {
+		return nil, fmt.Errorf("twilio sender needs an account SID, auth token and from number")
+	}
+	return &TwilioSender{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		baseURL:    twilioBaseURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Send delivers the message. The message is never logged, as it holds
+// codes and access links.
+func (s *TwilioSender) Send(to, message string) error {
+	form := url.Values{"To": {to}, "Body": {message}}
+	if strings.HasPrefix(s.from, "MG") {
+		form.Set("MessagingServiceSid", s.from)
+	} else {
+		form.Set("From", s.from)
+	}
+
+	endpoint := s.baseURL + "/Accounts/" + url.PathEscape(s.accountSID) + "/Messages.json"
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build SMS request: %w", err)
+	}
+	req.SetBasicAuth(s.accountSID, s.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("SMS provider returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
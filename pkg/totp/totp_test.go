@@ -0,0 +1,67 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit codes are their last six digits
+	vectors := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, v := range vectors {
+		got, err := Code(rfcSecret, time.Unix(v.unix, 0))
+		if err != nil {
+			t.Fatalf("T=%d: %v", v.unix, err)
+		}
+		if got != v.want {
+			t.Errorf("T=%d: got %s, want %s", v.unix, got, v.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	previous, err := Code(rfcSecret, now.Add(-Period))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if step, ok := Validate(rfcSecret, "050471", now, 0); !ok || step != now.Unix()/30 {
+		t.Errorf("current code: got step %d, ok %v", step, ok)
+	}
+	if step, ok := Validate(rfcSecret, previous, now, 1); !ok || step != now.Unix()/30-1 {
+		t.Errorf("previous code within skew: got step %d, ok %v", step, ok)
+	}
+	if _, ok := Validate(rfcSecret, previous, now, 0); ok {
+		t.Error("previous code accepted without skew")
+	}
+	if _, ok := Validate(rfcSecret, "50471", now, 1); ok {
+		t.Error("short code accepted")
+	}
+	if _, ok := Validate("not base32!", "050471", now, 1); ok {
+		t.Error("code accepted for an invalid secret")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Code(secret, time.Now()); err != nil {
+		t.Errorf("generated secret cannot be used: %v", err)
+	}
+}
@@ -1,104 +1,212 @@
 package routes
 
 import (
-	"service-booking/db"
-	"service-booking/internal/handler"
+	"fmt"
+
+	"service-booking/internal/app"
+	"service-booking/internal/dto"
 	"service-booking/internal/middleware"
-	"service-booking/internal/repository"
-	"service-booking/internal/service"
+	"service-booking/internal/model"
+	"service-booking/pkg/auth"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRouter configures the Gin router with all necessary routes
-func SetupRouter() *gin.Engine {
-	router := gin.Default()
-
-	// Initialize database connection
-	db := db.GetDB()
-
-	// Initialize repositories
-	serviceRepo := repository.NewServiceRepository(db)
-	bookingRepo := repository.NewBookingRepository(db)
-	userRepo := repository.NewUserRepository(db)
-	categoryRepo := repository.NewCategoryRepository(db)
+// SetupRouter configures the Gin router with all routes of the application
+func SetupRouter(a *app.App) (*gin.Engine, error) {
+	router := gin.New()
+	cfg := a.Config
 
-	// Initialize services
-	serviceService := service.NewServiceService(serviceRepo, categoryRepo)
-	bookingService := service.NewBookingService(bookingRepo, serviceRepo, userRepo)
-	authService := service.NewAuthService(userRepo)
-	categoryService := service.NewCategoryService(categoryRepo)
+	// Only listed proxies may set the client IP, which keys rate limits,
+	// login throttling and sessions
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 
-	// Initialize handlers
-	serviceHandler := handler.NewServiceHandler(serviceService)
-	bookingHandler := handler.NewBookingHandler(bookingService)
-	authHandler := handler.NewAuthHandler(authService)
-	categoryHandler := handler.NewCategoryHandler(categoryService)
+	// Register the request validation rules
+	if err := dto.RegisterValidators(); err != nil {
+		return nil, fmt.Errorf("failed to register validators: %w", err)
+	}
 
-	// Middleware for rate limiting and CORS
-	router.Use(gin.Recovery())
-	router.Use(gin.Logger())
+	handlers := a.Handlers
+	serviceHandler := handlers.Service
+	bookingHandler := handlers.Booking
+	authHandler := handlers.Auth
+	categoryHandler := handlers.Category
+	otpHandler := handlers.OTP
+	roleHandler := handlers.Role
+	apiKeyHandler := handlers.APIKey
+	socialAuthHandler := handlers.SocialAuth
+	mfaHandler := handlers.MFA
+	sessionHandler := handlers.Session
+	apiKeyService := a.Services.APIKey
+	roleService := a.Services.Role
+	sessionService := a.Services.Session
+
+	// Middleware for tracing, request logging, rate limiting and CORS
+	router.Use(middleware.Tracing(a.Tracing.Tracer(), a.Tracing.Propagator()))
+	router.Use(middleware.RequestID(a.Logger))
+	router.Use(middleware.RequestLogger())
+	router.Use(middleware.Recovery())
+	router.Use(middleware.Metrics(a.Metrics))
+	router.Use(middleware.ErrorHandler())
+	router.Use(middleware.SecurityHeaders(middleware.DefaultSecurityHeadersConfig(cfg)))
+	router.Use(middleware.CORS(middleware.DefaultCORSConfig(cfg)))
+
+	// Rate limiting policies per route group
+	limits, err := newRateLimits(a)
+	if err != nil {
+		return nil, err
+	}
+	authLimit := limits["auth"]
+	bookingLimit := limits["booking"]
+	catalogLimit := limits["catalog"]
+	defaultLimit := limits["default"]
+	lookupLimit := limits["lookup"]
+
+	// Idempotency-Key support for endpoints clients retry
+	idempotent := middleware.Idempotency(a.Repositories.Idempotency, a.IdempotencyTTL, a.Tokens.DeriveKey(auth.KeyPurposeIdempotency))
+
+	// Liveness and readiness probes
+	router.GET("/healthz", handlers.Health.Liveness)
+	router.GET("/readyz", handlers.Health.Readiness)
+
+	// Prometheus metrics, for scrapers holding the metrics token
+	if cfg.Metrics.Token != "" {
+		router.GET("/metrics", middleware.RequireToken(cfg.Metrics.Token), gin.WrapH(a.Metrics.Handler()))
+	}
 
 	// Public routes
 	v1 := router.Group("/api/v1")
 	{
 		// Service routes
-		v1.GET("/services", serviceHandler.GetServices)
-		v1.GET("/services/:id", serviceHandler.GetServiceByID)
-		v1.GET("/services/featured", serviceHandler.GetFeaturedServices)
+		v1.GET("/services", catalogLimit, serviceHandler.GetServices)
+		v1.GET("/services/:id", catalogLimit, serviceHandler.GetServiceByID)
+		v1.GET("/services/featured", catalogLimit, serviceHandler.GetFeaturedServices)
 
 		// Category routes
-		v1.GET("/categories", categoryHandler.GetCategories)
-		v1.GET("/categories/:id", categoryHandler.GetCategoryByID)
-		v1.GET("/categories/:id/subcategories", categoryHandler.GetSubCategories)
+		v1.GET("/categories", catalogLimit, categoryHandler.GetCategories)
+		v1.GET("/categories/:id", catalogLimit, categoryHandler.GetCategoryByID)
+		v1.GET("/categories/:id/subcategories", catalogLimit, categoryHandler.GetSubCategories)
 
 		// Booking routes
-		v1.POST("/bookings", bookingHandler.CreateBooking)
-		v1.GET("/bookings/:id", bookingHandler.GetBookingByID)
-		v1.GET("/bookings/reference/:code", bookingHandler.GetBookingByReferenceCode)
+		v1.POST("/bookings", middleware.OptionalAuth(a.Tokens, apiKeyService), middleware.RequireScope(model.PermissionBookingCreate), bookingLimit, idempotent, bookingHandler.CreateBooking)
+		v1.POST("/bookings/guest/verify", authLimit, otpHandler.VerifyGuestPhone)
+		v1.GET("/bookings/reference/:code", lookupLimit, bookingHandler.GetBookingByReferenceCode)
+		v1.POST("/bookings/reference/:code", lookupLimit, bookingHandler.LookupBookingByReferenceCode)
 
 		// Auth routes
-		v1.POST("/auth/register", authHandler.Register)
-		v1.POST("/auth/login", authHandler.Login)
+		v1.POST("/auth/register", authLimit, authHandler.Register)
+		v1.POST("/auth/login", authLimit, authHandler.Login)
+		v1.POST("/auth/mfa/verify", authLimit, mfaHandler.Verify)
+		v1.POST("/auth/otp/request", authLimit, otpHandler.RequestOTP)
+		v1.POST("/auth/otp/verify", authLimit, otpHandler.VerifyOTP)
+		v1.GET("/auth/oidc/providers", authLimit, socialAuthHandler.GetProviders)
+		v1.GET("/auth/oidc/:provider/login", authLimit, socialAuthHandler.BeginLogin)
+		v1.GET("/auth/oidc/:provider/callback", authLimit, socialAuthHandler.Callback)
 		
 		// Token refresh route (public but requires a valid refresh token)
-		v1.POST("/auth/refresh", middleware.RefreshTokenHandler)
+		v1.POST("/auth/refresh", authLimit, middleware.RefreshTokenHandler(a.Tokens, sessionService))
 	}
 
 	// Protected routes
 	protected := router.Group("/api/v1")
-	protected.Use(middleware.JWTAuth())
+	protected.Use(middleware.JWTAuth(a.Tokens, apiKeyService))
+	protected.Use(defaultLimit)
 	{
+		// Profile routes are for the account holder, not partner API keys
+		profile := protected.Group("")
+		profile.Use(middleware.RequireScope())
+
 		// User profile routes
-		protected.GET("/profile", authHandler.GetProfile)
-		protected.PUT("/profile", authHandler.UpdateProfile)
-		protected.POST("/profile/change-password", authHandler.ChangePassword)
-
-		// User bookings
-		protected.GET("/bookings", bookingHandler.GetBookings)
-		protected.PUT("/bookings/:id/status", bookingHandler.UpdateBookingStatus)
-		protected.DELETE("/bookings/:id", bookingHandler.CancelBooking)
+		profile.GET("/profile", authHandler.GetProfile)
+		profile.PUT("/profile", authHandler.UpdateProfile)
+		profile.POST("/profile/change-password", authHandler.ChangePassword)
+
+		// Device session routes
+		profile.GET("/profile/sessions", sessionHandler.GetSessions)
+		profile.DELETE("/profile/sessions/:id", sessionHandler.RevokeSession)
+
+		// Two-factor authentication routes
+		profile.POST("/profile/mfa/totp/enroll", mfaHandler.BeginEnrollment)
+		profile.POST("/profile/mfa/totp/confirm", mfaHandler.ConfirmEnrollment)
+		profile.POST("/profile/mfa/totp/disable", mfaHandler.Disable)
+		profile.POST("/profile/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+
+		// Social login linking, which accounts of roles requiring a second
+		// factor may only start after completing it
+		profile.POST("/profile/oidc/:provider/link", middleware.RequireMFA(), socialAuthHandler.BeginLink)
+
+		// User bookings, limited to the scopes of API keys
+		bookingRead := middleware.RequireScope(model.PermissionBookingRead)
+		bookingUpdate := middleware.RequireScope(model.PermissionBookingUpdateStatus)
+		protected.GET("/bookings", bookingRead, bookingHandler.GetBookings)
+		protected.GET("/bookings/:id", bookingRead, bookingHandler.GetBookingByID)
+		protected.PUT("/bookings/:id/status", bookingUpdate, bookingHandler.UpdateBookingStatus)
+		protected.DELETE("/bookings/:id", bookingUpdate, bookingHandler.CancelBooking)
 	}
 
 	// Admin routes (protected)
 	admin := router.Group("/api/v1/admin")
-	admin.Use(middleware.JWTAuth())
-	admin.Use(middleware.AdminOnly())
+	admin.Use(middleware.JWTAuth(a.Tokens, apiKeyService))
+	admin.Use(defaultLimit)
+	admin.Use(middleware.RequireMFA())
 	{
 		// Admin service routes
-		admin.POST("/services", serviceHandler.CreateService)
-		admin.PUT("/services/:id", serviceHandler.UpdateService)
-		admin.DELETE("/services/:id", serviceHandler.DeleteService)
+		serviceWrite := middleware.RequirePermission(roleService, model.PermissionServiceWrite)
+		admin.POST("/services", serviceWrite, serviceHandler.CreateService)
+		admin.PUT("/services/:id", serviceWrite, serviceHandler.UpdateService)
+		admin.DELETE("/services/:id", serviceWrite, serviceHandler.DeleteService)
 
 		// Admin booking routes
-		admin.GET("/bookings", bookingHandler.GetBookings)
-		admin.PUT("/bookings/:id/status", bookingHandler.UpdateBookingStatus)
+		admin.GET("/bookings", middleware.RequirePermission(roleService, model.PermissionBookingRead), bookingHandler.GetBookings)
+		admin.PUT("/bookings/:id/status", middleware.RequirePermission(roleService, model.PermissionBookingUpdateStatus), bookingHandler.UpdateBookingStatus)
+		admin.PUT("/bookings/:id/provider", middleware.RequirePermission(roleService, model.PermissionBookingUpdateStatus), bookingHandler.AssignProvider)
 
 		// Admin category routes
-		admin.POST("/categories", categoryHandler.CreateCategory)
-		admin.PUT("/categories/:id", categoryHandler.UpdateCategory)
-		admin.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+		categoryWrite := middleware.RequirePermission(roleService, model.PermissionCategoryWrite)
+		admin.POST("/categories", categoryWrite, categoryHandler.CreateCategory)
+		admin.PUT("/categories/:id", categoryWrite, categoryHandler.UpdateCategory)
+		admin.DELETE("/categories/:id", categoryWrite, categoryHandler.DeleteCategory)
+
+		// Admin role routes
+		roleManage := middleware.RequirePermission(roleService, model.PermissionRoleManage)
+		admin.GET("/roles", roleManage, roleHandler.GetRoles)
+		admin.GET("/roles/:id", roleManage, roleHandler.GetRoleByID)
+		admin.POST("/roles", roleManage, roleHandler.CreateRole)
+		admin.PUT("/roles/:id", roleManage, roleHandler.UpdateRole)
+		admin.DELETE("/roles/:id", roleManage, roleHandler.DeleteRole)
+		admin.PUT("/users/:id/role", roleManage, roleHandler.AssignUserRole)
+		admin.POST("/users/:id/unlock", middleware.RequirePermission(roleService, model.PermissionUserUnlock), authHandler.UnlockUser)
+
+		// Admin API key routes
+		apiKeyManage := middleware.RequirePermission(roleService, model.PermissionAPIKeyManage)
+		admin.GET("/api-keys", apiKeyManage, apiKeyHandler.GetAPIKeys)
+		admin.POST("/api-keys", apiKeyManage, apiKeyHandler.IssueAPIKey)
+		admin.DELETE("/api-keys/:id", apiKeyManage, apiKeyHandler.RevokeAPIKey)
 	}
 
-	return router
-}
\ No newline at end of file
+	return router, nil
+}
+
+// newRateLimits creates the rate limiting middleware of every configured
+// policy, keyed by policy name
+func newRateLimits(a *app.App) (map[string]gin.HandlerFunc, error) {
+	specs := map[string]string{
+		"auth":    a.Config.RateLimit.Auth,
+		"booking": a.Config.RateLimit.Booking,
+		"catalog": a.Config.RateLimit.Catalog,
+		"default": a.Config.RateLimit.Default,
+		"lookup":  a.Config.RateLimit.Lookup,
+	}
+
+	limits := make(map[string]gin.HandlerFunc, len(specs))
+	for name, spec := range specs {
+		policy, err := middleware.ParseRateLimitPolicy(name, spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s rate limit: %w", name, err)
+		}
+		limits[name] = middleware.RateLimit(a.Repositories.RateLimit, policy)
+	}
+	return limits, nil
+}
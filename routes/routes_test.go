@@ -0,0 +1,1133 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"service-booking/config"
+	"service-booking/internal/apitest"
+	"service-booking/internal/app"
+	"service-booking/internal/health"
+	"service-booking/internal/model"
+	"service-booking/pkg/oidc/oidctest"
+	"service-booking/pkg/sms"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// routeCase is a request against one registered route and its expected status
+type routeCase struct {
+	name  string
+	route string // registered route as "METHOD /path/:param"
+	path  string
+	body  interface{}
+	opts  []apitest.RequestOption
+	want  int
+}
+
+// fixtures are the records shared by the route cases
+type fixtures struct {
+	admin    *model.User
+	support  *model.User
+	provider *model.User
+	customer *model.User
+	other    *model.User
+	locked   *model.User
+	password *model.User
+
+	category      *model.Category
+	subcategory   *model.Category
+	emptyCategory *model.Category
+	service       *model.Service
+	spareService  *model.Service
+
+	booking      *model.Booking
+	otherBooking *model.Booking
+	guestBooking *model.Booking
+	cancellable  *model.Booking
+
+	customRole *model.Role
+	apiKeyID   uint
+	apiKey     string
+	sessionID  uint
+
+	bookingAPIKey   string
+	forgedLinkToken string
+
+	adminWithoutMFA string
+}
+
+const (
+	customerPhone = "+8801711111111"
+	guestPhone    = "+8801722222222"
+	otpPhone      = "+8801733333333"
+)
+
+func newFixtures(t *testing.T, h *apitest.Harness) *fixtures {
+	t.Helper()
+
+	f := &fixtures{
+		admin:    h.CreateAdmin(),
+		support:  h.CreateUser(apitest.WithRole(model.UserRoleSupport)),
+		provider: h.CreateUser(apitest.WithRole(model.UserRoleProvider)),
+		customer: h.CreateUser(apitest.WithPhone(customerPhone)),
+		other:    h.CreateUser(),
+		locked:   h.CreateUser(),
+		password: h.CreateUser(),
+	}
+
+	f.category = h.CreateCategory(nil)
+	f.subcategory = h.CreateCategory(f.category)
+	f.emptyCategory = h.CreateCategory(nil)
+	f.service = h.CreateService(f.category)
+	f.spareService = h.CreateService(f.subcategory)
+
+	f.booking = h.CreateBooking(f.service, f.customer, "")
+	f.otherBooking = h.CreateBooking(f.service, f.other, "")
+	f.guestBooking = h.CreateBooking(f.service, nil, guestPhone)
+	f.cancellable = h.CreateBooking(f.service, f.customer, "")
+
+	f.customRole = &model.Role{Name: "auditor", Permissions: []model.Permission{model.PermissionBookingRead}}
+	if err := h.DB.Create(f.customRole).Error; err != nil {
+		t.Fatalf("failed to create role: %v", err)
+	}
+
+	issueAPIKey := func(scope model.Permission) (uint, string) {
+		resp := h.Do(http.MethodPost, "/api/v1/admin/api-keys", map[string]interface{}{
+			"name":         "Partner",
+			"organization": "Partner Ltd",
+			"user_id":      f.other.ID,
+			"scopes":       []string{string(scope)},
+		}, apitest.WithToken(h.Token(f.admin)))
+		if resp.Code != http.StatusCreated {
+			t.Fatalf("failed to issue API key: %d %s", resp.Code, resp.Body)
+		}
+		var issued struct {
+			APIKey model.APIKey `json:"api_key"`
+			Key    string       `json:"key"`
+		}
+		resp.Decode(t, &issued)
+		return issued.APIKey.ID, issued.Key
+	}
+	f.apiKeyID, f.apiKey = issueAPIKey(model.PermissionBookingRead)
+	_, f.bookingAPIKey = issueAPIKey(model.PermissionBookingCreate)
+
+	// The session of the other user's login is revoked by its owner
+	h.Login(f.other)
+	var session model.Session
+	if err := h.DB.Where("user_id = ?", f.other.ID).First(&session).Error; err != nil {
+		t.Fatalf("failed to find session: %v", err)
+	}
+	f.sessionID = session.ID
+
+	// Admins must complete the second factor to use the admin routes
+	token, _, err := h.App.Tokens.GenerateAllTokens(f.admin.ID, f.admin.Email, f.admin.Name, string(f.admin.Role))
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	f.adminWithoutMFA = token
+
+	// Booking links have their own key, not the secret of the login tokens
+	f.forgedLinkToken = forgeBookingLinkToken(t, h, f.guestBooking)
+
+	return f
+}
+
+func routeCases(h *apitest.Harness, f *fixtures) []routeCase {
+	as := func(user *model.User) []apitest.RequestOption {
+		return []apitest.RequestOption{apitest.WithToken(h.Token(user))}
+	}
+	id := func(format string, id uint) string {
+		return fmt.Sprintf(format, id)
+	}
+	bookingBody := map[string]interface{}{"service_id": f.service.ID, "user_name": "Rahim"}
+
+	return []routeCase{
+		// Probes
+		{name: "liveness", route: "GET /healthz", path: "/healthz", want: http.StatusOK},
+		{name: "readiness", route: "GET /readyz", path: "/readyz", want: http.StatusOK},
+		{name: "metrics", route: "GET /metrics", path: "/metrics", opts: []apitest.RequestOption{apitest.WithToken(apitest.MetricsToken)}, want: http.StatusOK},
+		{name: "metrics without token", route: "GET /metrics", path: "/metrics", want: http.StatusUnauthorized},
+		{name: "metrics with user token", route: "GET /metrics", path: "/metrics", opts: as(f.admin), want: http.StatusUnauthorized},
+
+		// Catalog
+		{name: "list services", route: "GET /api/v1/services", path: "/api/v1/services", want: http.StatusOK},
+		{name: "get service", route: "GET /api/v1/services/:id", path: id("/api/v1/services/%d", f.service.ID), want: http.StatusOK},
+		{name: "get missing service", route: "GET /api/v1/services/:id", path: "/api/v1/services/999999", want: http.StatusNotFound},
+		{name: "get service with invalid ID", route: "GET /api/v1/services/:id", path: "/api/v1/services/abc", want: http.StatusBadRequest},
+		{name: "list featured services", route: "GET /api/v1/services/featured", path: "/api/v1/services/featured", want: http.StatusOK},
+		{name: "list categories", route: "GET /api/v1/categories", path: "/api/v1/categories", want: http.StatusOK},
+		{name: "get category", route: "GET /api/v1/categories/:id", path: id("/api/v1/categories/%d", f.category.ID), want: http.StatusOK},
+		{name: "get missing category", route: "GET /api/v1/categories/:id", path: "/api/v1/categories/999999", want: http.StatusNotFound},
+		{name: "list subcategories", route: "GET /api/v1/categories/:id/subcategories", path: id("/api/v1/categories/%d/subcategories", f.category.ID), want: http.StatusOK},
+
+		// Booking creation and guest access
+		{name: "guest booking without phone verification", route: "POST /api/v1/bookings", path: "/api/v1/bookings", body: bookingBody, want: http.StatusUnauthorized},
+		{name: "guest booking", route: "POST /api/v1/bookings", path: "/api/v1/bookings", body: bookingBody,
+			opts: []apitest.RequestOption{apitest.WithHeader("X-Guest-Token", h.GuestToken(guestPhone))}, want: http.StatusCreated},
+		{name: "user booking", route: "POST /api/v1/bookings", path: "/api/v1/bookings", body: bookingBody, opts: as(f.customer), want: http.StatusCreated},
+		{name: "booking with API key", route: "POST /api/v1/bookings", path: "/api/v1/bookings", body: bookingBody,
+			opts: []apitest.RequestOption{apitest.WithHeader("X-API-Key", f.bookingAPIKey)}, want: http.StatusCreated},
+		{name: "booking with API key missing scope", route: "POST /api/v1/bookings", path: "/api/v1/bookings", body: bookingBody,
+			opts: []apitest.RequestOption{apitest.WithHeader("X-API-Key", f.apiKey)}, want: http.StatusForbidden},
+		{name: "booking without service", route: "POST /api/v1/bookings", path: "/api/v1/bookings",
+			body: map[string]interface{}{"user_name": "Rahim"}, opts: as(f.customer), want: http.StatusBadRequest},
+		{name: "booking of missing service", route: "POST /api/v1/bookings", path: "/api/v1/bookings",
+			body: map[string]interface{}{"service_id": 999999, "user_name": "Rahim"}, opts: as(f.customer), want: http.StatusBadRequest},
+		{name: "verify guest phone", route: "POST /api/v1/bookings/guest/verify", path: "/api/v1/bookings/guest/verify",
+			body: map[string]string{"phone": guestPhone, "code": h.CreateOTP(guestPhone)}, want: http.StatusOK},
+		{name: "verify guest phone with wrong code", route: "POST /api/v1/bookings/guest/verify", path: "/api/v1/bookings/guest/verify",
+			body: map[string]string{"phone": otpPhone, "code": "000000"}, want: http.StatusUnauthorized},
+		{name: "open booking link", route: "GET /api/v1/bookings/reference/:code",
+			path: "/api/v1/bookings/reference/" + f.guestBooking.BookingReferenceCode + "?token=" + url.QueryEscape(h.BookingLinkToken(f.guestBooking)), want: http.StatusOK},
+		{name: "open booking link signed with the token secret", route: "GET /api/v1/bookings/reference/:code",
+			path: "/api/v1/bookings/reference/" + f.guestBooking.BookingReferenceCode + "?token=" + url.QueryEscape(f.forgedLinkToken), want: http.StatusNotFound},
+		{name: "open booking link without token", route: "GET /api/v1/bookings/reference/:code",
+			path: "/api/v1/bookings/reference/" + f.guestBooking.BookingReferenceCode, want: http.StatusBadRequest},
+		{name: "open booking link of another booking", route: "GET /api/v1/bookings/reference/:code",
+			path: "/api/v1/bookings/reference/" + f.guestBooking.BookingReferenceCode + "?token=" + url.QueryEscape(h.BookingLinkToken(f.booking)), want: http.StatusNotFound},
+		{name: "look up booking by phone", route: "POST /api/v1/bookings/reference/:code", path: "/api/v1/bookings/reference/" + f.guestBooking.BookingReferenceCode,
+			body: map[string]string{"phone": guestPhone}, want: http.StatusOK},
+		{name: "look up booking with wrong phone", route: "POST /api/v1/bookings/reference/:code", path: "/api/v1/bookings/reference/" + f.guestBooking.BookingReferenceCode,
+			body: map[string]string{"phone": otpPhone}, want: http.StatusNotFound},
+
+		// Authentication
+		{name: "register", route: "POST /api/v1/auth/register", path: "/api/v1/auth/register",
+			body: map[string]string{"name": "New User", "email": "new@example.com", "password": "password123"}, want: http.StatusCreated},
+		{name: "register taken email", route: "POST /api/v1/auth/register", path: "/api/v1/auth/register",
+			body: map[string]string{"name": "New User", "email": f.customer.Email, "password": "password123"}, want: http.StatusConflict},
+		{name: "register with short password", route: "POST /api/v1/auth/register", path: "/api/v1/auth/register",
+			body: map[string]string{"name": "New User", "email": "short@example.com", "password": "short"}, want: http.StatusBadRequest},
+		{name: "login", route: "POST /api/v1/auth/login", path: "/api/v1/auth/login",
+			body: map[string]string{"email": f.customer.Email, "password": apitest.DefaultPassword}, want: http.StatusOK},
+		{name: "login with wrong password", route: "POST /api/v1/auth/login", path: "/api/v1/auth/login",
+			body: map[string]string{"email": f.customer.Email, "password": "wrong-password"}, want: http.StatusUnauthorized},
+		{name: "verify second factor with invalid token", route: "POST /api/v1/auth/mfa/verify", path: "/api/v1/auth/mfa/verify",
+			body: map[string]string{"mfa_token": "invalid", "code": "123456"}, want: http.StatusUnauthorized},
+		{name: "request login code", route: "POST /api/v1/auth/otp/request", path: "/api/v1/auth/otp/request",
+			body: map[string]string{"phone": "01744444444"}, want: http.StatusOK},
+		{name: "request login code for invalid phone", route: "POST /api/v1/auth/otp/request", path: "/api/v1/auth/otp/request",
+			body: map[string]string{"phone": "12"}, want: http.StatusBadRequest},
+		{name: "verify login code", route: "POST /api/v1/auth/otp/verify", path: "/api/v1/auth/otp/verify",
+			body: map[string]string{"phone": otpPhone, "code": h.CreateOTP(otpPhone)}, want: http.StatusOK},
+		{name: "verify wrong login code", route: "POST /api/v1/auth/otp/verify", path: "/api/v1/auth/otp/verify",
+			body: map[string]string{"phone": customerPhone, "code": "000000"}, want: http.StatusUnauthorized},
+		{name: "list social login providers", route: "GET /api/v1/auth/oidc/providers", path: "/api/v1/auth/oidc/providers", want: http.StatusOK},
+		{name: "social login with unknown provider", route: "GET /api/v1/auth/oidc/:provider/login", path: "/api/v1/auth/oidc/unknown/login", want: http.StatusNotFound},
+		{name: "social login callback without code", route: "GET /api/v1/auth/oidc/:provider/callback", path: "/api/v1/auth/oidc/unknown/callback", want: http.StatusBadRequest},
+		{name: "link social login of unknown provider", route: "POST /api/v1/profile/oidc/:provider/link", path: "/api/v1/profile/oidc/unknown/link", opts: as(f.customer), want: http.StatusNotFound},
+		{name: "link social login without second factor", route: "POST /api/v1/profile/oidc/:provider/link", path: "/api/v1/profile/oidc/unknown/link",
+			opts: []apitest.RequestOption{apitest.WithToken(f.adminWithoutMFA)}, want: http.StatusForbidden},
+		{name: "refresh tokens", route: "POST /api/v1/auth/refresh", path: "/api/v1/auth/refresh",
+			opts: []apitest.RequestOption{apitest.WithHeader("X-Refresh-Token", h.Login(f.customer).Refresh)}, want: http.StatusOK},
+		{name: "refresh without token", route: "POST /api/v1/auth/refresh", path: "/api/v1/auth/refresh", want: http.StatusUnauthorized},
+		{name: "refresh with access token", route: "POST /api/v1/auth/refresh", path: "/api/v1/auth/refresh",
+			opts: []apitest.RequestOption{apitest.WithHeader("X-Refresh-Token", h.Token(f.customer))}, want: http.StatusUnauthorized},
+
+		// Profile
+		{name: "profile without token", route: "GET /api/v1/profile", path: "/api/v1/profile", want: http.StatusUnauthorized},
+		{name: "profile with invalid token", route: "GET /api/v1/profile", path: "/api/v1/profile",
+			opts: []apitest.RequestOption{apitest.WithToken("invalid")}, want: http.StatusUnauthorized},
+		{name: "profile", route: "GET /api/v1/profile", path: "/api/v1/profile", opts: as(f.customer), want: http.StatusOK},
+		{name: "profile with API key", route: "GET /api/v1/profile", path: "/api/v1/profile",
+			opts: []apitest.RequestOption{apitest.WithHeader("X-API-Key", f.apiKey)}, want: http.StatusForbidden},
+		{name: "update profile", route: "PUT /api/v1/profile", path: "/api/v1/profile",
+			body: map[string]string{"name": "Renamed Customer"}, opts: as(f.customer), want: http.StatusOK},
+		{name: "update profile without token", route: "PUT /api/v1/profile", path: "/api/v1/profile",
+			body: map[string]string{"name": "Renamed Customer"}, want: http.StatusUnauthorized},
+		{name: "change password", route: "POST /api/v1/profile/change-password", path: "/api/v1/profile/change-password",
+			body: map[string]string{"current_password": apitest.DefaultPassword, "new_password": "new-password123"}, opts: as(f.password), want: http.StatusOK},
+		{name: "change password with wrong current password", route: "POST /api/v1/profile/change-password", path: "/api/v1/profile/change-password",
+			body: map[string]string{"current_password": "wrong-password", "new_password": "new-password123"}, opts: as(f.customer), want: http.StatusBadRequest},
+		{name: "list sessions", route: "GET /api/v1/profile/sessions", path: "/api/v1/profile/sessions", opts: as(f.customer), want: http.StatusOK},
+		{name: "revoke session of another user", route: "DELETE /api/v1/profile/sessions/:id", path: id("/api/v1/profile/sessions/%d", f.sessionID),
+			opts: as(f.customer), want: http.StatusNotFound},
+		{name: "revoke session", route: "DELETE /api/v1/profile/sessions/:id", path: id("/api/v1/profile/sessions/%d", f.sessionID),
+			opts: as(f.other), want: http.StatusOK},
+		{name: "begin two-factor enrollment", route: "POST /api/v1/profile/mfa/totp/enroll", path: "/api/v1/profile/mfa/totp/enroll", opts: as(f.customer), want: http.StatusOK},
+		{name: "confirm two-factor enrollment with wrong code", route: "POST /api/v1/profile/mfa/totp/confirm", path: "/api/v1/profile/mfa/totp/confirm",
+			body: map[string]string{"code": "000000"}, opts: as(f.customer), want: http.StatusUnauthorized},
+		{name: "disable two-factor when not enrolled", route: "POST /api/v1/profile/mfa/totp/disable", path: "/api/v1/profile/mfa/totp/disable",
+			body: map[string]string{"code": "000000"}, opts: as(f.customer), want: http.StatusConflict},
+		{name: "regenerate recovery codes with wrong code", route: "POST /api/v1/profile/mfa/recovery-codes", path: "/api/v1/profile/mfa/recovery-codes",
+			body: map[string]string{"code": "000000"}, opts: as(f.admin), want: http.StatusUnauthorized},
+
+		// Bookings of the authenticated user
+		{name: "list own bookings", route: "GET /api/v1/bookings", path: "/api/v1/bookings", opts: as(f.customer), want: http.StatusOK},
+		{name: "list bookings with API key", route: "GET /api/v1/bookings", path: "/api/v1/bookings",
+			opts: []apitest.RequestOption{apitest.WithHeader("X-API-Key", f.apiKey)}, want: http.StatusOK},
+		{name: "cancel booking with API key missing scope", route: "DELETE /api/v1/bookings/:id", path: id("/api/v1/bookings/%d", f.otherBooking.ID),
+			opts: []apitest.RequestOption{apitest.WithHeader("X-API-Key", f.apiKey)}, want: http.StatusForbidden},
+		{name: "list bookings without token", route: "GET /api/v1/bookings", path: "/api/v1/bookings", want: http.StatusUnauthorized},
+		{name: "get own booking", route: "GET /api/v1/bookings/:id", path: id("/api/v1/bookings/%d", f.booking.ID), opts: as(f.customer), want: http.StatusOK},
+		{name: "get booking of another user", route: "GET /api/v1/bookings/:id", path: id("/api/v1/bookings/%d", f.otherBooking.ID), opts: as(f.customer), want: http.StatusNotFound},
+		{name: "staff get booking", route: "GET /api/v1/bookings/:id", path: id("/api/v1/bookings/%d", f.otherBooking.ID), opts: as(f.support), want: http.StatusOK},
+		{name: "customer confirms own booking", route: "PUT /api/v1/bookings/:id/status", path: id("/api/v1/bookings/%d/status", f.booking.ID),
+			body: map[string]string{"status": "confirmed"}, opts: as(f.customer), want: http.StatusForbidden},
+		{name: "set invalid booking status", route: "PUT /api/v1/bookings/:id/status", path: id("/api/v1/bookings/%d/status", f.booking.ID),
+			body: map[string]string{"status": "unknown"}, opts: as(f.support), want: http.StatusBadRequest},
+		{name: "cancel booking of another user", route: "DELETE /api/v1/bookings/:id", path: id("/api/v1/bookings/%d", f.otherBooking.ID), opts: as(f.customer), want: http.StatusNotFound},
+		{name: "cancel own booking", route: "DELETE /api/v1/bookings/:id", path: id("/api/v1/bookings/%d", f.cancellable.ID),
+			body: map[string]string{"reason": "Plans changed"}, opts: as(f.customer), want: http.StatusOK},
+
+		// Admin services
+		{name: "create service without token", route: "POST /api/v1/admin/services", path: "/api/v1/admin/services",
+			body: map[string]interface{}{"name": "AC repair", "price": 800, "category_id": f.category.ID}, want: http.StatusUnauthorized},
+		{name: "customer creates service", route: "POST /api/v1/admin/services", path: "/api/v1/admin/services",
+			body: map[string]interface{}{"name": "AC repair", "price": 800, "category_id": f.category.ID}, opts: as(f.customer), want: http.StatusForbidden},
+		{name: "admin without second factor creates service", route: "POST /api/v1/admin/services", path: "/api/v1/admin/services",
+			body: map[string]interface{}{"name": "AC repair", "price": 800, "category_id": f.category.ID},
+			opts: []apitest.RequestOption{apitest.WithToken(f.adminWithoutMFA)}, want: http.StatusForbidden},
+		{name: "create service", route: "POST /api/v1/admin/services", path: "/api/v1/admin/services",
+			body: map[string]interface{}{"name": "AC repair", "price": 800, "category_id": f.category.ID}, opts: as(f.admin), want: http.StatusCreated},
+		{name: "create service in missing category", route: "POST /api/v1/admin/services", path: "/api/v1/admin/services",
+			body: map[string]interface{}{"name": "AC repair", "price": 800, "category_id": 999999}, opts: as(f.admin), want: http.StatusBadRequest},
+		{name: "update service", route: "PUT /api/v1/admin/services/:id", path: id("/api/v1/admin/services/%d", f.service.ID),
+			body: map[string]interface{}{"name": "Home cleaning", "price": 650, "category_id": f.category.ID, "is_active": true}, opts: as(f.admin), want: http.StatusOK},
+		{name: "update missing service", route: "PUT /api/v1/admin/services/:id", path: "/api/v1/admin/services/999999",
+			body: map[string]interface{}{"name": "Home cleaning", "price": 650, "category_id": f.category.ID}, opts: as(f.admin), want: http.StatusNotFound},
+		{name: "support deletes service", route: "DELETE /api/v1/admin/services/:id", path: id("/api/v1/admin/services/%d", f.spareService.ID), opts: as(f.support), want: http.StatusForbidden},
+		{name: "delete service", route: "DELETE /api/v1/admin/services/:id", path: id("/api/v1/admin/services/%d", f.spareService.ID), opts: as(f.admin), want: http.StatusOK},
+
+		// Admin bookings
+		{name: "admin lists bookings", route: "GET /api/v1/admin/bookings", path: "/api/v1/admin/bookings", opts: as(f.admin), want: http.StatusOK},
+		{name: "support lists bookings", route: "GET /api/v1/admin/bookings", path: "/api/v1/admin/bookings", opts: as(f.support), want: http.StatusOK},
+		{name: "customer lists all bookings", route: "GET /api/v1/admin/bookings", path: "/api/v1/admin/bookings", opts: as(f.customer), want: http.StatusForbidden},
+		{name: "support confirms booking", route: "PUT /api/v1/admin/bookings/:id/status", path: id("/api/v1/admin/bookings/%d/status", f.booking.ID),
+			body: map[string]string{"status": "confirmed"}, opts: as(f.support), want: http.StatusOK},
+		{name: "provider sets status through admin route", route: "PUT /api/v1/admin/bookings/:id/status", path: id("/api/v1/admin/bookings/%d/status", f.booking.ID),
+			body: map[string]string{"status": "completed"}, opts: as(f.provider), want: http.StatusForbidden},
+		{name: "assign provider", route: "PUT /api/v1/admin/bookings/:id/provider", path: id("/api/v1/admin/bookings/%d/provider", f.booking.ID),
+			body: map[string]uint{"provider_id": f.provider.ID}, opts: as(f.admin), want: http.StatusOK},
+		{name: "assign customer as provider", route: "PUT /api/v1/admin/bookings/:id/provider", path: id("/api/v1/admin/bookings/%d/provider", f.booking.ID),
+			body: map[string]uint{"provider_id": f.other.ID}, opts: as(f.admin), want: http.StatusBadRequest},
+		{name: "assign provider to missing booking", route: "PUT /api/v1/admin/bookings/:id/provider", path: "/api/v1/admin/bookings/999999/provider",
+			body: map[string]uint{"provider_id": f.provider.ID}, opts: as(f.admin), want: http.StatusNotFound},
+
+		// Admin categories
+		{name: "create category", route: "POST /api/v1/admin/categories", path: "/api/v1/admin/categories",
+			body: map[string]interface{}{"name": "Plumbing", "is_active": true}, opts: as(f.admin), want: http.StatusCreated},
+		{name: "create category with taken name", route: "POST /api/v1/admin/categories", path: "/api/v1/admin/categories",
+			body: map[string]interface{}{"name": f.category.Name}, opts: as(f.admin), want: http.StatusConflict},
+		{name: "customer creates category", route: "POST /api/v1/admin/categories", path: "/api/v1/admin/categories",
+			body: map[string]interface{}{"name": "Painting"}, opts: as(f.customer), want: http.StatusForbidden},
+		{name: "update category", route: "PUT /api/v1/admin/categories/:id", path: id("/api/v1/admin/categories/%d", f.category.ID),
+			body: map[string]interface{}{"name": "Cleaning", "is_active": true}, opts: as(f.admin), want: http.StatusOK},
+		{name: "delete category with subcategories", route: "DELETE /api/v1/admin/categories/:id", path: id("/api/v1/admin/categories/%d", f.category.ID), opts: as(f.admin), want: http.StatusConflict},
+		{name: "delete category", route: "DELETE /api/v1/admin/categories/:id", path: id("/api/v1/admin/categories/%d", f.emptyCategory.ID), opts: as(f.admin), want: http.StatusOK},
+
+		// Admin roles and users
+		{name: "list roles", route: "GET /api/v1/admin/roles", path: "/api/v1/admin/roles", opts: as(f.admin), want: http.StatusOK},
+		{name: "support lists roles", route: "GET /api/v1/admin/roles", path: "/api/v1/admin/roles", opts: as(f.support), want: http.StatusForbidden},
+		{name: "get role", route: "GET /api/v1/admin/roles/:id", path: id("/api/v1/admin/roles/%d", f.customRole.ID), opts: as(f.admin), want: http.StatusOK},
+		{name: "get missing role", route: "GET /api/v1/admin/roles/:id", path: "/api/v1/admin/roles/999999", opts: as(f.admin), want: http.StatusNotFound},
+		{name: "create role", route: "POST /api/v1/admin/roles", path: "/api/v1/admin/roles",
+			body: map[string]interface{}{"name": "dispatcher", "permissions": []string{"booking:read"}}, opts: as(f.admin), want: http.StatusCreated},
+		{name: "create role with unknown permission", route: "POST /api/v1/admin/roles", path: "/api/v1/admin/roles",
+			body: map[string]interface{}{"name": "hacker", "permissions": []string{"everything"}}, opts: as(f.admin), want: http.StatusBadRequest},
+		{name: "create existing role", route: "POST /api/v1/admin/roles", path: "/api/v1/admin/roles",
+			body: map[string]interface{}{"name": "support"}, opts: as(f.admin), want: http.StatusConflict},
+		{name: "update role", route: "PUT /api/v1/admin/roles/:id", path: id("/api/v1/admin/roles/%d", f.customRole.ID),
+			body: map[string]interface{}{"name": "auditor", "permissions": []string{"booking:read", "refund:approve"}}, opts: as(f.admin), want: http.StatusOK},
+		{name: "assign role", route: "PUT /api/v1/admin/users/:id/role", path: id("/api/v1/admin/users/%d/role", f.other.ID),
+			body: map[string]string{"role": "auditor"}, opts: as(f.admin), want: http.StatusOK},
+		{name: "assign unknown role", route: "PUT /api/v1/admin/users/:id/role", path: id("/api/v1/admin/users/%d/role", f.other.ID),
+			body: map[string]string{"role": "unknown"}, opts: as(f.admin), want: http.StatusBadRequest},
+		{name: "delete role assigned to users", route: "DELETE /api/v1/admin/roles/:id", path: id("/api/v1/admin/roles/%d", f.customRole.ID), opts: as(f.admin), want: http.StatusConflict},
+		{name: "delete system role", route: "DELETE /api/v1/admin/roles/:id", path: id("/api/v1/admin/roles/%d", systemRoleID(h)), opts: as(f.admin), want: http.StatusForbidden},
+		{name: "support unlocks user", route: "POST /api/v1/admin/users/:id/unlock", path: id("/api/v1/admin/users/%d/unlock", f.locked.ID), opts: as(f.support), want: http.StatusOK},
+		{name: "customer unlocks user", route: "POST /api/v1/admin/users/:id/unlock", path: id("/api/v1/admin/users/%d/unlock", f.locked.ID), opts: as(f.customer), want: http.StatusForbidden},
+
+		// Admin API keys
+		{name: "list API keys", route: "GET /api/v1/admin/api-keys", path: "/api/v1/admin/api-keys", opts: as(f.admin), want: http.StatusOK},
+		{name: "issue API key", route: "POST /api/v1/admin/api-keys", path: "/api/v1/admin/api-keys",
+			body: map[string]interface{}{"name": "Second", "organization": "Partner Ltd", "user_id": f.other.ID}, opts: as(f.admin), want: http.StatusCreated},
+		{name: "issue API key for admin", route: "POST /api/v1/admin/api-keys", path: "/api/v1/admin/api-keys",
+			body: map[string]interface{}{"name": "Second", "organization": "Partner Ltd", "user_id": f.admin.ID}, opts: as(f.admin), want: http.StatusBadRequest},
+		{name: "issue API key for missing user", route: "POST /api/v1/admin/api-keys", path: "/api/v1/admin/api-keys",
+			body: map[string]interface{}{"name": "Second", "organization": "Partner Ltd", "user_id": 999999}, opts: as(f.admin), want: http.StatusBadRequest},
+		{name: "API key cannot manage API keys", route: "GET /api/v1/admin/api-keys", path: "/api/v1/admin/api-keys",
+			opts: []apitest.RequestOption{apitest.WithHeader("X-API-Key", f.apiKey)}, want: http.StatusForbidden},
+		{name: "revoke API key", route: "DELETE /api/v1/admin/api-keys/:id", path: id("/api/v1/admin/api-keys/%d", f.apiKeyID), opts: as(f.admin), want: http.StatusOK},
+		{name: "use revoked API key", route: "GET /api/v1/bookings", path: "/api/v1/bookings",
+			opts: []apitest.RequestOption{apitest.WithHeader("X-API-Key", f.apiKey)}, want: http.StatusUnauthorized},
+		{name: "revoke missing API key", route: "DELETE /api/v1/admin/api-keys/:id", path: "/api/v1/admin/api-keys/999999", opts: as(f.admin), want: http.StatusNotFound},
+	}
+}
+
+// forgeBookingLinkToken signs a booking link token with the secret of the
+// login tokens instead of the key derived for booking links
+func forgeBookingLinkToken(t *testing.T, h *apitest.Harness, booking *model.Booking) string {
+	t.Helper()
+
+	claims := jwt.MapClaims{
+		"ref": booking.BookingReferenceCode,
+		"sub": strconv.FormatUint(uint64(booking.ID), 10),
+		"aud": "booking-access",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(h.App.Tokens.Config().SecretKey))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+// systemRoleID returns the ID of a built-in role seeded by the migrations
+func systemRoleID(h *apitest.Harness) uint {
+	var role model.Role
+	h.DB.Where("name = ?", model.UserRoleSupport).First(&role)
+	return role.ID
+}
+
+func TestRoutes(t *testing.T) {
+	h := apitest.New(t)
+	f := newFixtures(t, h)
+	cases := routeCases(h, f)
+
+	for _, tc := range cases {
+		method := strings.SplitN(tc.route, " ", 2)[0]
+		t.Run(tc.name, func(t *testing.T) {
+			resp := h.Do(method, tc.path, tc.body, tc.opts...)
+			if resp.Code != tc.want {
+				t.Errorf("%s %s: got status %d, want %d: %s", method, tc.path, resp.Code, tc.want, resp.Body)
+			}
+		})
+	}
+
+	// New routes must be added to the cases above
+	t.Run("every route is covered", func(t *testing.T) {
+		covered := make(map[string]bool)
+		for _, tc := range cases {
+			covered[tc.route] = true
+		}
+
+		var missing []string
+		for _, route := range h.Router.Routes() {
+			if key := route.Method + " " + route.Path; !covered[key] {
+				missing = append(missing, key)
+			}
+		}
+		sort.Strings(missing)
+		if len(missing) > 0 {
+			t.Errorf("routes without test cases:\n%s", strings.Join(missing, "\n"))
+		}
+	})
+}
+
+func TestErrorResponses(t *testing.T) {
+	h := apitest.New(t)
+
+	resp := h.Do(http.MethodPost, "/api/v1/auth/register", map[string]string{"email": "not-an-email"})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d, want %d", resp.Code, http.StatusBadRequest)
+	}
+
+	var body struct {
+		Code   string `json:"code"`
+		Fields []struct {
+			Field string `json:"field"`
+			Rule  string `json:"rule"`
+		} `json:"fields"`
+	}
+	resp.Decode(t, &body)
+	if body.Code != "validation_failed" {
+		t.Errorf("got code %q, want validation_failed", body.Code)
+	}
+
+	rules := make(map[string]string)
+	for _, field := range body.Fields {
+		rules[field.Field] = field.Rule
+	}
+	want := map[string]string{"name": "required", "email": "email", "password": "required"}
+	for field, rule := range want {
+		if rules[field] != rule {
+			t.Errorf("field %s: got rule %q, want %q", field, rules[field], rule)
+		}
+	}
+}
+
+func TestOTPAttempts(t *testing.T) {
+	h := apitest.New(t)
+	const phone = "+8801755555555"
+	verify := func(code string) int {
+		return h.Do(http.MethodPost, "/api/v1/auth/otp/verify", map[string]string{"phone": phone, "code": code}).Code
+	}
+
+	// A code is good for one login
+	code := h.CreateOTP(phone)
+	if status := verify(code); status != http.StatusOK {
+		t.Fatalf("first use: got status %d, want %d", status, http.StatusOK)
+	}
+	if status := verify(code); status != http.StatusUnauthorized {
+		t.Errorf("second use: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	// Wrong guesses use up the code
+	code = h.CreateOTP(phone)
+	for i := 0; i < h.App.Config.OTP.MaxAttempts; i++ {
+		if status := verify("000000"); status != http.StatusUnauthorized {
+			t.Fatalf("guess %d: got status %d, want %d", i+1, status, http.StatusUnauthorized)
+		}
+	}
+	if status := verify(code); status != http.StatusUnauthorized {
+		t.Errorf("correct code after too many guesses: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestGuestBookingClaim(t *testing.T) {
+	h := apitest.New(t)
+	const phone = "+8801777777777"
+	booking := h.CreateBooking(h.CreateService(h.CreateCategory(nil)), nil, phone)
+
+	// Registering with someone else's number does not verify it
+	resp := h.Do(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"name": "Squatter", "email": "squatter@example.com", "password": "password123", "phone": phone,
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("register: got status %d: %s", resp.Code, resp.Body)
+	}
+
+	// The owner of the number logs in with a code and gets the bookings
+	resp = h.Do(http.MethodPost, "/api/v1/auth/otp/verify", map[string]string{"phone": phone, "code": h.CreateOTP(phone)})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("phone login: got status %d: %s", resp.Code, resp.Body)
+	}
+	var login struct {
+		User struct {
+			Email string `json:"email"`
+		} `json:"user"`
+	}
+	resp.Decode(t, &login)
+	if login.User.Email == "squatter@example.com" {
+		t.Fatalf("phone login entered the account that only registered the number")
+	}
+
+	var claimed model.Booking
+	if err := h.DB.Preload("User").First(&claimed, booking.ID).Error; err != nil {
+		t.Fatalf("failed to load booking: %v", err)
+	}
+	if claimed.User == nil || claimed.User.Email != login.User.Email {
+		t.Errorf("guest booking was not claimed into the account of the verified phone")
+	}
+}
+
+func TestBookingLink(t *testing.T) {
+	h := apitest.New(t)
+	sender := h.App.SMS.(*sms.FakeSender)
+	svc := h.CreateService(h.CreateCategory(nil))
+	const guest, stranger = "+8801788888888", "+8801799999999"
+
+	// Account holders cannot text a link to any number they type in
+	resp := h.Do(http.MethodPost, "/api/v1/bookings", map[string]interface{}{"service_id": svc.ID, "user_name": "Rahim", "phone_number": stranger},
+		apitest.WithToken(h.Token(h.CreateUser())))
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("user booking: got status %d: %s", resp.Code, resp.Body)
+	}
+	if _, sent := sender.LastMessage(stranger); sent {
+		t.Errorf("user booking texted a link to the number in the request")
+	}
+
+	// Guests get the link on the phone they verified
+	resp = h.Do(http.MethodPost, "/api/v1/bookings", map[string]interface{}{"service_id": svc.ID, "user_name": "Rahim", "phone_number": stranger},
+		apitest.WithHeader("X-Guest-Token", h.GuestToken(guest)))
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("guest booking: got status %d: %s", resp.Code, resp.Body)
+	}
+	if _, sent := sender.LastMessage(guest); !sent {
+		t.Errorf("guest booking did not text a link to the verified phone")
+	}
+	if _, sent := sender.LastMessage(stranger); sent {
+		t.Errorf("guest booking texted a link to the number in the request")
+	}
+}
+
+func TestSessionRefresh(t *testing.T) {
+	h := apitest.New(t)
+	user := h.CreateUser()
+	refresh := func(token string) (int, string) {
+		resp := h.Do(http.MethodPost, "/api/v1/auth/refresh", nil, apitest.WithHeader("X-Refresh-Token", token))
+		var tokens struct {
+			AccessToken  string `json:"access_token"`
+			RefreshToken string `json:"refresh_token"`
+		}
+		if resp.Code == http.StatusOK {
+			resp.Decode(t, &tokens)
+		}
+		return resp.Code, tokens.RefreshToken
+	}
+
+	// Refresh tokens without a session are refused
+	_, sessionless, err := h.App.Tokens.GenerateAllTokens(user.ID, user.Email, user.Name, string(user.Role))
+	if err != nil {
+		t.Fatalf("failed to generate tokens: %v", err)
+	}
+	if status, _ := refresh(sessionless); status != http.StatusUnauthorized {
+		t.Errorf("refresh without session: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	// Refreshing picks up changes to the user
+	first := h.Login(user).Refresh
+	if err := h.DB.Model(user).Update("role", model.UserRoleSupport).Error; err != nil {
+		t.Fatalf("failed to change role: %v", err)
+	}
+	status, second := refresh(first)
+	if status != http.StatusOK {
+		t.Fatalf("refresh: got status %d, want %d", status, http.StatusOK)
+	}
+	claims, err := h.App.Tokens.ValidateToken(second)
+	if err != nil || claims.Role != string(model.UserRoleSupport) {
+		t.Errorf("refreshed token does not carry the new role")
+	}
+
+	// Reusing a replaced refresh token ends the session
+	if status, _ := refresh(first); status != http.StatusUnauthorized {
+		t.Errorf("reused refresh token: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status, _ := refresh(second); status != http.StatusUnauthorized {
+		t.Errorf("refresh after reuse: got status %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	h := apitest.New(t)
+	body := map[string]interface{}{"service_id": h.CreateService(h.CreateCategory(nil)).ID, "user_name": "Rahim"}
+	const first, second = "+8801712121212", "+8801713131313"
+	guestTokens := map[string]string{first: h.GuestToken(first), second: h.GuestToken(second)}
+	book := func(phone string) (*apitest.Response, model.Booking) {
+		resp := h.Do(http.MethodPost, "/api/v1/bookings", body,
+			apitest.WithHeader("X-Guest-Token", guestTokens[phone]), apitest.WithHeader("Idempotency-Key", "retry-1"))
+		var booking model.Booking
+		resp.Decode(t, &booking)
+		return resp, booking
+	}
+
+	// A retry gets the stored response
+	resp, booked := book(first)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("booking: got status %d: %s", resp.Code, resp.Body)
+	}
+	resp, replayed := book(first)
+	if resp.Header.Get("Idempotent-Replayed") != "true" || replayed.ID != booked.ID {
+		t.Errorf("retry was not answered with the stored response")
+	}
+
+	// Other guests using the same key get their own booking
+	resp, other := book(second)
+	if resp.Code != http.StatusCreated || other.ID == booked.ID || other.PhoneNumber != second {
+		t.Errorf("another guest got the response of the first: %s", resp.Body)
+	}
+
+	// Stored responses do not reveal the booking
+	var records []model.IdempotencyKey
+	h.DB.Find(&records)
+	for _, record := range records {
+		if strings.Contains(record.ResponseBody, first) || strings.Contains(record.Scope, first) {
+			t.Errorf("stored idempotency record contains the phone number")
+		}
+	}
+
+	// A request that never completed holds its key until its lease ends
+	inFlight := func(lockedUntil time.Time) {
+		err := h.DB.Model(&model.IdempotencyKey{}).Where("scope LIKE ?", "%guest:%").
+			Updates(map[string]interface{}{"completed_at": nil, "locked_until": lockedUntil}).Error
+		if err != nil {
+			t.Fatalf("failed to reset idempotency records: %v", err)
+		}
+	}
+	inFlight(time.Now().Add(time.Minute))
+	if resp, _ := book(first); resp.Code != http.StatusConflict {
+		t.Errorf("retry during the lease: got status %d, want %d: %s", resp.Code, http.StatusConflict, resp.Body)
+	}
+	inFlight(time.Now().Add(-time.Second))
+	resp, retried := book(first)
+	if resp.Code != http.StatusCreated || resp.Header.Get("Idempotent-Replayed") != "" || retried.ID == booked.ID {
+		t.Errorf("retry after the lease: got status %d, want the request processed again: %s", resp.Code, resp.Body)
+	}
+	if resp, replayed := book(first); resp.Header.Get("Idempotent-Replayed") != "true" || replayed.ID != retried.ID {
+		t.Errorf("the retried response was not stored")
+	}
+}
+
+func TestProviderStatusChanges(t *testing.T) {
+	h := apitest.New(t)
+	provider := h.CreateUser(apitest.WithRole(model.UserRoleProvider))
+	booking := h.CreateBooking(h.CreateService(h.CreateCategory(nil)), h.CreateUser(), "")
+	if err := h.DB.Model(booking).Updates(map[string]interface{}{"provider_id": provider.ID, "status": model.BookingStatusConfirmed}).Error; err != nil {
+		t.Fatalf("failed to assign provider: %v", err)
+	}
+	token := h.Token(provider)
+	path := fmt.Sprintf("/api/v1/bookings/%d/status", booking.ID)
+
+	// Providers move bookings forward one step at a time and cannot cancel them
+	steps := []struct {
+		status string
+		want   int
+	}{
+		{"cancelled", http.StatusForbidden},
+		{"completed", http.StatusForbidden},
+		{"in_progress", http.StatusOK},
+		{"confirmed", http.StatusForbidden},
+		{"completed", http.StatusOK},
+		{"pending", http.StatusForbidden},
+	}
+	for _, step := range steps {
+		resp := h.Do(http.MethodPut, path, map[string]string{"status": step.status}, apitest.WithToken(token))
+		if resp.Code != step.want {
+			t.Errorf("set %s: got status %d, want %d: %s", step.status, resp.Code, step.want, resp.Body)
+		}
+	}
+}
+
+func TestOwnerCancellation(t *testing.T) {
+	h := apitest.New(t)
+	customer := h.CreateUser()
+	svc := h.CreateService(h.CreateCategory(nil))
+	token := h.Token(customer)
+
+	// Owners may only cancel bookings the provider has not started
+	statuses := []struct {
+		status model.BookingStatus
+		want   int
+	}{
+		{model.BookingStatusPending, http.StatusOK},
+		{model.BookingStatusConfirmed, http.StatusOK},
+		{model.BookingStatusInProgress, http.StatusForbidden},
+		{model.BookingStatusCompleted, http.StatusForbidden},
+	}
+	for _, tc := range statuses {
+		booking := h.CreateBooking(svc, customer, "")
+		if err := h.DB.Model(booking).Update("status", tc.status).Error; err != nil {
+			t.Fatalf("failed to set status: %v", err)
+		}
+
+		resp := h.Do(http.MethodDelete, fmt.Sprintf("/api/v1/bookings/%d", booking.ID), nil, apitest.WithToken(token))
+		if resp.Code != tc.want {
+			t.Errorf("cancel %s booking: got status %d, want %d: %s", tc.status, resp.Code, tc.want, resp.Body)
+		}
+	}
+}
+
+func TestSecondFactor(t *testing.T) {
+	h := apitest.New(t)
+
+	// Phone login cannot skip the second factor of an enrolled user
+	const phone = "+8801766666666"
+	h.CreateUser(apitest.WithPhone(phone), apitest.WithTOTP())
+	resp := h.Do(http.MethodPost, "/api/v1/auth/otp/verify", map[string]string{"phone": phone, "code": h.CreateOTP(phone)})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("phone login: got status %d: %s", resp.Code, resp.Body)
+	}
+	var login struct {
+		MFARequired bool   `json:"mfa_required"`
+		AccessToken string `json:"access_token"`
+	}
+	resp.Decode(t, &login)
+	if !login.MFARequired || login.AccessToken != "" {
+		t.Errorf("phone login of an enrolled user must be challenged: %s", resp.Body)
+	}
+
+	// Admins without a second factor have no staff rights on bookings
+	admin := h.CreateUser(apitest.WithRole(model.UserRoleAdmin))
+	booking := h.CreateBooking(h.CreateService(h.CreateCategory(nil)), h.CreateUser(), "")
+	token := h.Token(admin)
+
+	resp = h.Do(http.MethodPut, fmt.Sprintf("/api/v1/bookings/%d/status", booking.ID), map[string]string{"status": "cancelled"}, apitest.WithToken(token))
+	if resp.Code != http.StatusNotFound {
+		t.Errorf("status change: got status %d, want %d: %s", resp.Code, http.StatusNotFound, resp.Body)
+	}
+	resp = h.Do(http.MethodGet, "/api/v1/bookings", nil, apitest.WithToken(token))
+	var list struct {
+		Data []model.Booking `json:"data"`
+	}
+	resp.Decode(t, &list)
+	if resp.Code != http.StatusOK || len(list.Data) != 0 {
+		t.Errorf("listing: got status %d with %d bookings, want only own bookings", resp.Code, len(list.Data))
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	h := apitest.New(t, apitest.WithConfig(func(cfg *config.Config) {
+		cfg.Database.ReadTimeout = "1ns"
+	}))
+
+	resp := h.Do(http.MethodGet, "/api/v1/services", nil)
+	if resp.Code != http.StatusGatewayTimeout {
+		t.Fatalf("got status %d, want %d: %s", resp.Code, http.StatusGatewayTimeout, resp.Body)
+	}
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	resp.Decode(t, &body)
+	if body.Code != "timeout" {
+		t.Errorf("got code %q, want timeout", body.Code)
+	}
+}
+
+func TestRateLimitClientIP(t *testing.T) {
+	lookups := func(h *apitest.Harness) []int {
+		var codes []int
+		for i := 0; i < 3; i++ {
+			forwardedFor := apitest.WithHeader("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+			codes = append(codes, h.Do(http.MethodGet, "/api/v1/bookings/reference/SB0000000?token=x", nil, forwardedFor).Code)
+		}
+		return codes
+	}
+	limitLookups := func(cfg *config.Config) {
+		cfg.RateLimit.Lookup = "2/1m"
+	}
+
+	// A spoofed X-Forwarded-For does not get a fresh bucket
+	if codes := lookups(apitest.New(t, apitest.WithConfig(limitLookups))); codes[2] != http.StatusTooManyRequests {
+		t.Errorf("untrusted proxy: got statuses %v, want the third request limited", codes)
+	}
+
+	// Behind a trusted proxy every forwarded client has its own bucket
+	trusted := apitest.New(t, apitest.WithConfig(limitLookups), apitest.WithConfig(func(cfg *config.Config) {
+		cfg.Server.TrustedProxies = []string{"192.0.2.0/24"}
+	}))
+	for i, code := range lookups(trusted) {
+		if code == http.StatusTooManyRequests {
+			t.Errorf("trusted proxy: request %d was limited", i+1)
+		}
+	}
+}
+
+func TestRateLimitIdleBuckets(t *testing.T) {
+	h := apitest.New(t, apitest.WithConfig(func(cfg *config.Config) {
+		cfg.RateLimit.Store = "database"
+	}))
+
+	h.Do(http.MethodGet, "/api/v1/services", nil)
+	h.Do(http.MethodGet, "/api/v1/profile", nil, apitest.WithToken(h.Token(h.CreateUser())))
+	countBuckets := func() int64 {
+		var count int64
+		h.DB.Model(&model.RateLimitBucket{}).Count(&count)
+		return count
+	}
+	if count := countBuckets(); count < 2 {
+		t.Fatalf("got %d buckets, want one per policy and client", count)
+	}
+
+	// Only idle buckets of the given policy are deleted
+	if err := h.App.Repositories.RateLimit.DeleteIdle(context.Background(), "catalog:", time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to delete idle buckets: %v", err)
+	}
+	var remaining []model.RateLimitBucket
+	h.DB.Find(&remaining)
+	for _, bucket := range remaining {
+		if strings.HasPrefix(bucket.BucketKey, "catalog:") {
+			t.Errorf("idle bucket %s was not deleted", bucket.BucketKey)
+		}
+	}
+	if len(remaining) == 0 {
+		t.Error("buckets of other policies were deleted")
+	}
+
+	if err := h.App.Repositories.RateLimit.DeleteIdle(context.Background(), "", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("failed to delete idle buckets: %v", err)
+	}
+	if count := countBuckets(); count != int64(len(remaining)) {
+		t.Errorf("buckets used within the period were deleted")
+	}
+}
+
+func TestSocialLogin(t *testing.T) {
+	provider := oidctest.NewServer(oidctest.User{
+		Subject:       "stub-subject",
+		Email:         "social@example.com",
+		EmailVerified: true,
+		Name:          "Social User",
+	})
+	defer provider.Close()
+
+	h := apitest.New(t, apitest.WithOIDCProvider("stub", provider.Issuer()))
+
+	// callback signs in at the provider with the login state set by start
+	// and completes the flow
+	callback := func(start *apitest.Response, authURL string) *apitest.Response {
+		t.Helper()
+		redirect, err := provider.Authorize(authURL)
+		if err != nil {
+			t.Fatalf("failed to authorize: %v", err)
+		}
+		cookie := start.Header.Get("Set-Cookie")
+		return h.Do(http.MethodGet, redirect.RequestURI(), nil, apitest.WithHeader("Cookie", strings.SplitN(cookie, ";", 2)[0]))
+	}
+	login := func(user oidctest.User) *apitest.Response {
+		t.Helper()
+		provider.SetUser(user)
+		resp := h.Do(http.MethodGet, "/api/v1/auth/oidc/stub/login", nil)
+		if resp.Code != http.StatusFound {
+			t.Fatalf("login: got status %d, want %d: %s", resp.Code, http.StatusFound, resp.Body)
+		}
+		return callback(resp, resp.Header.Get("Location"))
+	}
+
+	resp := login(oidctest.User{Subject: "stub-subject", Email: "social@example.com", EmailVerified: true, Name: "Social User"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("callback: got status %d, want %d: %s", resp.Code, http.StatusOK, resp.Body)
+	}
+
+	var body struct {
+		AccessToken string     `json:"access_token"`
+		User        model.User `json:"user"`
+	}
+	resp.Decode(t, &body)
+	if body.AccessToken == "" || body.User.Email != "social@example.com" {
+		t.Errorf("unexpected login response: %s", resp.Body)
+	}
+
+	// Accounts are only linked by email when their own email was verified
+	verified := h.CreateUser(apitest.WithEmail("verified@example.com"), apitest.WithVerifiedEmail())
+	if resp := login(oidctest.User{Subject: "verified-subject", Email: verified.Email, EmailVerified: true}); resp.Code != http.StatusOK {
+		t.Errorf("verified account: got status %d, want %d: %s", resp.Code, http.StatusOK, resp.Body)
+	}
+	unverified := h.CreateUser(apitest.WithEmail("unverified@example.com"))
+	if resp := login(oidctest.User{Subject: "unverified-subject", Email: unverified.Email, EmailVerified: true}); resp.Code != http.StatusConflict {
+		t.Errorf("unverified account: got status %d, want %d: %s", resp.Code, http.StatusConflict, resp.Body)
+	}
+
+	// Accounts that require a second factor are never linked by email
+	admin := h.CreateUser(apitest.WithRole(model.UserRoleAdmin), apitest.WithTOTP(), apitest.WithEmail("admin@example.com"), apitest.WithVerifiedEmail())
+	adminIdentity := oidctest.User{Subject: "admin-subject", Email: admin.Email, EmailVerified: true}
+	if resp := login(adminIdentity); resp.Code != http.StatusConflict {
+		t.Fatalf("admin account: got status %d, want %d: %s", resp.Code, http.StatusConflict, resp.Body)
+	}
+
+	// The admin links the provider account from a signed-in session
+	resp = h.Do(http.MethodPost, "/api/v1/profile/oidc/stub/link", nil, apitest.WithToken(h.Token(admin)))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("begin link: got status %d, want %d: %s", resp.Code, http.StatusOK, resp.Body)
+	}
+	var link struct {
+		AuthorizationURL string `json:"authorization_url"`
+	}
+	resp.Decode(t, &link)
+	provider.SetUser(adminIdentity)
+	if resp := callback(resp, link.AuthorizationURL); resp.Code != http.StatusOK {
+		t.Fatalf("link: got status %d, want %d: %s", resp.Code, http.StatusOK, resp.Body)
+	}
+
+	// Logging in with the linked account still asks for the second factor
+	resp = login(adminIdentity)
+	var challenge struct {
+		MFARequired bool `json:"mfa_required"`
+	}
+	resp.Decode(t, &challenge)
+	if resp.Code != http.StatusOK || !challenge.MFARequired {
+		t.Errorf("linked admin login: got status %d, want a second factor challenge: %s", resp.Code, resp.Body)
+	}
+}
+
+func TestIsolatedInstances(t *testing.T) {
+	first := apitest.New(t)
+	second := apitest.New(t, apitest.WithConfig(func(cfg *config.Config) {
+		cfg.JWT.SecretKey = "another-secret-key"
+	}))
+
+	user := first.CreateUser()
+	token := first.Token(user)
+
+	if resp := first.Do(http.MethodGet, "/api/v1/profile", nil, apitest.WithToken(token)); resp.Code != http.StatusOK {
+		t.Fatalf("own instance: got status %d, want %d: %s", resp.Code, http.StatusOK, resp.Body)
+	}
+	if resp := second.Do(http.MethodGet, "/api/v1/profile", nil, apitest.WithToken(token)); resp.Code != http.StatusUnauthorized {
+		t.Errorf("other instance: got status %d, want %d: %s", resp.Code, http.StatusUnauthorized, resp.Body)
+	}
+
+	// Phone numbers are read with the country code of the own instance, even
+	// after another instance was set up. The number has too many digits for
+	// the default country code 880.
+	us := apitest.New(t, apitest.WithConfig(func(cfg *config.Config) {
+		cfg.OTP.DefaultCountryCode = "1"
+	}))
+	apitest.New(t)
+	body := map[string]string{"phone": "012345678901234"}
+	if resp := us.Do(http.MethodPost, "/api/v1/auth/otp/request", body); resp.Code != http.StatusOK {
+		t.Errorf("own country code: got status %d, want %d: %s", resp.Code, http.StatusOK, resp.Body)
+	}
+	if resp := first.Do(http.MethodPost, "/api/v1/auth/otp/request", body); resp.Code != http.StatusBadRequest {
+		t.Errorf("other country code: got status %d, want %d: %s", resp.Code, http.StatusBadRequest, resp.Body)
+	}
+}
+
+func TestSecretKey(t *testing.T) {
+	cfg := apitest.Config()
+	cfg.Env = "production"
+	cfg.JWT.SecretKey = ""
+	if _, err := app.New(cfg, nil); err == nil {
+		t.Error("expected an app without a secret key to be refused in production")
+	}
+
+	// Outside production every instance generates its own secret key
+	noSecretKey := apitest.WithConfig(func(cfg *config.Config) {
+		cfg.JWT.SecretKey = ""
+	})
+	first := apitest.New(t, noSecretKey)
+	second := apitest.New(t, noSecretKey)
+
+	token := first.Token(first.CreateUser())
+	if resp := first.Do(http.MethodGet, "/api/v1/profile", nil, apitest.WithToken(token)); resp.Code != http.StatusOK {
+		t.Fatalf("own instance: got status %d, want %d: %s", resp.Code, http.StatusOK, resp.Body)
+	}
+	if resp := second.Do(http.MethodGet, "/api/v1/profile", nil, apitest.WithToken(token)); resp.Code != http.StatusUnauthorized {
+		t.Errorf("other instance: got status %d, want %d: %s", resp.Code, http.StatusUnauthorized, resp.Body)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	h := apitest.New(t)
+
+	// A migration that failed halfway leaves the schema unusable
+	if err := h.DB.Exec("UPDATE schema_migrations SET dirty = ? WHERE version = ?", true, 2).Error; err != nil {
+		t.Fatalf("failed to mark migration dirty: %v", err)
+	}
+
+	resp := h.Do(http.MethodGet, "/readyz", nil)
+	if resp.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got status %d, want %d: %s", resp.Code, http.StatusServiceUnavailable, resp.Body)
+	}
+
+	var summary health.Summary
+	resp.Decode(t, &summary)
+	if summary.Checks["migrations"] != health.StatusFail || summary.Checks["database"] != health.StatusOK {
+		t.Errorf("unexpected readiness report: %s", resp.Body)
+	}
+	// Errors and details are only logged
+	if bytes.Contains(resp.Body, []byte("dirty")) || bytes.Contains(resp.Body, []byte("version")) {
+		t.Errorf("readiness report reveals check details: %s", resp.Body)
+	}
+
+	if resp := h.Do(http.MethodGet, "/healthz", nil); resp.Code != http.StatusOK {
+		t.Errorf("liveness: got status %d, want %d", resp.Code, http.StatusOK)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	h := apitest.New(t)
+
+	support := h.CreateUser(apitest.WithRole(model.UserRoleSupport))
+	customer := h.CreateUser()
+	category := h.CreateCategory(nil)
+	svc := h.CreateService(category)
+
+	resp := h.Do(http.MethodPost, "/api/v1/bookings", map[string]interface{}{"service_id": svc.ID, "user_name": "Rahim"}, apitest.WithToken(h.Token(customer)))
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create booking: got status %d: %s", resp.Code, resp.Body)
+	}
+	var booking model.Booking
+	resp.Decode(t, &booking)
+
+	path := fmt.Sprintf("/api/v1/bookings/%d/status", booking.ID)
+	resp = h.Do(http.MethodPut, path, map[string]string{"status": "completed"}, apitest.WithToken(h.Token(support)))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("complete booking: got status %d: %s", resp.Code, resp.Body)
+	}
+	resp = h.Do(http.MethodPut, path, map[string]string{"status": "completed"}, apitest.WithToken(h.Token(support)))
+	if resp.Code != http.StatusConflict {
+		t.Errorf("complete booking again: got status %d, want %d", resp.Code, http.StatusConflict)
+	}
+	h.Do(http.MethodGet, "/api/v1/services/999999", nil)
+	h.Do(http.MethodGet, "/no-such-path", nil)
+
+	resp = h.Do(http.MethodGet, "/metrics", nil, apitest.WithToken(apitest.MetricsToken))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("metrics: got status %d", resp.Code)
+	}
+
+	body := string(resp.Body)
+	want := []string{
+		fmt.Sprintf(`bookings_created_total{category=%q} 1`, category.Name),
+		`booking_status_transitions_total{from="pending",to="completed"} 1`,
+		fmt.Sprintf(`booking_revenue_total{category=%q} %g`, category.Name, svc.Price),
+		`http_requests_total{method="GET",route="/api/v1/services/:id",status="404"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`db_query_duration_seconds_count{operation="query",table="services"}`,
+		`go_sql_open_connections{db_name="sqlite"}`,
+	}
+	for _, line := range want {
+		if !strings.Contains(body, line) {
+			t.Errorf("metrics do not contain %s", line)
+		}
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	h := apitest.New(t)
+	user := h.CreateUser()
+	token := h.Token(user)
+
+	resp := h.Do(http.MethodGet, "/api/v1/profile", nil, apitest.WithToken(token), apitest.WithHeader("X-Request-ID", "client-request-1"))
+	if got := resp.Header.Get("X-Request-ID"); got != "client-request-1" {
+		t.Errorf("propagated request ID: got %q", got)
+	}
+	for _, id := range []string{"", "not allowed", strings.Repeat("a", 129)} {
+		resp := h.Do(http.MethodGet, "/healthz", nil, apitest.WithHeader("X-Request-ID", id))
+		if got := resp.Header.Get("X-Request-ID"); len(got) != 32 {
+			t.Errorf("request ID %q: got generated ID %q", id, got)
+		}
+	}
+
+	var served map[string]any
+	for _, record := range h.Logs.Records() {
+		if record["request_id"] == "client-request-1" && record["msg"] == "Request served" {
+			served = record
+		}
+	}
+	if served == nil {
+		t.Fatal("no log record for the request")
+	}
+	if served["route"] != "/api/v1/profile" || served["status"] != float64(http.StatusOK) || served["user_id"] != float64(user.ID) {
+		t.Errorf("unexpected log record: %v", served)
+	}
+
+	h.App.Logger.Info("Redaction", "password", "hunter2", "refresh_token", token, "phone", customerPhone, "user_email", "rahim@example.com")
+	records := h.Logs.Records()
+	redacted := records[len(records)-1]
+	want := map[string]any{
+		"password":      "[REDACTED]",
+		"refresh_token": "[REDACTED]",
+		"phone":         "+8**********11",
+		"user_email":    "ra*im@example.com",
+	}
+	for key, value := range want {
+		if redacted[key] != value {
+			t.Errorf("%s: got %v, want %v", key, redacted[key], value)
+		}
+	}
+	for _, record := range records {
+		if strings.Contains(fmt.Sprint(record), token) {
+			t.Errorf("log record contains the access token: %v", record)
+		}
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	h := apitest.New(t)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	h.Do(http.MethodGet, "/api/v1/services", nil,
+		apitest.WithHeader("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01"),
+		apitest.WithHeader("X-Request-ID", "traced-request"),
+	)
+
+	for _, record := range h.Logs.Records() {
+		if record["request_id"] == "traced-request" {
+			if record["trace_id"] != traceID {
+				t.Errorf("got trace ID %v, want %s", record["trace_id"], traceID)
+			}
+			return
+		}
+	}
+	t.Fatal("no log record for the request")
+}
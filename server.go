@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"service-booking/config"
+)
+
+// serverTimeouts are the parsed [server] timeouts
+type serverTimeouts struct {
+	read       time.Duration
+	readHeader time.Duration
+	write      time.Duration
+	idle       time.Duration
+	shutdown   time.Duration
+}
+
+// parseServerTimeouts validates the configured HTTP server timeouts
+func parseServerTimeouts(cfg *config.Config) (serverTimeouts, error) {
+	var timeouts serverTimeouts
+	values := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"read_timeout", cfg.Server.ReadTimeout, &timeouts.read},
+		{"read_header_timeout", cfg.Server.ReadHeaderTimeout, &timeouts.readHeader},
+		{"write_timeout", cfg.Server.WriteTimeout, &timeouts.write},
+		{"idle_timeout", cfg.Server.IdleTimeout, &timeouts.idle},
+		{"shutdown_timeout", cfg.Server.ShutdownTimeout, &timeouts.shutdown},
+	}
+	for _, v := range values {
+		duration, err := time.ParseDuration(v.value)
+		if err != nil || duration <= 0 {
+			return timeouts, fmt.Errorf("invalid server %s %q", v.name, v.value)
+		}
+		*v.dest = duration
+	}
+	return timeouts, nil
+}
+
+// serve runs the server until SIGINT or SIGTERM, then stops accepting
+// connections and waits for in-flight requests to finish
+func serve(addr string, handler http.Handler, timeouts serverTimeouts) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       timeouts.read,
+		ReadHeaderTimeout: timeouts.readHeader,
+		WriteTimeout:      timeouts.write,
+		IdleTimeout:       timeouts.idle,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// A second signal kills the process instead of waiting for the drain
+	stop()
+	slog.Info("Shutting down, waiting for in-flight requests", "timeout", timeouts.shutdown.String())
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeouts.shutdown)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to drain connections: %w", err)
+	}
+	if err := <-serverErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	slog.Info("Server stopped")
+	return nil
+}